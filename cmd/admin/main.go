@@ -0,0 +1,297 @@
+// Package main provides an operator CLI for managing user accounts, e.g. to
+// create the first administrator of a fresh deployment.
+//
+// Usage:
+//
+//	go run ./cmd/admin create-user -email ops@example.com -name "Ops Team" -role admin
+//	go run ./cmd/admin reset-password -email ops@example.com
+//	go run ./cmd/admin set-role -email jane@example.com -role admin
+//	go run ./cmd/admin list -role admin
+//
+// Passwords not given with -password are read from the first line of stdin,
+// which keeps them out of shell history.
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/one-backend-go/internal/config"
+	"github.com/one-backend-go/internal/db"
+	"github.com/one-backend-go/internal/domain/actiontoken"
+	"github.com/one-backend-go/internal/domain/auth"
+	"github.com/one-backend-go/internal/domain/role"
+	"github.com/one-backend-go/internal/domain/user"
+	"github.com/one-backend-go/internal/pkg/notify"
+	"github.com/one-backend-go/internal/pkg/pagination"
+	"github.com/one-backend-go/internal/pkg/password"
+	"github.com/one-backend-go/internal/pkg/validate"
+)
+
+const usage = `usage: admin <command> [flags]
+
+commands:
+  create-user     create a user with a given role
+  reset-password  set a new password and sign the user out everywhere
+  set-role        promote or demote a user
+  list            list users
+
+Run "admin <command> -h" for the flags of a command.
+`
+
+// app bundles the dependencies shared by all commands.
+type app struct {
+	users     *user.Service
+	auth      *auth.Service
+	validator *validate.Validator
+	stdin     *bufio.Reader
+	stdout    io.Writer
+}
+
+func main() {
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn})))
+
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		slog.Error("failed to load config", "error", err)
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+	mongoDB, err := db.Connect(ctx, cfg.MongoURI, cfg.MongoDB)
+	if err != nil {
+		slog.Error("failed to connect to MongoDB", "error", err)
+		os.Exit(1)
+	}
+	defer func() { _ = db.Disconnect(ctx, mongoDB) }()
+
+	if err := db.EnsureIndexes(ctx, mongoDB); err != nil {
+		slog.Error("failed to create indexes", "error", err)
+		os.Exit(1)
+	}
+
+	hasher, err := password.NewFromConfig(cfg)
+	if err != nil {
+		slog.Error("invalid password hashing config", "error", err)
+		os.Exit(1)
+	}
+
+	policy, err := password.NewPolicyFromConfig(cfg)
+	if err != nil {
+		slog.Error("invalid password policy config", "error", err)
+		os.Exit(1)
+	}
+
+	jwtMgr, err := auth.NewJWTManagerFromConfig(cfg)
+	if err != nil {
+		slog.Error("failed to load JWT signing keys", "error", err)
+		os.Exit(1)
+	}
+
+	notifier := notify.NewLogNotifier()
+	authRepo := auth.NewRepository(mongoDB)
+	userRepo := user.NewRepository(mongoDB)
+	roleSvc := role.NewService(role.NewRepository(mongoDB), userRepo)
+	tokenSvc := actiontoken.NewService(actiontoken.NewRepository(mongoDB))
+	userSvc := user.NewService(cfg, userRepo, roleSvc, authRepo, tokenSvc, notifier, hasher)
+
+	a := &app{
+		users:     userSvc,
+		auth:      auth.NewService(cfg, jwtMgr, authRepo, userSvc, roleSvc, tokenSvc, notifier),
+		validator: validate.NewWithPasswordPolicy(policy),
+		stdin:     bufio.NewReader(os.Stdin),
+		stdout:    os.Stdout,
+	}
+
+	if err := a.run(ctx, os.Args[1], os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+// run dispatches to the named command.
+func (a *app) run(ctx context.Context, cmd string, args []string) error {
+	switch cmd {
+	case "create-user":
+		return a.createUser(ctx, args)
+	case "reset-password":
+		return a.resetPassword(ctx, args)
+	case "set-role":
+		return a.setRole(ctx, args)
+	case "list":
+		return a.list(ctx, args)
+	case "help", "-h", "--help":
+		fmt.Fprint(a.stdout, usage)
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q\n\n%s", cmd, usage)
+	}
+}
+
+func (a *app) createUser(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("create-user", flag.ExitOnError)
+	email := fs.String("email", "", "email address (required)")
+	name := fs.String("name", "", "display name (required)")
+	password := fs.String("password", "", "password (read from stdin if empty)")
+	roleName := fs.String("role", role.User, "role to assign")
+	_ = fs.Parse(args)
+
+	pw, err := a.passwordFrom(*password)
+	if err != nil {
+		return err
+	}
+
+	req := user.RegisterRequest{Name: *name, Email: *email, Password: pw}
+	if errs := a.validator.Struct(req); errs != nil {
+		return validationError(errs)
+	}
+
+	u, err := a.users.CreateWithRole(ctx, req, *roleName)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.stdout, "created %s (%s) with role %q\n", u.Email, u.ID.Hex(), u.Role)
+	return nil
+}
+
+func (a *app) resetPassword(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("reset-password", flag.ExitOnError)
+	email := fs.String("email", "", "email address (required)")
+	password := fs.String("password", "", "new password (read from stdin if empty)")
+	_ = fs.Parse(args)
+
+	u, err := a.findUser(ctx, *email)
+	if err != nil {
+		return err
+	}
+
+	pw, err := a.passwordFrom(*password)
+	if err != nil {
+		return err
+	}
+	req := struct {
+		Password string `validate:"required,strongpass"`
+	}{Password: pw}
+	if errs := a.validator.Struct(req); errs != nil {
+		return validationError(errs)
+	}
+
+	if err = a.auth.AdminResetPassword(ctx, u.ID, pw); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.stdout, "password reset for %s; all sessions revoked\n", u.Email)
+	return nil
+}
+
+func (a *app) setRole(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("set-role", flag.ExitOnError)
+	email := fs.String("email", "", "email address (required)")
+	roleName := fs.String("role", "", "role to assign (required)")
+	_ = fs.Parse(args)
+
+	if *roleName == "" {
+		return errors.New("-role is required")
+	}
+
+	u, err := a.findUser(ctx, *email)
+	if err != nil {
+		return err
+	}
+
+	u, err = a.users.AssignRole(ctx, u.ID, *roleName)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.stdout, "%s now has role %q\n", u.Email, u.Role)
+	return nil
+}
+
+func (a *app) list(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	roleName := fs.String("role", "", "only users with this role")
+	email := fs.String("email", "", "only emails containing this text")
+	page := fs.Int64("page", 1, "page number")
+	pageSize := fs.Int64("page-size", 50, "users per page (max 50)")
+	_ = fs.Parse(args)
+
+	p := pagination.DefaultParams()
+	p.Page, p.PageSize = *page, *pageSize
+	p.Sort, p.Order = "email", "asc"
+	p.Clamp()
+
+	result, err := a.users.List(ctx, user.ListFilter{Role: *roleName, Email: *email}, p)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(a.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tEMAIL\tNAME\tROLE\tVERIFIED\tMFA\tDISABLED")
+	for _, u := range result.Items {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%t\t%t\t%t\n",
+			u.ID, u.Email, u.Name, u.Role, u.EmailVerified, u.MFAEnabled, u.Disabled)
+	}
+	if err = w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.stdout, "page %d of %d (%d users)\n", result.Page, result.TotalPages, result.Total)
+	return nil
+}
+
+// findUser looks up a user by email, failing if there is none.
+func (a *app) findUser(ctx context.Context, email string) (*user.User, error) {
+	if email == "" {
+		return nil, errors.New("-email is required")
+	}
+	u, err := a.users.GetByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, fmt.Errorf("no user with email %q", email)
+	}
+	return u, nil
+}
+
+// passwordFrom returns the flag value, or reads a password from stdin.
+func (a *app) passwordFrom(flagValue string) (string, error) {
+	if flagValue != "" {
+		return flagValue, nil
+	}
+
+	fmt.Fprint(os.Stderr, "password: ")
+	line, err := a.stdin.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("read password: %w", err)
+	}
+	pw := strings.TrimRight(line, "\r\n")
+	if pw == "" {
+		return "", errors.New("password is required")
+	}
+	return pw, nil
+}
+
+// validationError formats validator messages as a single error.
+func validationError(errs map[string]string) error {
+	msgs := make([]string, 0, len(errs))
+	for field, msg := range errs {
+		msgs = append(msgs, field+": "+msg)
+	}
+	return fmt.Errorf("invalid input: %s", strings.Join(msgs, "; "))
+}
@@ -0,0 +1,124 @@
+// Package main provides a CLI to inspect and apply database migrations.
+//
+// Usage:
+//
+//	go run ./cmd/migrate status
+//	go run ./cmd/migrate up [-to VERSION]
+//	go run ./cmd/migrate down [-steps N]
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/one-backend-go/internal/config"
+	"github.com/one-backend-go/internal/db"
+	"github.com/one-backend-go/internal/db/migrate"
+	"github.com/one-backend-go/internal/db/migrations"
+)
+
+const usage = `usage: migrate <command> [flags]
+
+commands:
+  status  list migrations and whether they have been applied
+  up      apply pending migrations (-to VERSION stops after that version)
+  down    roll back applied migrations (-steps N, default 1)
+`
+
+func main() {
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelInfo})))
+
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		slog.Error("failed to load config", "error", err)
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+	mongoDB, err := db.Connect(ctx, cfg.MongoURI, cfg.MongoDB)
+	if err != nil {
+		slog.Error("failed to connect to MongoDB", "error", err)
+		os.Exit(1)
+	}
+	defer func() { _ = db.Disconnect(ctx, mongoDB) }()
+
+	m, err := migrate.New(mongoDB, migrations.All())
+	if err != nil {
+		slog.Error("invalid migrations", "error", err)
+		os.Exit(1)
+	}
+
+	if err := run(ctx, m, os.Args[1], os.Args[2:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+// run dispatches to the named command.
+func run(ctx context.Context, m *migrate.Migrator, cmd string, args []string, out io.Writer) error {
+	switch cmd {
+	case "status":
+		return status(ctx, m, out)
+	case "up":
+		fs := flag.NewFlagSet("up", flag.ExitOnError)
+		to := fs.Int64("to", 0, "stop after this version (default: apply all)")
+		_ = fs.Parse(args)
+
+		done, err := m.Up(ctx, *to)
+		report(out, "applied", done)
+		return err
+	case "down":
+		fs := flag.NewFlagSet("down", flag.ExitOnError)
+		steps := fs.Int("steps", 1, "number of migrations to roll back")
+		_ = fs.Parse(args)
+
+		done, err := m.Down(ctx, *steps)
+		report(out, "rolled back", done)
+		return err
+	case "help", "-h", "--help":
+		fmt.Fprint(out, usage)
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q\n\n%s", cmd, usage)
+	}
+}
+
+func status(ctx context.Context, m *migrate.Migrator, out io.Writer) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, st := range statuses {
+		applied := "pending"
+		if st.Applied() {
+			applied = st.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", st.Version, st.Name, applied)
+	}
+	return w.Flush()
+}
+
+// report prints the migrations a command got through, even if it then failed.
+func report(out io.Writer, verb string, done []migrate.Migration) {
+	if len(done) == 0 {
+		fmt.Fprintf(out, "nothing %s\n", verb)
+		return
+	}
+	for _, mig := range done {
+		fmt.Fprintf(out, "%s %d_%s\n", verb, mig.Version, mig.Name)
+	}
+}
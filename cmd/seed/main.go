@@ -1,4 +1,5 @@
-// Package main provides a CLI tool to seed the database with sample products.
+// Package main provides a CLI tool to seed the database with sample categories
+// and products.
 package main
 
 import (
@@ -6,9 +7,13 @@ import (
 	"log/slog"
 	"os"
 
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
 	"github.com/one-backend-go/internal/config"
 	"github.com/one-backend-go/internal/db"
+	"github.com/one-backend-go/internal/domain/category"
 	"github.com/one-backend-go/internal/domain/product"
+	"github.com/one-backend-go/internal/pkg/slug"
 )
 
 func main() {
@@ -33,19 +38,43 @@ func main() {
 		os.Exit(1)
 	}
 
+	categoryRepo := category.NewRepository(mongoDB)
 	repo := product.NewRepository(mongoDB)
 
+	// Categories are reused if an earlier run created them.
+	names := []string{"Appetizers", "Burgers", "Pizza", "Pasta", "Mexican", "Seafood", "Salads", "Healthy", "Desserts", "Drinks"}
+	ids := make(map[string]primitive.ObjectID, len(names))
+	for i, name := range names {
+		c, err := categoryRepo.FindBySlug(ctx, slug.Make(name))
+		if err == nil && c == nil {
+			c = &category.Category{Name: name, Slug: slug.Make(name), Position: i, Active: true}
+			err = categoryRepo.Create(ctx, c)
+		}
+		if err != nil {
+			slog.Error("failed to seed categories", "error", err)
+			os.Exit(1)
+		}
+		ids[c.Slug] = c.ID
+	}
+
 	products := []product.Product{
-		{Name: "Classic Cheeseburger", Description: "Juicy beef patty with cheddar cheese, lettuce, tomato, and pickles", PriceCents: 999, Category: "burgers", ImageURL: "https://example.com/img/cheeseburger.jpg", IsAvailable: true},
-		{Name: "Margherita Pizza", Description: "Traditional pizza with fresh mozzarella, tomato sauce, and basil", PriceCents: 1299, Category: "pizza", ImageURL: "https://example.com/img/margherita.jpg", IsAvailable: true},
-		{Name: "Caesar Salad", Description: "Romaine lettuce with parmesan, croutons, and Caesar dressing", PriceCents: 799, Category: "salads", ImageURL: "https://example.com/img/caesar.jpg", IsAvailable: true},
-		{Name: "Chicken Tacos", Description: "Three soft corn tortillas with grilled chicken, salsa, and guacamole", PriceCents: 1099, Category: "mexican", ImageURL: "https://example.com/img/tacos.jpg", IsAvailable: true},
-		{Name: "Spaghetti Carbonara", Description: "Classic Italian pasta with pancetta, egg, and pecorino cheese", PriceCents: 1399, Category: "pasta", ImageURL: "https://example.com/img/carbonara.jpg", IsAvailable: true},
-		{Name: "Fish and Chips", Description: "Beer-battered cod with crispy fries and tartar sauce", PriceCents: 1199, Category: "seafood", ImageURL: "https://example.com/img/fishnchips.jpg", IsAvailable: true},
-		{Name: "Veggie Wrap", Description: "Grilled vegetables with hummus in a whole wheat tortilla", PriceCents: 849, Category: "healthy", ImageURL: "https://example.com/img/veggiewrap.jpg", IsAvailable: true},
-		{Name: "Chocolate Brownie", Description: "Rich dark chocolate brownie served with vanilla ice cream", PriceCents: 599, Category: "desserts", ImageURL: "https://example.com/img/brownie.jpg", IsAvailable: true},
-		{Name: "Mango Smoothie", Description: "Fresh mango blended with yogurt and honey", PriceCents: 499, Category: "drinks", ImageURL: "https://example.com/img/mango-smoothie.jpg", IsAvailable: true},
-		{Name: "BBQ Chicken Wings", Description: "Crispy chicken wings tossed in smoky BBQ sauce", PriceCents: 999, Category: "appetizers", ImageURL: "https://example.com/img/wings.jpg", IsAvailable: true},
+		{Name: "Classic Cheeseburger", Description: "Juicy beef patty with cheddar cheese, lettuce, tomato, and pickles", PriceCents: 999, CategoryID: ids["burgers"], ImageURL: "https://example.com/img/cheeseburger.jpg", IsAvailable: true},
+		{Name: "Margherita Pizza", Description: "Traditional pizza with fresh mozzarella, tomato sauce, and basil", CategoryID: ids["pizza"], ImageURL: "https://example.com/img/margherita.jpg", IsAvailable: true, Variants: []product.Variant{
+			{SKU: "MARGHERITA-S", Name: "Small", PriceCents: 999, IsAvailable: true},
+			{SKU: "MARGHERITA-M", Name: "Medium", PriceCents: 1299, IsAvailable: true},
+			{SKU: "MARGHERITA-L", Name: "Large", PriceCents: 1599, IsAvailable: true},
+		}},
+		{Name: "Caesar Salad", Description: "Romaine lettuce with parmesan, croutons, and Caesar dressing", PriceCents: 799, CategoryID: ids["salads"], ImageURL: "https://example.com/img/caesar.jpg", IsAvailable: true},
+		{Name: "Chicken Tacos", Description: "Three soft corn tortillas with grilled chicken, salsa, and guacamole", PriceCents: 1099, CategoryID: ids["mexican"], ImageURL: "https://example.com/img/tacos.jpg", IsAvailable: true},
+		{Name: "Spaghetti Carbonara", Description: "Classic Italian pasta with pancetta, egg, and pecorino cheese", PriceCents: 1399, CategoryID: ids["pasta"], ImageURL: "https://example.com/img/carbonara.jpg", IsAvailable: true},
+		{Name: "Fish and Chips", Description: "Beer-battered cod with crispy fries and tartar sauce", PriceCents: 1199, CategoryID: ids["seafood"], ImageURL: "https://example.com/img/fishnchips.jpg", IsAvailable: true},
+		{Name: "Veggie Wrap", Description: "Grilled vegetables with hummus in a whole wheat tortilla", PriceCents: 849, CategoryID: ids["healthy"], ImageURL: "https://example.com/img/veggiewrap.jpg", IsAvailable: true},
+		{Name: "Chocolate Brownie", Description: "Rich dark chocolate brownie served with vanilla ice cream", PriceCents: 599, CategoryID: ids["desserts"], ImageURL: "https://example.com/img/brownie.jpg", IsAvailable: true},
+		{Name: "Mango Smoothie", Description: "Fresh mango blended with yogurt and honey", CategoryID: ids["drinks"], ImageURL: "https://example.com/img/mango-smoothie.jpg", IsAvailable: true, Variants: []product.Variant{
+			{SKU: "MANGO-330", Name: "330ml", PriceCents: 499, IsAvailable: true},
+			{SKU: "MANGO-500", Name: "500ml", PriceCents: 649, IsAvailable: true},
+		}},
+		{Name: "BBQ Chicken Wings", Description: "Crispy chicken wings tossed in smoky BBQ sauce", PriceCents: 999, CategoryID: ids["appetizers"], ImageURL: "https://example.com/img/wings.jpg", IsAvailable: true},
 	}
 
 	if err := repo.InsertMany(ctx, products); err != nil {
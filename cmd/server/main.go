@@ -3,6 +3,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -11,12 +12,26 @@ import (
 	"syscall"
 	"time"
 
+	"go.mongodb.org/mongo-driver/mongo"
+
 	"github.com/one-backend-go/internal/config"
 	"github.com/one-backend-go/internal/db"
+	"github.com/one-backend-go/internal/db/migrate"
+	"github.com/one-backend-go/internal/db/migrations"
+	"github.com/one-backend-go/internal/domain/actiontoken"
+	"github.com/one-backend-go/internal/domain/apikey"
 	"github.com/one-backend-go/internal/domain/auth"
+	"github.com/one-backend-go/internal/domain/category"
+	"github.com/one-backend-go/internal/domain/inventory"
+	"github.com/one-backend-go/internal/domain/modifier"
+	"github.com/one-backend-go/internal/domain/privacy"
 	"github.com/one-backend-go/internal/domain/product"
+	"github.com/one-backend-go/internal/domain/role"
+	"github.com/one-backend-go/internal/domain/sso"
 	"github.com/one-backend-go/internal/domain/user"
 	apphttp "github.com/one-backend-go/internal/http"
+	"github.com/one-backend-go/internal/pkg/notify"
+	"github.com/one-backend-go/internal/pkg/password"
 	"github.com/one-backend-go/internal/pkg/validate"
 )
 
@@ -49,29 +64,89 @@ func main() {
 		os.Exit(1)
 	}
 
+	if err := runMigrations(ctx, mongoDB, cfg.MigrateOnStart); err != nil {
+		slog.Error("failed to apply migrations", "error", err)
+		os.Exit(1)
+	}
+
 	// ── Dependencies ───────────────────────────────────────────────────
-	validator := validate.New()
+	passwordPolicy, err := password.NewPolicyFromConfig(cfg)
+	if err != nil {
+		slog.Error("invalid password policy config", "error", err)
+		os.Exit(1)
+	}
+	validator := validate.NewWithPasswordPolicy(passwordPolicy)
 
 	// Repositories
 	userRepo := user.NewRepository(mongoDB)
 	authRepo := auth.NewRepository(mongoDB)
+	roleRepo := role.NewRepository(mongoDB)
 	productRepo := product.NewRepository(mongoDB)
+	categoryRepo := category.NewRepository(mongoDB)
+	modifierRepo := modifier.NewRepository(mongoDB)
+	inventoryRepo := inventory.NewRepository(mongoDB)
+	actionTokenRepo := actiontoken.NewRepository(mongoDB)
+	apiKeyRepo := apikey.NewRepository(mongoDB)
+	ssoRepo := sso.NewRepository(mongoDB)
 
 	// JWT Manager
-	jwtMgr := auth.NewJWTManager(cfg.JWTSecret, cfg.AccessTokenTTL)
+	jwtMgr, err := auth.NewJWTManagerFromConfig(cfg)
+	if err != nil {
+		slog.Error("failed to load JWT signing keys", "error", err)
+		os.Exit(1)
+	}
+
+	// Notifier
+	notifier, err := notify.New(cfg.Notifier, notify.Options{
+		FilePath:     cfg.NotifierFile,
+		WebhookURL:   cfg.NotifierWebhookURL,
+		WebhookToken: cfg.NotifierWebhookToken,
+	})
+	if err != nil {
+		slog.Error("failed to create notifier", "error", err)
+		os.Exit(1)
+	}
+
+	// Password hasher
+	hasher, err := password.NewFromConfig(cfg)
+	if err != nil {
+		slog.Error("invalid password hashing config", "error", err)
+		os.Exit(1)
+	}
 
 	// Services
-	userSvc := user.NewService(userRepo)
-	authSvc := auth.NewService(cfg, jwtMgr, authRepo, userSvc)
-	productSvc := product.NewService(productRepo)
+	actionTokenSvc := actiontoken.NewService(actionTokenRepo)
+	roleSvc := role.NewService(roleRepo, userRepo)
+	userSvc := user.NewService(cfg, userRepo, roleSvc, authRepo, actionTokenSvc, notifier, hasher)
+	authSvc := auth.NewService(cfg, jwtMgr, authRepo, userSvc, roleSvc, actionTokenSvc, notifier)
+	categorySvc := category.NewService(categoryRepo, productRepo)
+	modifierSvc := modifier.NewService(modifierRepo, productRepo)
+	productSvc := product.NewService(productRepo, categorySvc, modifierSvc, inventoryRepo)
+	inventorySvc := inventory.NewService(inventoryRepo, productRepo)
+	apiKeySvc := apikey.NewService(apiKeyRepo, userSvc)
+	ssoSvc := sso.NewService(cfg, ssoRepo, userSvc, authSvc)
+	privacySvc := privacy.NewService(userSvc, authSvc)
+	privacySvc.RegisterExporter("account", userSvc)
+	privacySvc.RegisterExporter("sessions", authSvc)
+	privacySvc.RegisterExporter("action_tokens", actionTokenSvc)
+	privacySvc.RegisterEraser("sessions", authSvc)
+	privacySvc.RegisterEraser("action_tokens", actionTokenSvc)
+	privacySvc.RegisterEraser("account", userSvc) // last: anonymizes the user
 
 	// Handlers
 	userHandler := user.NewHandler(userSvc, validator)
 	authHandler := auth.NewHandler(authSvc, validator)
 	productHandler := product.NewHandler(productSvc, validator)
+	categoryHandler := category.NewHandler(categorySvc, validator)
+	modifierHandler := modifier.NewHandler(modifierSvc, validator)
+	inventoryHandler := inventory.NewHandler(inventorySvc, validator)
+	roleHandler := role.NewHandler(roleSvc, validator)
+	apiKeyHandler := apikey.NewHandler(apiKeySvc, validator)
+	ssoHandler := sso.NewHandler(ssoSvc, validator)
+	privacyHandler := privacy.NewHandler(privacySvc, validator)
 
 	// ── HTTP Server ────────────────────────────────────────────────────
-	router := apphttp.NewRouter(cfg, jwtMgr, userRepo, userHandler, authHandler, productHandler)
+	router := apphttp.NewRouter(cfg, jwtMgr, userRepo, roleSvc, apiKeySvc, userHandler, authHandler, productHandler, roleHandler, privacyHandler, apiKeyHandler, ssoHandler, categoryHandler, modifierHandler, inventoryHandler)
 
 	srv := &http.Server{
 		Addr:         fmt.Sprintf(":%s", cfg.Port),
@@ -81,6 +156,10 @@ func main() {
 		IdleTimeout:  60 * time.Second,
 	}
 
+	// ── Background jobs ────────────────────────────────────────────────
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	go inventorySvc.ReleaseExpiredEvery(jobsCtx, time.Minute)
+
 	// ── Graceful Shutdown ──────────────────────────────────────────────
 	go func() {
 		slog.Info("server starting", "port", cfg.Port)
@@ -94,6 +173,7 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	sig := <-quit
 	slog.Info("shutting down server", "signal", sig.String())
+	stopJobs()
 
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -105,3 +185,31 @@ func main() {
 
 	slog.Info("server exited gracefully")
 }
+
+// runMigrations applies pending migrations, or only reports them when apply
+// is false. Another instance already migrating is not an error: it will
+// finish the job, and every migration tolerates running against old data.
+func runMigrations(ctx context.Context, mongoDB *mongo.Database, apply bool) error {
+	m, err := migrate.New(mongoDB, migrations.All())
+	if err != nil {
+		return err
+	}
+
+	if !apply {
+		pending, err := m.Pending(ctx)
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			slog.Warn("database has pending migrations; run cmd/migrate", "pending", len(pending))
+		}
+		return nil
+	}
+
+	_, err = m.Up(ctx, 0)
+	if errors.Is(err, migrate.ErrLocked) {
+		slog.Warn("another instance is applying migrations; continuing")
+		return nil
+	}
+	return err
+}
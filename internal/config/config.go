@@ -4,6 +4,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -12,15 +13,91 @@ import (
 
 // Config holds all application configuration values.
 type Config struct {
+	Env                string // "development" or "production"
 	Port               string
 	MongoURI           string
 	MongoDB            string
 	JWTSecret          string
+	JWTSigningKeyFile  string
+	JWTRetiredKeyFiles []string
 	AccessTokenTTL     time.Duration
 	RefreshTokenTTL    time.Duration
 	CORSAllowedOrigins []string
+
+	// Login throttling and lockout.
+	LoginMaxFailures   int
+	LoginIPMaxFailures int
+	LoginFailureWindow time.Duration
+	LoginLockout       time.Duration
+	LoginBaseDelay     time.Duration
+
+	// Account recovery and user notifications.
+	PasswordResetTTL     time.Duration
+	Notifier             string // "log", "file" or "webhook"; required in production
+	NotifierFile         string
+	NotifierWebhookURL   string
+	NotifierWebhookToken string
+
+	// Email verification; see the EmailVerification* modes.
+	EmailVerification    string
+	EmailVerificationTTL time.Duration
+
+	// TOTP two-factor authentication.
+	MFARequiredRoles []string
+	MFAIssuer        string
+	MFAChallengeTTL  time.Duration
+
+	// Password hashing for new and rehashed passwords.
+	PasswordHashAlgorithm string // "argon2id" or "bcrypt"
+	PasswordBcryptCost    int
+	PasswordArgon2Memory  int // KiB
+	PasswordArgon2Time    int
+	PasswordArgon2Threads int
+
+	// Password policy for new passwords.
+	PasswordMinLength       int
+	PasswordMaxLength       int      // bytes
+	PasswordRequiredClasses []string // lower, upper, letter, digit, symbol
+	PasswordMaxRepeat       int      // 0 disables
+	PasswordBlocklist       string   // "builtin", "none" or a file path
+
+	// Apply pending database migrations when the server starts.
+	MigrateOnStart bool
+
+	// OpenID Connect login providers, and how long a login may take.
+	OIDCProviders []OIDCProvider
+	OIDCStateTTL  time.Duration
+}
+
+// OIDCProvider registers this service as a client of an OpenID Connect
+// provider. Each is configured by OIDC_<NAME>_* variables.
+type OIDCProvider struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
 }
 
+// Environments. Production is the default, so a deployment that forgets
+// APP_ENV gets the strict checks.
+const (
+	EnvDevelopment = "development"
+	EnvProduction  = "production"
+)
+
+// Email verification modes.
+const (
+	// EmailVerificationOff sends verification emails but enforces nothing.
+	EmailVerificationOff = "off"
+	// EmailVerificationLogin refuses logins until the email is verified.
+	EmailVerificationLogin = "login"
+	// EmailVerificationActions allows login but refuses routes guarded by
+	// the verified-email middleware, such as placing orders.
+	EmailVerificationActions = "actions"
+)
+
 // Load reads configuration from .env (if present) and environment variables.
 // In production, .env may not exist — environment variables are used directly.
 func Load() (*Config, error) {
@@ -36,24 +113,180 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("config: invalid REFRESH_TOKEN_TTL: %w", err)
 	}
 
+	// An asymmetric signing key replaces the shared HS256 secret.
 	jwtSecret := getEnv("JWT_SECRET", "")
-	if jwtSecret == "" {
-		return nil, fmt.Errorf("config: JWT_SECRET is required")
+	signingKeyFile := getEnv("JWT_SIGNING_KEY_FILE", "")
+	if jwtSecret == "" && signingKeyFile == "" {
+		return nil, fmt.Errorf("config: JWT_SECRET or JWT_SIGNING_KEY_FILE is required")
 	}
 
 	origins := getEnv("CORS_ALLOWED_ORIGINS", "*")
 
+	maxFailures, err := getInt("LOGIN_MAX_FAILURES", 5)
+	if err != nil {
+		return nil, err
+	}
+	ipMaxFailures, err := getInt("LOGIN_IP_MAX_FAILURES", 20)
+	if err != nil {
+		return nil, err
+	}
+	failureWindow, err := getDuration("LOGIN_FAILURE_WINDOW", "15m")
+	if err != nil {
+		return nil, err
+	}
+	lockout, err := getDuration("LOGIN_LOCKOUT_DURATION", "15m")
+	if err != nil {
+		return nil, err
+	}
+	baseDelay, err := getDuration("LOGIN_BASE_DELAY", "1s")
+	if err != nil {
+		return nil, err
+	}
+	resetTTL, err := getDuration("PASSWORD_RESET_TTL", "30m")
+	if err != nil {
+		return nil, err
+	}
+	verifyTTL, err := getDuration("EMAIL_VERIFICATION_TTL", "24h")
+	if err != nil {
+		return nil, err
+	}
+	mfaChallengeTTL, err := getDuration("MFA_CHALLENGE_TTL", "5m")
+	if err != nil {
+		return nil, err
+	}
+	bcryptCost, err := getInt("PASSWORD_BCRYPT_COST", 12)
+	if err != nil {
+		return nil, err
+	}
+	argon2Memory, err := getInt("PASSWORD_ARGON2_MEMORY", 19456)
+	if err != nil {
+		return nil, err
+	}
+	argon2Time, err := getInt("PASSWORD_ARGON2_TIME", 2)
+	if err != nil {
+		return nil, err
+	}
+	argon2Threads, err := getInt("PASSWORD_ARGON2_THREADS", 1)
+	if err != nil {
+		return nil, err
+	}
+	minLength, err := getInt("PASSWORD_MIN_LENGTH", 8)
+	if err != nil {
+		return nil, err
+	}
+	maxLength, err := getInt("PASSWORD_MAX_LENGTH", 72)
+	if err != nil {
+		return nil, err
+	}
+	maxRepeat, err := getInt("PASSWORD_MAX_REPEAT", 3)
+	if err != nil {
+		return nil, err
+	}
+	migrateOnStart, err := getBool("MIGRATE_ON_START", true)
+	if err != nil {
+		return nil, err
+	}
+	oidcStateTTL, err := getDuration("OIDC_STATE_TTL", "10m")
+	if err != nil {
+		return nil, err
+	}
+	oidcProviders, err := loadOIDCProviders()
+	if err != nil {
+		return nil, err
+	}
+	env := getEnv("APP_ENV", EnvProduction)
+	if env != EnvDevelopment && env != EnvProduction {
+		return nil, fmt.Errorf("config: invalid APP_ENV %q (want development or production)", env)
+	}
+	// Notifications carry account takeover tokens, so production must say
+	// where they go. The log notifier delivers nothing: it logs below the
+	// server's level, with the tokens redacted.
+	notifier := getEnv("NOTIFIER", "")
+	switch {
+	case notifier == "" && env == EnvProduction:
+		return nil, fmt.Errorf("config: NOTIFIER is required when APP_ENV=production")
+	case notifier == "":
+		notifier = "log"
+	case notifier == "log" && env == EnvProduction:
+		return nil, fmt.Errorf("config: NOTIFIER=log delivers nothing and is not allowed when APP_ENV=production")
+	}
+	webhookURL := getEnv("NOTIFIER_WEBHOOK_URL", "")
+	if notifier == "webhook" && webhookURL == "" {
+		return nil, fmt.Errorf("config: NOTIFIER_WEBHOOK_URL is required when NOTIFIER=webhook")
+	}
+	verifyMode := getEnv("EMAIL_VERIFICATION", EmailVerificationOff)
+	switch verifyMode {
+	case EmailVerificationOff, EmailVerificationLogin, EmailVerificationActions:
+	default:
+		return nil, fmt.Errorf("config: invalid EMAIL_VERIFICATION %q (want off, login or actions)", verifyMode)
+	}
+
 	return &Config{
-		Port:               getEnv("PORT", "8080"),
-		MongoURI:           getEnv("MONGODB_URI", "mongodb://localhost:27017"),
-		MongoDB:            getEnv("MONGODB_DB", "foodsvc"),
-		JWTSecret:          jwtSecret,
-		AccessTokenTTL:     accessTTL,
-		RefreshTokenTTL:    refreshTTL,
-		CORSAllowedOrigins: splitOrigins(origins),
+		Env:                     env,
+		Port:                    getEnv("PORT", "8080"),
+		MongoURI:                getEnv("MONGODB_URI", "mongodb://localhost:27017"),
+		MongoDB:                 getEnv("MONGODB_DB", "foodsvc"),
+		JWTSecret:               jwtSecret,
+		JWTSigningKeyFile:       signingKeyFile,
+		JWTRetiredKeyFiles:      splitList(getEnv("JWT_RETIRED_KEY_FILES", "")),
+		AccessTokenTTL:          accessTTL,
+		RefreshTokenTTL:         refreshTTL,
+		CORSAllowedOrigins:      splitList(origins),
+		LoginMaxFailures:        maxFailures,
+		LoginIPMaxFailures:      ipMaxFailures,
+		LoginFailureWindow:      failureWindow,
+		LoginLockout:            lockout,
+		LoginBaseDelay:          baseDelay,
+		PasswordResetTTL:        resetTTL,
+		Notifier:                notifier,
+		NotifierFile:            getEnv("NOTIFIER_FILE", "notifications.log"),
+		NotifierWebhookURL:      webhookURL,
+		NotifierWebhookToken:    getEnv("NOTIFIER_WEBHOOK_TOKEN", ""),
+		EmailVerification:       verifyMode,
+		EmailVerificationTTL:    verifyTTL,
+		MFARequiredRoles:        splitList(getEnv("MFA_REQUIRED_ROLES", "")),
+		MFAIssuer:               getEnv("MFA_ISSUER", "Food Service"),
+		MFAChallengeTTL:         mfaChallengeTTL,
+		PasswordHashAlgorithm:   getEnv("PASSWORD_HASH_ALGORITHM", "argon2id"),
+		PasswordBcryptCost:      bcryptCost,
+		PasswordArgon2Memory:    argon2Memory,
+		PasswordArgon2Time:      argon2Time,
+		PasswordArgon2Threads:   argon2Threads,
+		PasswordMinLength:       minLength,
+		PasswordMaxLength:       maxLength,
+		PasswordRequiredClasses: splitList(getEnv("PASSWORD_REQUIRED_CLASSES", "letter,digit")),
+		PasswordMaxRepeat:       maxRepeat,
+		PasswordBlocklist:       getEnv("PASSWORD_BLOCKLIST", "builtin"),
+		MigrateOnStart:          migrateOnStart,
+		OIDCProviders:           oidcProviders,
+		OIDCStateTTL:            oidcStateTTL,
 	}, nil
 }
 
+// loadOIDCProviders reads the providers named in OIDC_PROVIDERS. A provider
+// "google" is configured by OIDC_GOOGLE_ISSUER, OIDC_GOOGLE_CLIENT_ID,
+// OIDC_GOOGLE_CLIENT_SECRET, OIDC_GOOGLE_REDIRECT_URL and OIDC_GOOGLE_SCOPES.
+func loadOIDCProviders() ([]OIDCProvider, error) {
+	names := splitList(getEnv("OIDC_PROVIDERS", ""))
+	providers := make([]OIDCProvider, 0, len(names))
+	for _, name := range names {
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		p := OIDCProvider{
+			Name:         strings.ToLower(name),
+			Issuer:       getEnv(prefix+"ISSUER", ""),
+			ClientID:     getEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret: getEnv(prefix+"CLIENT_SECRET", ""),
+			RedirectURL:  getEnv(prefix+"REDIRECT_URL", ""),
+			Scopes:       splitList(getEnv(prefix+"SCOPES", "openid,email,profile")),
+		}
+		if p.Issuer == "" || p.ClientID == "" || p.RedirectURL == "" {
+			return nil, fmt.Errorf("config: OIDC provider %q needs %sISSUER, %sCLIENT_ID and %sREDIRECT_URL", name, prefix, prefix, prefix)
+		}
+		providers = append(providers, p)
+	}
+	return providers, nil
+}
+
 // getEnv returns the value of an environment variable or a fallback default.
 func getEnv(key, fallback string) string {
 	if v := os.Getenv(key); v != "" {
@@ -62,14 +295,49 @@ func getEnv(key, fallback string) string {
 	return fallback
 }
 
-// splitOrigins splits a comma-separated origins string into a slice.
-func splitOrigins(raw string) []string {
+// getDuration parses a duration environment variable with a fallback default.
+func getDuration(key, fallback string) (time.Duration, error) {
+	d, err := time.ParseDuration(getEnv(key, fallback))
+	if err != nil {
+		return 0, fmt.Errorf("config: invalid %s: %w", key, err)
+	}
+	return d, nil
+}
+
+// getInt parses an integer environment variable with a fallback default.
+func getInt(key string, fallback int) (int, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("config: invalid %s: %w", key, err)
+	}
+	return n, nil
+}
+
+// getBool parses a boolean environment variable with a fallback default.
+func getBool(key string, fallback bool) (bool, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("config: invalid %s: %w", key, err)
+	}
+	return b, nil
+}
+
+// splitList splits a comma-separated string into a slice, dropping blanks.
+func splitList(raw string) []string {
 	parts := strings.Split(raw, ",")
-	origins := make([]string, 0, len(parts))
+	items := make([]string, 0, len(parts))
 	for _, p := range parts {
 		if t := strings.TrimSpace(p); t != "" {
-			origins = append(origins, t)
+			items = append(items, t)
 		}
 	}
-	return origins
+	return items
 }
@@ -0,0 +1,305 @@
+// Package migrate applies ordered, versioned schema and data migrations to
+// MongoDB. Applied versions are recorded in the schema_migrations collection,
+// and a lease-based lock ensures only one process migrates at a time.
+package migrate
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Func changes the database in one direction of a migration.
+type Func func(ctx context.Context, db *mongo.Database) error
+
+// Migration is a single versioned change. Versions must be positive and
+// unique; migrations are applied in ascending version order. Down may be nil
+// for changes that cannot be undone, such as one-way backfills.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      Func
+	Down    Func
+}
+
+// Status reports whether a known migration has been applied.
+type Status struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// Applied reports whether the migration has been applied.
+func (s Status) Applied() bool {
+	return s.AppliedAt != nil
+}
+
+// record is the schema_migrations document for an applied migration.
+type record struct {
+	Version   int64     `bson:"_id"`
+	Name      string    `bson:"name"`
+	AppliedAt time.Time `bson:"applied_at"`
+}
+
+// lockID is the _id of the single lock document.
+const lockID = "schema_migrations"
+
+// DefaultLockTTL is how long a lock is held without being renewed. The lock
+// is renewed after every migration, so this bounds a single migration's run
+// time and how long a crashed process blocks others.
+const DefaultLockTTL = 15 * time.Minute
+
+// Migrator applies a set of migrations to a database.
+type Migrator struct {
+	records    *mongo.Collection
+	locks      *mongo.Collection
+	db         *mongo.Database
+	migrations []Migration
+	owner      string
+	lockTTL    time.Duration
+}
+
+// New returns a Migrator for the given migrations, which need not be sorted.
+// It fails if a version is not positive or is used twice.
+func New(db *mongo.Database, migrations []Migration) (*Migrator, error) {
+	sorted, err := Sort(migrations)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{
+		records:    db.Collection("schema_migrations"),
+		locks:      db.Collection("schema_migrations_lock"),
+		db:         db,
+		migrations: sorted,
+		owner:      newOwnerID(),
+		lockTTL:    DefaultLockTTL,
+	}, nil
+}
+
+// Sort returns the migrations in ascending version order after checking that
+// every version is positive and unique and every migration has an Up func.
+func Sort(migrations []Migration) ([]Migration, error) {
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+
+	for i, m := range sorted {
+		if m.Version <= 0 {
+			return nil, fmt.Errorf("migrate: %q has non-positive version %d", m.Name, m.Version)
+		}
+		if m.Up == nil {
+			return nil, fmt.Errorf("migrate: %d_%s has no Up func", m.Version, m.Name)
+		}
+		if i > 0 && sorted[i-1].Version == m.Version {
+			return nil, fmt.Errorf("migrate: version %d used by both %q and %q", m.Version, sorted[i-1].Name, m.Name)
+		}
+	}
+	return sorted, nil
+}
+
+// Status lists every known migration with its applied time, if any.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		st := Status{Version: mig.Version, Name: mig.Name}
+		if rec, ok := applied[mig.Version]; ok {
+			at := rec.AppliedAt
+			st.AppliedAt = &at
+		}
+		statuses = append(statuses, st)
+	}
+	return statuses, nil
+}
+
+// Pending returns the migrations that have not been applied yet.
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.Version]; !ok {
+			pending = append(pending, mig)
+		}
+	}
+	return pending, nil
+}
+
+// Up applies pending migrations in order, up to and including version to.
+// A zero to applies everything. It returns the migrations it applied; on
+// error, those applied before the failure stay recorded.
+func (m *Migrator) Up(ctx context.Context, to int64) ([]Migration, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.unlock()
+
+	// Read the applied set only once the lock is held, so migrations that
+	// another process finished just before are not run twice.
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, mig := range pending {
+		if to > 0 && mig.Version > to {
+			break
+		}
+
+		start := time.Now()
+		if err = mig.Up(ctx, m.db); err != nil {
+			return done, fmt.Errorf("migrate: up %d_%s: %w", mig.Version, mig.Name, err)
+		}
+		rec := record{Version: mig.Version, Name: mig.Name, AppliedAt: time.Now().UTC()}
+		if _, err = m.records.InsertOne(ctx, rec); err != nil {
+			return done, fmt.Errorf("migrate: record %d_%s: %w", mig.Version, mig.Name, err)
+		}
+		slog.Info("migration applied", "version", mig.Version, "name", mig.Name, "duration", time.Since(start).String())
+		done = append(done, mig)
+
+		if err = m.renew(ctx); err != nil {
+			return done, err
+		}
+	}
+	return done, nil
+}
+
+// Down rolls back the most recently applied migrations, newest first, up to
+// steps of them. It stops with ErrIrreversible at a migration without a Down
+// func. It returns the migrations it rolled back.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	if err := m.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.unlock()
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		mig := m.migrations[i]
+		if _, ok := applied[mig.Version]; !ok {
+			continue
+		}
+		if mig.Down == nil {
+			return done, fmt.Errorf("migrate: down %d_%s: %w", mig.Version, mig.Name, ErrIrreversible)
+		}
+
+		if err = mig.Down(ctx, m.db); err != nil {
+			return done, fmt.Errorf("migrate: down %d_%s: %w", mig.Version, mig.Name, err)
+		}
+		if _, err = m.records.DeleteOne(ctx, bson.M{"_id": mig.Version}); err != nil {
+			return done, fmt.Errorf("migrate: unrecord %d_%s: %w", mig.Version, mig.Name, err)
+		}
+		slog.Info("migration rolled back", "version", mig.Version, "name", mig.Name)
+		done = append(done, mig)
+
+		if err = m.renew(ctx); err != nil {
+			return done, err
+		}
+	}
+	return done, nil
+}
+
+// applied returns the recorded migrations keyed by version.
+func (m *Migrator) applied(ctx context.Context) (map[int64]record, error) {
+	cursor, err := m.records.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("migrate: list applied: %w", err)
+	}
+	var recs []record
+	if err = cursor.All(ctx, &recs); err != nil {
+		return nil, fmt.Errorf("migrate: decode applied: %w", err)
+	}
+
+	applied := make(map[int64]record, len(recs))
+	for _, rec := range recs {
+		applied[rec.Version] = rec
+	}
+	return applied, nil
+}
+
+// lock takes the migration lock, or fails with ErrLocked if another process
+// holds an unexpired lease. An expired lease is taken over.
+func (m *Migrator) lock(ctx context.Context) error {
+	now := time.Now().UTC()
+	_, err := m.locks.UpdateOne(ctx,
+		bson.M{"_id": lockID, "expires_at": bson.M{"$lte": now}},
+		bson.M{"$set": bson.M{
+			"owner":       m.owner,
+			"acquired_at": now,
+			"expires_at":  now.Add(m.lockTTL),
+		}},
+		options.Update().SetUpsert(true),
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		// The document exists and its lease has not expired.
+		return ErrLocked
+	}
+	if err != nil {
+		return fmt.Errorf("migrate: lock: %w", err)
+	}
+	return nil
+}
+
+// renew extends the lease, failing with ErrLockLost if it was taken over.
+func (m *Migrator) renew(ctx context.Context) error {
+	res, err := m.locks.UpdateOne(ctx,
+		bson.M{"_id": lockID, "owner": m.owner},
+		bson.M{"$set": bson.M{"expires_at": time.Now().UTC().Add(m.lockTTL)}},
+	)
+	if err != nil {
+		return fmt.Errorf("migrate: renew lock: %w", err)
+	}
+	if res.MatchedCount == 0 {
+		return ErrLockLost
+	}
+	return nil
+}
+
+// unlock releases the lock if this Migrator still holds it.
+func (m *Migrator) unlock() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := m.locks.DeleteOne(ctx, bson.M{"_id": lockID, "owner": m.owner}); err != nil {
+		slog.Error("failed to release migration lock", "error", err)
+	}
+}
+
+// newOwnerID identifies this process in the lock document.
+func newOwnerID() string {
+	host, _ := os.Hostname()
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return fmt.Sprintf("%s/%d/%s", host, os.Getpid(), hex.EncodeToString(b))
+}
+
+// ErrLocked indicates another process holds the migration lock.
+var ErrLocked = fmt.Errorf("migrate: another process holds the migration lock")
+
+// ErrLockLost indicates the lock expired mid-run and another process took it over.
+var ErrLockLost = fmt.Errorf("migrate: migration lock expired and was taken over")
+
+// ErrIrreversible indicates a migration has no Down func.
+var ErrIrreversible = fmt.Errorf("migration cannot be rolled back")
@@ -0,0 +1,52 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/one-backend-go/internal/db/migrate"
+	"github.com/one-backend-go/internal/domain/auth"
+)
+
+// hashLegacyRefreshTokens replaces refresh tokens stored in plaintext, from
+// before tokens were hashed, with their digest. It cannot be undone.
+var hashLegacyRefreshTokens = migrate.Migration{
+	Version: 1,
+	Name:    "hash_legacy_refresh_tokens",
+	Up: func(ctx context.Context, db *mongo.Database) error {
+		col := db.Collection("refresh_tokens")
+		cursor, err := col.Find(ctx, bson.M{
+			"token":      bson.M{"$exists": true},
+			"token_hash": bson.M{"$exists": false},
+		})
+		if err != nil {
+			return fmt.Errorf("find plaintext tokens: %w", err)
+		}
+		defer cursor.Close(ctx)
+
+		for cursor.Next(ctx) {
+			var doc struct {
+				ID    primitive.ObjectID `bson:"_id"`
+				Token string             `bson:"token"`
+			}
+			if err = cursor.Decode(&doc); err != nil {
+				return fmt.Errorf("decode token: %w", err)
+			}
+			_, err = col.UpdateOne(ctx,
+				bson.M{"_id": doc.ID},
+				bson.M{
+					"$set":   bson.M{"token_hash": auth.HashRefreshToken(doc.Token)},
+					"$unset": bson.M{"token": ""},
+				},
+			)
+			if err != nil {
+				return fmt.Errorf("hash token %s: %w", doc.ID.Hex(), err)
+			}
+		}
+		return cursor.Err()
+	},
+}
@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/one-backend-go/internal/db/migrate"
+)
+
+// backfillEmailVerified marks accounts created before email verification
+// existed as verified, so enabling EMAIL_VERIFICATION does not lock them
+// out. It cannot be undone: afterwards they are indistinguishable from
+// accounts that verified normally.
+var backfillEmailVerified = migrate.Migration{
+	Version: 2,
+	Name:    "backfill_email_verified",
+	Up: func(ctx context.Context, db *mongo.Database) error {
+		_, err := db.Collection("users").UpdateMany(ctx,
+			bson.M{"email_verified": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"email_verified": true}},
+		)
+		if err != nil {
+			return fmt.Errorf("backfill users: %w", err)
+		}
+		return nil
+	},
+}
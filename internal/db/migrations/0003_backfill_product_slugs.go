@@ -0,0 +1,71 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/one-backend-go/internal/db/migrate"
+	"github.com/one-backend-go/internal/domain/product"
+	"github.com/one-backend-go/internal/pkg/slug"
+)
+
+// backfillProductSlugs gives products created before slugs existed a unique
+// slug made from their name, oldest product first, so the oldest keeps the
+// plain slug. It cannot be undone: clients may have stored the slugs.
+var backfillProductSlugs = migrate.Migration{
+	Version: 3,
+	Name:    "backfill_product_slugs",
+	Up: func(ctx context.Context, db *mongo.Database) error {
+		col := db.Collection("products")
+
+		taken := map[string]bool{}
+		cursor, err := col.Find(ctx, bson.M{"slug": bson.M{"$type": "string"}},
+			options.Find().SetProjection(bson.M{"slug": 1}))
+		if err != nil {
+			return fmt.Errorf("find slugs: %w", err)
+		}
+		var existing []struct {
+			Slug string `bson:"slug"`
+		}
+		if err = cursor.All(ctx, &existing); err != nil {
+			return fmt.Errorf("decode slugs: %w", err)
+		}
+		for _, e := range existing {
+			taken[e.Slug] = true
+		}
+
+		cursor, err = col.Find(ctx, bson.M{"slug": bson.M{"$not": bson.M{"$type": "string"}}},
+			options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}))
+		if err != nil {
+			return fmt.Errorf("find products: %w", err)
+		}
+		defer cursor.Close(ctx)
+
+		for cursor.Next(ctx) {
+			var doc struct {
+				ID   primitive.ObjectID `bson:"_id"`
+				Name string             `bson:"name"`
+			}
+			if err = cursor.Decode(&doc); err != nil {
+				return fmt.Errorf("decode product: %w", err)
+			}
+
+			base := product.BaseSlug(doc.Name)
+			candidate := base
+			for n := 2; taken[candidate]; n++ {
+				candidate = slug.WithSuffix(base, n)
+			}
+			taken[candidate] = true
+
+			if _, err = col.UpdateOne(ctx, bson.M{"_id": doc.ID}, bson.M{"$set": bson.M{"slug": candidate}}); err != nil {
+				return fmt.Errorf("set slug %s: %w", doc.ID.Hex(), err)
+			}
+		}
+		return cursor.Err()
+	},
+}
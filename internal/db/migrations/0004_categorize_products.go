@@ -0,0 +1,108 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/one-backend-go/internal/db/migrate"
+	"github.com/one-backend-go/internal/pkg/slug"
+)
+
+// categorizeProducts replaces the free-form category string on products with
+// a reference to a top-level category. Strings that make the same slug, such
+// as "Pizza" and "pizza", share a category named after the first one seen.
+// It cannot be undone: categories may have been edited since.
+var categorizeProducts = migrate.Migration{
+	Version: 4,
+	Name:    "categorize_products",
+	Up: func(ctx context.Context, db *mongo.Database) error {
+		products := db.Collection("products")
+		categories := db.Collection("categories")
+
+		ids := map[string]primitive.ObjectID{}
+		cursor, err := categories.Find(ctx, bson.M{}, options.Find().SetProjection(bson.M{"slug": 1}))
+		if err != nil {
+			return fmt.Errorf("find categories: %w", err)
+		}
+		var existing []struct {
+			ID   primitive.ObjectID `bson:"_id"`
+			Slug string             `bson:"slug"`
+		}
+		if err = cursor.All(ctx, &existing); err != nil {
+			return fmt.Errorf("decode categories: %w", err)
+		}
+		for _, e := range existing {
+			ids[e.Slug] = e.ID
+		}
+		position := len(existing)
+
+		cursor, err = products.Find(ctx,
+			bson.M{"category": bson.M{"$type": "string"}, "category_id": bson.M{"$exists": false}},
+			options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}))
+		if err != nil {
+			return fmt.Errorf("find products: %w", err)
+		}
+		defer cursor.Close(ctx)
+
+		for cursor.Next(ctx) {
+			var doc struct {
+				ID       primitive.ObjectID `bson:"_id"`
+				Category string             `bson:"category"`
+			}
+			if err = cursor.Decode(&doc); err != nil {
+				return fmt.Errorf("decode product: %w", err)
+			}
+
+			name := strings.TrimSpace(doc.Category)
+			key := slug.Make(name)
+			if key == "" {
+				name, key = "Uncategorized", "uncategorized"
+			}
+			id, ok := ids[key]
+			if !ok {
+				id = primitive.NewObjectID()
+				now := time.Now().UTC()
+				_, err = categories.InsertOne(ctx, bson.M{
+					"_id":        id,
+					"name":       capitalize(name),
+					"slug":       key,
+					"parent_id":  nil,
+					"position":   position,
+					"active":     true,
+					"created_at": now,
+					"updated_at": now,
+				})
+				if err != nil {
+					return fmt.Errorf("create category %s: %w", key, err)
+				}
+				ids[key] = id
+				position++
+			}
+
+			_, err = products.UpdateOne(ctx, bson.M{"_id": doc.ID}, bson.M{
+				"$set":   bson.M{"category_id": id},
+				"$unset": bson.M{"category": ""},
+			})
+			if err != nil {
+				return fmt.Errorf("set category %s: %w", doc.ID.Hex(), err)
+			}
+		}
+		return cursor.Err()
+	},
+}
+
+// capitalize upper-cases the first letter of s, since category strings were
+// often stored in lower case.
+func capitalize(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[size:]
+}
@@ -0,0 +1,59 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/one-backend-go/internal/db/migrate"
+)
+
+// backfillProductVariants gives single-price products from older versions a
+// single "Regular" variant at their price and availability, with a SKU made
+// from their slug.
+// It cannot be undone: variants added since have no single price to return
+// to.
+var backfillProductVariants = migrate.Migration{
+	Version: 5,
+	Name:    "backfill_product_variants",
+	Up: func(ctx context.Context, db *mongo.Database) error {
+		col := db.Collection("products")
+
+		cursor, err := col.Find(ctx, bson.M{"variants": bson.M{"$exists": false}})
+		if err != nil {
+			return fmt.Errorf("find products: %w", err)
+		}
+		defer cursor.Close(ctx)
+
+		for cursor.Next(ctx) {
+			var doc struct {
+				ID          primitive.ObjectID `bson:"_id"`
+				Slug        string             `bson:"slug"`
+				PriceCents  int64              `bson:"price_cents"`
+				IsAvailable bool               `bson:"is_available"`
+			}
+			if err = cursor.Decode(&doc); err != nil {
+				return fmt.Errorf("decode product: %w", err)
+			}
+
+			sku := strings.ToUpper(doc.Slug)
+			if sku == "" {
+				sku = strings.ToUpper(doc.ID.Hex())
+			}
+			variant := bson.M{
+				"sku":          sku,
+				"name":         "Regular",
+				"price_cents":  doc.PriceCents,
+				"is_available": doc.IsAvailable,
+			}
+			if _, err = col.UpdateOne(ctx, bson.M{"_id": doc.ID}, bson.M{"$set": bson.M{"variants": bson.A{variant}}}); err != nil {
+				return fmt.Errorf("set variants %s: %w", doc.ID.Hex(), err)
+			}
+		}
+		return cursor.Err()
+	},
+}
@@ -0,0 +1,18 @@
+// Package migrations holds the application's database migrations. Add a new
+// migration in its own file, named after its version, and list it in All.
+// Released migrations must not be renumbered or edited. Indexes belong to
+// db.EnsureIndexes, which runs first; migrations change data only.
+package migrations
+
+import "github.com/one-backend-go/internal/db/migrate"
+
+// All returns every migration known to this build.
+func All() []migrate.Migration {
+	return []migrate.Migration{
+		hashLegacyRefreshTokens,
+		backfillEmailVerified,
+		backfillProductSlugs,
+		categorizeProducts,
+		backfillProductVariants,
+	}
+}
@@ -3,6 +3,7 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"time"
@@ -37,7 +38,10 @@ func Connect(ctx context.Context, uri, dbName string) (*mongo.Database, error) {
 	return client.Database(dbName), nil
 }
 
-// EnsureIndexes creates required indexes idempotently.
+// EnsureIndexes creates required indexes idempotently. It owns every index
+// and runs before migrations, which may rely on the indexes but never create
+// or drop them. A changed index gets a new name here, and the old one is
+// dropped here too, with dropIndexIfExists, before the new one is created.
 func EnsureIndexes(ctx context.Context, db *mongo.Database) error {
 	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
@@ -51,9 +55,26 @@ func EnsureIndexes(ctx context.Context, db *mongo.Database) error {
 	if err != nil {
 		return fmt.Errorf("db: index users.email: %w", err)
 	}
+	_, err = usersCol.Indexes().CreateOne(ctx, mongo.IndexModel{
+		// An external identity can be linked to one user only.
+		Keys: bson.D{{Key: "identities.provider", Value: 1}, {Key: "identities.subject", Value: 1}},
+		Options: options.Index().
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"identities.subject": bson.M{"$exists": true}}),
+	})
+	if err != nil {
+		return fmt.Errorf("db: index users.identities: %w", err)
+	}
 
 	// ── Products ───────────────────────────────────────────────────────
 	productsCol := db.Collection("products")
+
+	// Products used to carry a free-form category string; migration 4
+	// replaces it with category_id.
+	if err = dropIndexIfExists(ctx, productsCol, "category_1"); err != nil {
+		return fmt.Errorf("db: drop legacy index products: %w", err)
+	}
+
 	productIndexes := []mongo.IndexModel{
 		{
 			Keys: bson.D{
@@ -62,26 +83,121 @@ func EnsureIndexes(ctx context.Context, db *mongo.Database) error {
 			},
 		},
 		{
-			Keys: bson.D{{Key: "category", Value: 1}},
+			Keys: bson.D{{Key: "category_id", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "modifier_group_ids", Value: 1}},
 		},
 		{
 			Keys: bson.D{{Key: "created_at", Value: -1}},
 		},
+		{
+			// Partial, so products from before slugs existed do not collide
+			// on a missing slug until migration 3 gives them one.
+			Keys: bson.D{{Key: "slug", Value: 1}},
+			Options: options.Index().
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"slug": bson.M{"$type": "string"}}),
+		},
+		{
+			Keys: bson.D{{Key: "old_slugs", Value: 1}},
+		},
+		{
+			// Partial for the same reason as slug: products get variants
+			// from migration 5.
+			Keys: bson.D{{Key: "variants.sku", Value: 1}},
+			Options: options.Index().
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"variants.sku": bson.M{"$type": "string"}}),
+		},
 	}
 	_, err = productsCol.Indexes().CreateMany(ctx, productIndexes)
 	if err != nil {
 		return fmt.Errorf("db: index products: %w", err)
 	}
 
+	// ── Categories ─────────────────────────────────────────────────────
+	categoryIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "slug", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "parent_id", Value: 1}},
+		},
+	}
+	_, err = db.Collection("categories").Indexes().CreateMany(ctx, categoryIndexes)
+	if err != nil {
+		return fmt.Errorf("db: index categories: %w", err)
+	}
+
+	// ── Stock ──────────────────────────────────────────────────────────
+	_, err = db.Collection("stock_levels").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "product_id", Value: 1}, {Key: "sku", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("db: index stock_levels: %w", err)
+	}
+
+	// Not a TTL index: an expired reservation still holds stock until it
+	// is released.
+	_, err = db.Collection("stock_reservations").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "expires_at", Value: 1}},
+	})
+	if err != nil {
+		return fmt.Errorf("db: index stock_reservations: %w", err)
+	}
+
+	ledgerIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "product_id", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "product_id", Value: 1}, {Key: "sku", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+	}
+	_, err = db.Collection("stock_ledger").Indexes().CreateMany(ctx, ledgerIndexes)
+	if err != nil {
+		return fmt.Errorf("db: index stock_ledger: %w", err)
+	}
+
+	// ── Roles ──────────────────────────────────────────────────────────
+	_, err = db.Collection("roles").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "name", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("db: index roles.name: %w", err)
+	}
+
 	// ── Refresh Tokens ─────────────────────────────────────────────────
 	rtCol := db.Collection("refresh_tokens")
+
+	// Tokens used to be stored in plaintext under a unique (user_id, token)
+	// index. Hashed documents have no "token" field, so that index would
+	// reject a user's second session; drop it if it is still around.
+	if err = dropIndexIfExists(ctx, rtCol, "user_id_1_token_1"); err != nil {
+		return fmt.Errorf("db: drop legacy index refresh_tokens: %w", err)
+	}
+
 	rtIndexes := []mongo.IndexModel{
 		{
-			Keys: bson.D{
-				{Key: "user_id", Value: 1},
-				{Key: "token", Value: 1},
-			},
-			Options: options.Index().SetUnique(true),
+			Keys: bson.D{{Key: "token_hash", Value: 1}},
+			Options: options.Index().
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"token_hash": bson.M{"$exists": true}}),
+		},
+		{
+			// Lookup path for legacy plaintext tokens until they expire.
+			Keys:    bson.D{{Key: "token", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "family_id", Value: 1}},
 		},
 		{
 			Keys:    bson.D{{Key: "expires_at", Value: 1}},
@@ -93,10 +209,79 @@ func EnsureIndexes(ctx context.Context, db *mongo.Database) error {
 		return fmt.Errorf("db: index refresh_tokens: %w", err)
 	}
 
+	// ── Login Attempts ─────────────────────────────────────────────────
+	_, err = db.Collection("login_attempts").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0), // TTL index
+	})
+	if err != nil {
+		return fmt.Errorf("db: index login_attempts: %w", err)
+	}
+
+	// ── Action Tokens ──────────────────────────────────────────────────
+	atIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "token_hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "purpose", Value: 1}},
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0), // TTL index
+		},
+	}
+	_, err = db.Collection("action_tokens").Indexes().CreateMany(ctx, atIndexes)
+	if err != nil {
+		return fmt.Errorf("db: index action_tokens: %w", err)
+	}
+
+	// ── API Keys ───────────────────────────────────────────────────────
+	_, err = db.Collection("api_keys").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "key_hash", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("db: index api_keys: %w", err)
+	}
+
+	// ── OIDC Login States ──────────────────────────────────────────────
+	stateIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "state_hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0), // TTL index
+		},
+	}
+	_, err = db.Collection("oidc_states").Indexes().CreateMany(ctx, stateIndexes)
+	if err != nil {
+		return fmt.Errorf("db: index oidc_states: %w", err)
+	}
+
 	slog.Info("database indexes ensured")
 	return nil
 }
 
+// dropIndexIfExists drops the named index, ignoring a missing index or collection.
+func dropIndexIfExists(ctx context.Context, col *mongo.Collection, name string) error {
+	_, err := col.Indexes().DropOne(ctx, name)
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && (cmdErr.Code == codeIndexNotFound || cmdErr.Code == codeNamespaceNotFound) {
+		return nil
+	}
+	return err
+}
+
+// MongoDB server error codes.
+const (
+	codeNamespaceNotFound = 26
+	codeIndexNotFound     = 27
+)
+
 // Disconnect gracefully closes the MongoDB connection.
 func Disconnect(ctx context.Context, db *mongo.Database) error {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
@@ -0,0 +1,109 @@
+package actiontoken
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Repository provides persistence operations for action tokens.
+type Repository struct {
+	col *mongo.Collection
+}
+
+// NewRepository returns a new action token Repository.
+func NewRepository(db *mongo.Database) *Repository {
+	return &Repository{col: db.Collection("action_tokens")}
+}
+
+// Create inserts a new action token document.
+func (r *Repository) Create(ctx context.Context, t *ActionToken) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	t.ID = primitive.NewObjectID()
+	t.CreatedAt = time.Now().UTC()
+
+	if _, err := r.col.InsertOne(ctx, t); err != nil {
+		return fmt.Errorf("actiontoken repo create: %w", err)
+	}
+	return nil
+}
+
+// Consume atomically marks an unused, unexpired token as used and returns it.
+// It returns nil if no such token exists.
+func (r *Repository) Consume(ctx context.Context, purpose, tokenHash string, now time.Time) (*ActionToken, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var t ActionToken
+	err := r.col.FindOneAndUpdate(ctx,
+		bson.M{
+			"purpose":    purpose,
+			"token_hash": tokenHash,
+			"used_at":    bson.M{"$exists": false},
+			"expires_at": bson.M{"$gt": now},
+		},
+		bson.M{"$set": bson.M{"used_at": now}},
+	).Decode(&t)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("actiontoken repo consume: %w", err)
+	}
+	return &t, nil
+}
+
+// DeleteOutstanding removes a user's unused tokens for a purpose, so only the
+// most recently issued one stays valid.
+func (r *Repository) DeleteOutstanding(ctx context.Context, userID primitive.ObjectID, purpose string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := r.col.DeleteMany(ctx, bson.M{
+		"user_id": userID,
+		"purpose": purpose,
+		"used_at": bson.M{"$exists": false},
+	})
+	if err != nil {
+		return fmt.Errorf("actiontoken repo deleteOutstanding: %w", err)
+	}
+	return nil
+}
+
+// ListForUser returns every token issued to a user, newest first.
+func (r *Repository) ListForUser(ctx context.Context, userID primitive.ObjectID) ([]ActionToken, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := r.col.Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("actiontoken repo listForUser: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var tokens []ActionToken
+	if err = cursor.All(ctx, &tokens); err != nil {
+		return nil, fmt.Errorf("actiontoken repo listForUser decode: %w", err)
+	}
+	return tokens, nil
+}
+
+// DeleteAllForUser removes every token issued to a user, used or not.
+func (r *Repository) DeleteAllForUser(ctx context.Context, userID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if _, err := r.col.DeleteMany(ctx, bson.M{"user_id": userID}); err != nil {
+		return fmt.Errorf("actiontoken repo deleteAllForUser: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package actiontoken
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Service issues and redeems action tokens.
+type Service struct {
+	repo *Repository
+}
+
+// NewService creates a new action token Service.
+func NewService(repo *Repository) *Service {
+	return &Service{repo: repo}
+}
+
+// Issue creates a token for the user and purpose, valid for ttl. Any earlier
+// unused token for the same purpose is invalidated. The plaintext token is
+// returned once and never stored.
+func (s *Service) Issue(ctx context.Context, userID primitive.ObjectID, purpose string, ttl time.Duration) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("actiontoken generate: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	if err := s.repo.DeleteOutstanding(ctx, userID, purpose); err != nil {
+		return "", err
+	}
+
+	t := &ActionToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: Hash(token),
+		ExpiresAt: time.Now().UTC().Add(ttl),
+	}
+	if err := s.repo.Create(ctx, t); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Consume redeems a token for the given purpose and returns the user it was
+// issued to. Each token can be consumed once.
+func (s *Service) Consume(ctx context.Context, purpose, token string) (primitive.ObjectID, error) {
+	t, err := s.repo.Consume(ctx, purpose, Hash(token), time.Now().UTC())
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	if t == nil {
+		return primitive.NilObjectID, ErrInvalidToken
+	}
+	return t.UserID, nil
+}
+
+// ExportUserData returns the tokens issued to a user for a data export.
+func (s *Service) ExportUserData(ctx context.Context, userID primitive.ObjectID) (interface{}, error) {
+	tokens, err := s.repo.ListForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]Export, 0, len(tokens))
+	for _, t := range tokens {
+		out = append(out, Export{Purpose: t.Purpose, CreatedAt: t.CreatedAt, ExpiresAt: t.ExpiresAt, UsedAt: t.UsedAt})
+	}
+	return out, nil
+}
+
+// EraseUserData deletes every token issued to a user.
+func (s *Service) EraseUserData(ctx context.Context, userID primitive.ObjectID) error {
+	return s.repo.DeleteAllForUser(ctx, userID)
+}
+
+// Hash returns the hex-encoded SHA-256 digest under which a token is stored.
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ErrInvalidToken indicates the token is unknown, expired, or already used.
+var ErrInvalidToken = fmt.Errorf("invalid or expired token")
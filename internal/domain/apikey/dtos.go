@@ -0,0 +1,53 @@
+package apikey
+
+import "time"
+
+// ── Request DTOs ───────────────────────────────────────────────────────────────
+
+// CreateRequest is the body for POST /api/v1/admin/api-keys.
+type CreateRequest struct {
+	Name      string     `json:"name"       validate:"required,min=2,max=80"`
+	Scopes    []string   `json:"scopes"     validate:"required,min=1,dive,required"`
+	ExpiresAt *time.Time `json:"expires_at"` // omit for a key that never expires
+}
+
+// ── Response DTOs ──────────────────────────────────────────────────────────────
+
+// KeyResponse describes an API key without the key itself.
+type KeyResponse struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedBy  string     `json:"created_by"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP string     `json:"last_used_ip,omitempty"`
+	UseCount   int64      `json:"use_count"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreatedResponse is returned once, when a key is created. The key cannot be
+// retrieved again.
+type CreatedResponse struct {
+	KeyResponse
+	Key string `json:"key"`
+}
+
+// ToResponse converts an APIKey model to its public response form.
+func (k *APIKey) ToResponse() KeyResponse {
+	return KeyResponse{
+		ID:         k.ID.Hex(),
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     k.Scopes,
+		CreatedBy:  k.CreatedBy.Hex(),
+		ExpiresAt:  k.ExpiresAt,
+		RevokedAt:  k.RevokedAt,
+		LastUsedAt: k.LastUsedAt,
+		LastUsedIP: k.LastUsedIP,
+		UseCount:   k.UseCount,
+		CreatedAt:  k.CreatedAt,
+	}
+}
@@ -0,0 +1,95 @@
+package apikey
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/one-backend-go/internal/pkg/reqctx"
+	"github.com/one-backend-go/internal/pkg/resp"
+	"github.com/one-backend-go/internal/pkg/validate"
+)
+
+// Handler holds HTTP handlers for API key management endpoints.
+type Handler struct {
+	svc      *Service
+	validate *validate.Validator
+}
+
+// NewHandler creates a new API key Handler.
+func NewHandler(svc *Service, v *validate.Validator) *Handler {
+	return &Handler{svc: svc, validate: v}
+}
+
+// List handles GET /api/v1/admin/api-keys.
+func (h *Handler) List(c *gin.Context) {
+	keys, err := h.svc.List(c.Request.Context())
+	if err != nil {
+		resp.InternalError(c)
+		return
+	}
+
+	resp.Success(c, http.StatusOK, gin.H{"items": keys})
+}
+
+// Create handles POST /api/v1/admin/api-keys.
+func (h *Handler) Create(c *gin.Context) {
+	actorID, _ := reqctx.UserID(c)
+
+	var req CreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.Fail(c, http.StatusBadRequest, "BAD_REQUEST", "invalid JSON body", nil)
+		return
+	}
+
+	if errs := h.validate.Struct(req); errs != nil {
+		resp.ValidationError(c, errs)
+		return
+	}
+
+	created, err := h.svc.Create(c.Request.Context(), actorID, req)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+
+	resp.Success(c, http.StatusCreated, created)
+}
+
+// Revoke handles DELETE /api/v1/admin/api-keys/:id.
+func (h *Handler) Revoke(c *gin.Context) {
+	actorID, _ := reqctx.UserID(c)
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		resp.NotFound(c, "api key not found")
+		return
+	}
+
+	k, err := h.svc.Revoke(c.Request.Context(), actorID, id)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+
+	resp.Success(c, http.StatusOK, k)
+}
+
+// fail maps API key service errors to HTTP responses.
+func (h *Handler) fail(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrKeyNotFound):
+		resp.NotFound(c, "api key not found")
+	case errors.Is(err, ErrKeyRevoked):
+		resp.Conflict(c, "api key already revoked")
+	case errors.Is(err, ErrScopeNotGrantable):
+		resp.ValidationError(c, map[string]string{"scopes": err.Error()})
+	case errors.Is(err, ErrScopeNotHeld):
+		resp.Forbidden(c, err.Error())
+	case errors.Is(err, ErrExpiryInPast):
+		resp.ValidationError(c, map[string]string{"expires_at": err.Error()})
+	default:
+		resp.InternalError(c)
+	}
+}
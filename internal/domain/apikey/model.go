@@ -0,0 +1,28 @@
+// Package apikey issues long-lived, scoped API keys for machine clients such
+// as point-of-sale terminals and internal jobs. Only key digests are stored.
+package apikey
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// KeyPrefix starts every API key, so leaked keys are easy to recognize.
+const KeyPrefix = "fsk_"
+
+// APIKey is a key stored in MongoDB. Scopes are role permissions.
+type APIKey struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty"`
+	Name       string             `bson:"name"`
+	Prefix     string             `bson:"prefix"` // first characters of the key, for display
+	KeyHash    string             `bson:"key_hash"`
+	Scopes     []string           `bson:"scopes"`
+	CreatedBy  primitive.ObjectID `bson:"created_by"`
+	ExpiresAt  *time.Time         `bson:"expires_at,omitempty"`
+	RevokedAt  *time.Time         `bson:"revoked_at,omitempty"`
+	LastUsedAt *time.Time         `bson:"last_used_at,omitempty"`
+	LastUsedIP string             `bson:"last_used_ip,omitempty"`
+	UseCount   int64              `bson:"use_count"`
+	CreatedAt  time.Time          `bson:"created_at"`
+}
@@ -0,0 +1,124 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Repository provides persistence operations for API keys.
+type Repository struct {
+	col *mongo.Collection
+}
+
+// NewRepository returns a new API key Repository.
+func NewRepository(db *mongo.Database) *Repository {
+	return &Repository{col: db.Collection("api_keys")}
+}
+
+// Create inserts a new API key document.
+func (r *Repository) Create(ctx context.Context, k *APIKey) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	k.ID = primitive.NewObjectID()
+	k.CreatedAt = time.Now().UTC()
+
+	if _, err := r.col.InsertOne(ctx, k); err != nil {
+		return fmt.Errorf("apikey repo create: %w", err)
+	}
+	return nil
+}
+
+// FindByID retrieves an API key by ID.
+func (r *Repository) FindByID(ctx context.Context, id primitive.ObjectID) (*APIKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var k APIKey
+	err := r.col.FindOne(ctx, bson.M{"_id": id}).Decode(&k)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("apikey repo findByID: %w", err)
+	}
+	return &k, nil
+}
+
+// List returns every API key, newest first.
+func (r *Repository) List(ctx context.Context) ([]APIKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := r.col.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("apikey repo list: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	keys := []APIKey{}
+	if err = cursor.All(ctx, &keys); err != nil {
+		return nil, fmt.Errorf("apikey repo list decode: %w", err)
+	}
+	return keys, nil
+}
+
+// Use atomically records a use of the active key with the given digest and
+// returns it. It returns nil if no active key matches.
+func (r *Repository) Use(ctx context.Context, keyHash, ip string, now time.Time) (*APIKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var k APIKey
+	err := r.col.FindOneAndUpdate(ctx,
+		bson.M{
+			"key_hash":   keyHash,
+			"revoked_at": bson.M{"$exists": false},
+			"$or": bson.A{
+				bson.M{"expires_at": bson.M{"$exists": false}},
+				bson.M{"expires_at": bson.M{"$gt": now}},
+			},
+		},
+		bson.M{
+			"$set": bson.M{"last_used_at": now, "last_used_ip": ip},
+			"$inc": bson.M{"use_count": 1},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&k)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("apikey repo use: %w", err)
+	}
+	return &k, nil
+}
+
+// Revoke marks an unrevoked key as revoked and returns it. It returns nil if
+// the key does not exist or was already revoked.
+func (r *Repository) Revoke(ctx context.Context, id primitive.ObjectID, now time.Time) (*APIKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var k APIKey
+	err := r.col.FindOneAndUpdate(ctx,
+		bson.M{"_id": id, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": now}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&k)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("apikey repo revoke: %w", err)
+	}
+	return &k, nil
+}
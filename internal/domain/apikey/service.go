@@ -0,0 +1,180 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/one-backend-go/internal/domain/role"
+)
+
+// displayPrefixLen is how much of a key is kept in clear to identify it.
+const displayPrefixLen = len(KeyPrefix) + 8
+
+// GrantableScopes lists the permissions a key may carry: those required by
+// the routes that accept keys. Wildcards are never granted to keys.
+var GrantableScopes = []string{role.PermProductsWrite, role.PermInventoryManage}
+
+// Users resolves the permissions a user currently holds. It is implemented
+// by the user service.
+type Users interface {
+	Permissions(ctx context.Context, userID primitive.ObjectID) ([]string, error)
+}
+
+// Service creates, authenticates and revokes API keys.
+type Service struct {
+	repo  *Repository
+	users Users
+}
+
+// NewService creates a new API key Service.
+func NewService(repo *Repository, users Users) *Service {
+	return &Service{repo: repo, users: users}
+}
+
+// Create mints a key on an administrator's behalf. The plaintext key is
+// returned once and never stored. Keys carry only grantable scopes that the
+// administrator holds themselves.
+func (s *Service) Create(ctx context.Context, actorID primitive.ObjectID, req CreateRequest) (*CreatedResponse, error) {
+	if err := s.checkScopes(ctx, actorID, req.Scopes); err != nil {
+		return nil, err
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, ErrExpiryInPast
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("apikey generate: %w", err)
+	}
+	key := KeyPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	k := &APIKey{
+		Name:      req.Name,
+		Prefix:    key[:displayPrefixLen],
+		KeyHash:   Hash(key),
+		Scopes:    role.NormalizePermissions(req.Scopes),
+		CreatedBy: actorID,
+	}
+	if req.ExpiresAt != nil {
+		at := req.ExpiresAt.UTC()
+		k.ExpiresAt = &at
+	}
+	if err := s.repo.Create(ctx, k); err != nil {
+		return nil, err
+	}
+
+	slog.Info("api key created",
+		"key_id", k.ID.Hex(), "name", k.Name, "scopes", k.Scopes, "by", actorID.Hex())
+	return &CreatedResponse{KeyResponse: k.ToResponse(), Key: key}, nil
+}
+
+// checkScopes refuses scopes that are not grantable or that the actor's
+// current role does not grant.
+func (s *Service) checkScopes(ctx context.Context, actorID primitive.ObjectID, scopes []string) error {
+	for _, sc := range scopes {
+		if !slices.Contains(GrantableScopes, sc) {
+			return fmt.Errorf("%w: %s", ErrScopeNotGrantable, sc)
+		}
+	}
+
+	held, err := s.users.Permissions(ctx, actorID)
+	if err != nil {
+		return fmt.Errorf("apikey check scopes: %w", err)
+	}
+	for _, sc := range scopes {
+		if !role.HasPermission(held, sc) {
+			return fmt.Errorf("%w: %s", ErrScopeNotHeld, sc)
+		}
+	}
+	return nil
+}
+
+// List returns every key, including revoked and expired ones.
+func (s *Service) List(ctx context.Context) ([]KeyResponse, error) {
+	keys, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]KeyResponse, 0, len(keys))
+	for i := range keys {
+		out = append(out, keys[i].ToResponse())
+	}
+	return out, nil
+}
+
+// Revoke permanently disables a key on an administrator's behalf.
+func (s *Service) Revoke(ctx context.Context, actorID, id primitive.ObjectID) (*KeyResponse, error) {
+	k, err := s.repo.Revoke(ctx, id, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	if k == nil {
+		existing, err := s.repo.FindByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if existing == nil {
+			return nil, ErrKeyNotFound
+		}
+		return nil, ErrKeyRevoked
+	}
+
+	slog.Info("api key revoked", "key_id", k.ID.Hex(), "name", k.Name, "by", actorID.Hex())
+	out := k.ToResponse()
+	return &out, nil
+}
+
+// Authenticate returns the active key matching the presented one and records
+// the use. Unknown, expired and revoked keys all fail with ErrInvalidKey.
+func (s *Service) Authenticate(ctx context.Context, key, clientIP string) (*APIKey, error) {
+	if !strings.HasPrefix(key, KeyPrefix) {
+		return nil, ErrInvalidKey
+	}
+
+	k, err := s.repo.Use(ctx, Hash(key), clientIP, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	if k == nil {
+		return nil, ErrInvalidKey
+	}
+	return k, nil
+}
+
+// Hash returns the hex-encoded SHA-256 digest under which a key is stored.
+// Keys carry 256 bits of entropy, so a fast hash is sufficient.
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// ErrKeyNotFound indicates the API key does not exist.
+var ErrKeyNotFound = fmt.Errorf("api key not found")
+
+// ErrKeyRevoked indicates the API key was already revoked.
+var ErrKeyRevoked = fmt.Errorf("api key already revoked")
+
+// ErrInvalidKey indicates a presented key is unknown, expired, or revoked.
+var ErrInvalidKey = fmt.Errorf("invalid or expired api key")
+
+// ErrExpiryInPast indicates a key was requested with an expiry that has passed.
+var ErrExpiryInPast = fmt.Errorf("expires_at must be in the future")
+
+// ErrScopeNotGrantable indicates a scope that no route accepting keys
+// requires, or a wildcard.
+var ErrScopeNotGrantable = fmt.Errorf("scope cannot be granted to api keys")
+
+// ErrScopeNotHeld indicates a scope the administrator creating the key does
+// not hold.
+var ErrScopeNotHeld = fmt.Errorf("cannot grant a scope you do not hold")
@@ -2,11 +2,15 @@ package auth
 
 import (
 	"errors"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"github.com/one-backend-go/internal/domain/user"
+	"github.com/one-backend-go/internal/pkg/reqctx"
 	"github.com/one-backend-go/internal/pkg/resp"
 	"github.com/one-backend-go/internal/pkg/validate"
 )
@@ -35,19 +39,76 @@ func (h *Handler) Login(c *gin.Context) {
 		return
 	}
 
-	tokens, err := h.svc.Login(c.Request.Context(), req.Email, req.Password)
+	tokens, challenge, err := h.svc.Login(c.Request.Context(), req.Email, req.Password, c.ClientIP())
 	if err != nil {
-		if errors.Is(err, user.ErrInvalidCredentials) {
+		var throttled *ThrottleError
+		switch {
+		case errors.As(err, &throttled):
+			FailThrottled(c, throttled)
+		case errors.Is(err, user.ErrInvalidCredentials):
 			resp.Unauthorized(c, "invalid email or password")
-			return
+		case errors.Is(err, user.ErrAccountDisabled):
+			resp.Fail(c, http.StatusForbidden, "ACCOUNT_DISABLED", "this account has been disabled", nil)
+		case errors.Is(err, user.ErrEmailNotVerified):
+			resp.Fail(c, http.StatusForbidden, "EMAIL_NOT_VERIFIED", "verify your email address before logging in", nil)
+		default:
+			resp.InternalError(c)
 		}
-		resp.InternalError(c)
 		return
 	}
 
+	if challenge != nil {
+		resp.Success(c, http.StatusOK, challenge)
+		return
+	}
 	resp.Success(c, http.StatusOK, tokens)
 }
 
+// LoginMFA handles POST /api/v1/auth/login/mfa.
+func (h *Handler) LoginMFA(c *gin.Context) {
+	var req MFALoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.Fail(c, http.StatusBadRequest, "BAD_REQUEST", "invalid JSON body", nil)
+		return
+	}
+
+	if errs := h.validate.Struct(req); errs != nil {
+		resp.ValidationError(c, errs)
+		return
+	}
+
+	tokens, err := h.svc.LoginMFA(c.Request.Context(), req.MFAToken, req.Code, c.ClientIP())
+	if err != nil {
+		var throttled *ThrottleError
+		switch {
+		case errors.As(err, &throttled):
+			FailThrottled(c, throttled)
+		case errors.Is(err, ErrInvalidMFAToken):
+			resp.Unauthorized(c, "invalid or expired MFA token")
+		case errors.Is(err, user.ErrInvalidMFACode):
+			resp.Unauthorized(c, "invalid two-factor code")
+		default:
+			resp.InternalError(c)
+		}
+		return
+	}
+
+	resp.Success(c, http.StatusOK, tokens)
+}
+
+// FailThrottled responds to a refused login or password check with 423 or
+// 429 and a Retry-After header.
+func FailThrottled(c *gin.Context, throttled *ThrottleError) {
+	retryAfter := int(math.Ceil(throttled.RetryAfter.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	details := gin.H{"retry_after": retryAfter}
+	if throttled.Locked {
+		resp.Fail(c, http.StatusLocked, "ACCOUNT_LOCKED", "account temporarily locked after too many failed logins", details)
+		return
+	}
+	resp.Fail(c, http.StatusTooManyRequests, "TOO_MANY_ATTEMPTS", "too many login attempts, slow down", details)
+}
+
 // Refresh handles POST /api/v1/auth/refresh.
 func (h *Handler) Refresh(c *gin.Context) {
 	var req RefreshRequest
@@ -73,3 +134,186 @@ func (h *Handler) Refresh(c *gin.Context) {
 
 	resp.Success(c, http.StatusOK, tokens)
 }
+
+// Logout handles POST /api/v1/auth/logout.
+func (h *Handler) Logout(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.Fail(c, http.StatusBadRequest, "BAD_REQUEST", "invalid JSON body", nil)
+		return
+	}
+
+	if errs := h.validate.Struct(req); errs != nil {
+		resp.ValidationError(c, errs)
+		return
+	}
+
+	if err := h.svc.Logout(c.Request.Context(), req.RefreshToken); err != nil {
+		if errors.Is(err, ErrInvalidRefreshToken) {
+			resp.Unauthorized(c, "invalid or expired refresh token")
+			return
+		}
+		resp.InternalError(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "logged out"})
+}
+
+// LogoutAll handles POST /api/v1/auth/logout-all (authenticated).
+func (h *Handler) LogoutAll(c *gin.Context) {
+	uid, ok := reqctx.UserID(c)
+	if !ok {
+		resp.Unauthorized(c, "authentication required")
+		return
+	}
+
+	if err := h.svc.LogoutAll(c.Request.Context(), uid); err != nil {
+		resp.InternalError(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "all sessions revoked"})
+}
+
+// ForgotPassword handles POST /api/v1/auth/password/forgot. The response is
+// the same whether or not the email is registered.
+func (h *Handler) ForgotPassword(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.Fail(c, http.StatusBadRequest, "BAD_REQUEST", "invalid JSON body", nil)
+		return
+	}
+
+	if errs := h.validate.Struct(req); errs != nil {
+		resp.ValidationError(c, errs)
+		return
+	}
+
+	if err := h.svc.ForgotPassword(c.Request.Context(), req.Email); err != nil {
+		resp.InternalError(c)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "if the account exists, a password reset link has been sent"})
+}
+
+// ResetPassword handles POST /api/v1/auth/password/reset.
+func (h *Handler) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.Fail(c, http.StatusBadRequest, "BAD_REQUEST", "invalid JSON body", nil)
+		return
+	}
+
+	if errs := h.validate.Struct(req); errs != nil {
+		resp.ValidationError(c, errs)
+		return
+	}
+
+	if err := h.svc.ResetPassword(c.Request.Context(), req.Token, req.Password); err != nil {
+		if errors.Is(err, ErrInvalidResetToken) {
+			resp.Fail(c, http.StatusBadRequest, "INVALID_TOKEN", "invalid or expired reset token", nil)
+			return
+		}
+		resp.InternalError(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "password has been reset"})
+}
+
+// ChangePassword handles POST /api/v1/users/me/password (authenticated).
+func (h *Handler) ChangePassword(c *gin.Context) {
+	uid, ok := reqctx.UserID(c)
+	if !ok {
+		resp.Unauthorized(c, "authentication required")
+		return
+	}
+
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.Fail(c, http.StatusBadRequest, "BAD_REQUEST", "invalid JSON body", nil)
+		return
+	}
+
+	if errs := h.validate.Struct(req); errs != nil {
+		resp.ValidationError(c, errs)
+		return
+	}
+
+	sid, _ := reqctx.SessionID(c)
+	err := h.svc.ChangePassword(c.Request.Context(), uid, sid, req.CurrentPassword, req.NewPassword, c.ClientIP())
+	if err != nil {
+		var throttled *ThrottleError
+		switch {
+		case errors.As(err, &throttled):
+			FailThrottled(c, throttled)
+		case errors.Is(err, ErrWrongPassword):
+			resp.Fail(c, http.StatusBadRequest, "WRONG_PASSWORD", "current password is incorrect", nil)
+		case errors.Is(err, user.ErrUserNotFound):
+			resp.Unauthorized(c, "user not found")
+		default:
+			resp.InternalError(c)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "password changed; other sessions have been signed out"})
+}
+
+// JWKS handles GET /.well-known/jwks.json.
+func (h *Handler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	resp.Success(c, http.StatusOK, h.svc.JWKS())
+}
+
+// ForceLogout handles POST /api/v1/admin/users/:id/logout.
+func (h *Handler) ForceLogout(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		resp.NotFound(c, "user not found")
+		return
+	}
+
+	actorID, _ := reqctx.UserID(c)
+	if err = h.svc.ForceLogout(c.Request.Context(), actorID, id); err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			resp.NotFound(c, "user not found")
+			return
+		}
+		if errors.Is(err, user.ErrInsufficientAuthority) {
+			resp.Forbidden(c, "you cannot sign out a user with permissions you do not hold")
+			return
+		}
+		resp.InternalError(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "all sessions revoked"})
+}
+
+// UnlockUser handles POST /api/v1/admin/users/:id/unlock.
+func (h *Handler) UnlockUser(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		resp.NotFound(c, "user not found")
+		return
+	}
+
+	actorID, _ := reqctx.UserID(c)
+	if err = h.svc.UnlockUser(c.Request.Context(), actorID, id); err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			resp.NotFound(c, "user not found")
+			return
+		}
+		if errors.Is(err, user.ErrInsufficientAuthority) {
+			resp.Forbidden(c, "you cannot unlock a user with permissions you do not hold")
+			return
+		}
+		resp.InternalError(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "account unlocked"})
+}
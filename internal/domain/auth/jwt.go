@@ -2,20 +2,35 @@ package auth
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/one-backend-go/internal/config"
 )
 
 // JWTManager handles creation and validation of JWT access tokens.
+//
+// It runs in one of two modes: HS256 with a shared secret, or asymmetric
+// (RS256/EdDSA) with an active signing key plus any number of retired keys.
+// A retired key is accepted for verification until the access token TTL has
+// passed since its retirement, when every token it signed has expired, so
+// retiring a compromised key revokes it within one TTL.
 type JWTManager struct {
 	secret    []byte
+	active    *SigningKey
+	keys      map[string]*SigningKey // active + retired, by kid
 	accessTTL time.Duration
 }
 
-// NewJWTManager creates a new JWTManager.
+// NewJWTManager creates a new HS256 JWTManager.
 func NewJWTManager(secret string, accessTTL time.Duration) *JWTManager {
 	return &JWTManager{
 		secret:    []byte(secret),
@@ -23,37 +38,176 @@ func NewJWTManager(secret string, accessTTL time.Duration) *JWTManager {
 	}
 }
 
+// NewKeyedJWTManager creates a JWTManager that signs with the active key and
+// verifies tokens signed by the active key or a recently retired key. Every
+// retired key must have its RetiredAt set.
+func NewKeyedJWTManager(active *SigningKey, retired []*SigningKey, accessTTL time.Duration) (*JWTManager, error) {
+	if active == nil || !active.CanSign() {
+		return nil, fmt.Errorf("jwt: active signing key must include a private key")
+	}
+
+	keys := map[string]*SigningKey{active.ID: active}
+	for _, k := range retired {
+		if k.ID == active.ID {
+			continue
+		}
+		if k.RetiredAt.IsZero() {
+			return nil, fmt.Errorf("jwt: retired key %s has no retirement time", k.ID)
+		}
+		keys[k.ID] = k
+	}
+
+	return &JWTManager{
+		active:    active,
+		keys:      keys,
+		accessTTL: accessTTL,
+	}, nil
+}
+
+// NewJWTManagerFromConfig builds the JWTManager described by the config:
+// asymmetric when JWT_SIGNING_KEY_FILE is set, HS256 otherwise. Retired keys
+// are listed as "path@retired-at", the retirement time in RFC 3339.
+func NewJWTManagerFromConfig(cfg *config.Config) (*JWTManager, error) {
+	if cfg.JWTSigningKeyFile == "" {
+		return NewJWTManager(cfg.JWTSecret, cfg.AccessTokenTTL), nil
+	}
+
+	active, err := LoadSigningKey(cfg.JWTSigningKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("jwt: %w", err)
+	}
+
+	retired := make([]*SigningKey, 0, len(cfg.JWTRetiredKeyFiles))
+	for _, entry := range cfg.JWTRetiredKeyFiles {
+		i := strings.LastIndex(entry, "@")
+		if i < 0 {
+			return nil, fmt.Errorf("jwt: retired key %s needs a retirement time (path@2006-01-02T15:04:05Z)", entry)
+		}
+		retiredAt, err := time.Parse(time.RFC3339, entry[i+1:])
+		if err != nil {
+			return nil, fmt.Errorf("jwt: invalid retirement time for %s: %w", entry[:i], err)
+		}
+		k, err := LoadSigningKey(entry[:i])
+		if err != nil {
+			return nil, fmt.Errorf("jwt: %w", err)
+		}
+		k.RetiredAt = retiredAt
+		if !k.RetiredAt.Add(cfg.AccessTokenTTL).After(time.Now()) {
+			slog.Warn("retired signing key is no longer accepted; remove it from JWT_RETIRED_KEY_FILES", "kid", k.ID, "file", entry[:i])
+		}
+		retired = append(retired, k)
+	}
+
+	return NewKeyedJWTManager(active, retired, cfg.AccessTokenTTL)
+}
+
+// tokenTypeMFA marks a short-lived MFA challenge token. Access tokens carry
+// no type.
+const tokenTypeMFA = "mfa"
+
 // Claims are the custom JWT claims embedded in access tokens.
 type Claims struct {
-	Email string `json:"email"`
+	TokenType     string   `json:"typ,omitempty"`
+	Email         string   `json:"email"`
+	EmailVerified bool     `json:"email_verified"`
+	Name          string   `json:"name,omitempty"`
+	Role          string   `json:"role,omitempty"`
+	Permissions   []string `json:"perms,omitempty"`
+	// SessionID is the refresh token family the access token was issued
+	// from, identifying the login session.
+	SessionID string `json:"sid,omitempty"`
+	// MFAPending is set when the role requires two-factor authentication but
+	// the session was established without it; permissions are withheld.
+	MFAPending bool `json:"mfa_pending,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// Identity describes the user an access token is issued for.
+type Identity struct {
+	UserID        string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Role          string
+	Permissions   []string
+	SessionID     string
+	MFAPending    bool
+}
+
 // GenerateAccessToken creates a signed JWT for the given user.
-func (j *JWTManager) GenerateAccessToken(userID, email string) (string, error) {
+func (j *JWTManager) GenerateAccessToken(id Identity) (string, error) {
 	now := time.Now().UTC()
 	claims := Claims{
-		Email: email,
+		Email:         id.Email,
+		EmailVerified: id.EmailVerified,
+		Name:          id.Name,
+		Role:          id.Role,
+		Permissions:   id.Permissions,
+		SessionID:     id.SessionID,
+		MFAPending:    id.MFAPending,
 		RegisteredClaims: jwt.RegisteredClaims{
-			Subject:   userID,
+			Subject:   id.UserID,
 			IssuedAt:  jwt.NewNumericDate(now),
 			ExpiresAt: jwt.NewNumericDate(now.Add(j.accessTTL)),
 		},
 	}
+	return j.sign(claims)
+}
+
+// GenerateMFAToken creates a challenge token proving the user passed the
+// password step of a login. It cannot be used as an access token.
+func (j *JWTManager) GenerateMFAToken(userID string, ttl time.Duration) (string, error) {
+	now := time.Now().UTC()
+	return j.sign(Claims{
+		TokenType: tokenTypeMFA,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   userID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	})
+}
+
+// sign signs claims with the active key, or the shared secret in HS256 mode.
+func (j *JWTManager) sign(claims Claims) (string, error) {
+	if j.active == nil {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		return token.SignedString(j.secret)
+	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(j.secret)
+	token := jwt.NewWithClaims(j.active.Method, claims)
+	token.Header["kid"] = j.active.ID
+	return token.SignedString(j.active.private)
 }
 
 // ValidateAccessToken parses and validates a JWT string, returning the claims.
+// Typed tokens such as MFA challenges are rejected.
 func (j *JWTManager) ValidateAccessToken(tokenStr string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenStr, &Claims{}, func(t *jwt.Token) (interface{}, error) {
-		// Ensure only HS256 is accepted.
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
-		}
-		return j.secret, nil
-	})
+	claims, err := j.parse(tokenStr)
+	if err != nil {
+		return nil, err
+	}
+	if claims.TokenType != "" {
+		return nil, fmt.Errorf("invalid token type %q", claims.TokenType)
+	}
+	return claims, nil
+}
+
+// ValidateMFAToken parses an MFA challenge token and returns its claims.
+func (j *JWTManager) ValidateMFAToken(tokenStr string) (*Claims, error) {
+	claims, err := j.parse(tokenStr)
+	if err != nil {
+		return nil, err
+	}
+	if claims.TokenType != tokenTypeMFA {
+		return nil, fmt.Errorf("not an MFA token")
+	}
+	return claims, nil
+}
+
+// parse verifies a token's signature and expiry and returns its claims.
+func (j *JWTManager) parse(tokenStr string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenStr, &Claims{}, j.keyFunc)
 	if err != nil {
 		return nil, fmt.Errorf("invalid token: %w", err)
 	}
@@ -65,6 +219,57 @@ func (j *JWTManager) ValidateAccessToken(tokenStr string) (*Claims, error) {
 	return claims, nil
 }
 
+// keyFunc selects the verification key for a token, pinning the algorithm
+// to the one the key was configured for.
+func (j *JWTManager) keyFunc(t *jwt.Token) (interface{}, error) {
+	if j.active == nil {
+		// Ensure only HS256 is accepted.
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return j.secret, nil
+	}
+
+	kid, _ := t.Header["kid"].(string)
+	k, ok := j.keys[kid]
+	if !ok || !j.accepts(k, time.Now()) {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if t.Method.Alg() != k.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+	}
+	return k.public, nil
+}
+
+// accepts reports whether tokens signed by k are still verified at now:
+// always for the active key, and for a retired key until the access token
+// TTL has passed since it was retired.
+func (j *JWTManager) accepts(k *SigningKey, now time.Time) bool {
+	return k.RetiredAt.IsZero() || now.Before(k.RetiredAt.Add(j.accessTTL))
+}
+
+// JWKS returns the public verification keys, active key first. Retired keys
+// are published only while they are still accepted. It is empty in HS256
+// mode, where there is nothing that can be published.
+func (j *JWTManager) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	if j.active == nil {
+		return set
+	}
+
+	set.Keys = append(set.Keys, j.active.JWK())
+	now := time.Now()
+	for kid, k := range j.keys {
+		if kid != j.active.ID && j.accepts(k, now) {
+			set.Keys = append(set.Keys, k.JWK())
+		}
+	}
+	sort.Slice(set.Keys[1:], func(a, b int) bool {
+		return set.Keys[1+a].Kid < set.Keys[1+b].Kid
+	})
+	return set
+}
+
 // AccessTTLSeconds returns the access token TTL in whole seconds.
 func (j *JWTManager) AccessTTLSeconds() int {
 	return int(j.accessTTL.Seconds())
@@ -78,3 +283,11 @@ func GenerateRefreshTokenString() (string, error) {
 	}
 	return base64.URLEncoding.EncodeToString(b), nil
 }
+
+// HashRefreshToken returns the hex-encoded SHA-256 digest under which a refresh
+// token is stored. The token carries 256 bits of entropy, so an unsalted hash
+// is sufficient to make a database dump useless for session takeover.
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -8,15 +8,29 @@ import (
 )
 
 // RefreshToken represents a server-side refresh token stored in MongoDB.
+// All tokens produced by rotating the same login share a FamilyID. Only the
+// SHA-256 digest of the token is persisted; see HashRefreshToken.
 type RefreshToken struct {
 	ID        primitive.ObjectID `bson:"_id,omitempty"`
 	UserID    primitive.ObjectID `bson:"user_id"`
-	Token     string             `bson:"token"`
+	FamilyID  primitive.ObjectID `bson:"family_id"`
+	TokenHash string             `bson:"token_hash"`
 	ExpiresAt time.Time          `bson:"expires_at"`
 	Revoked   bool               `bson:"revoked"`
+	MFA       bool               `bson:"mfa"` // session passed a second factor
 	CreatedAt time.Time          `bson:"created_at"`
 }
 
+// SessionExport is a refresh token as included in a user's data export. The
+// token digest is left out.
+type SessionExport struct {
+	SessionID string    `json:"session_id"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Revoked   bool      `json:"revoked"`
+	MFA       bool      `json:"mfa"`
+}
+
 // TokenResponse is returned by login and refresh endpoints.
 type TokenResponse struct {
 	AccessToken          string `json:"access_token"`
@@ -25,7 +39,38 @@ type TokenResponse struct {
 	TokenType            string `json:"token_type"`
 }
 
+// MFAChallengeResponse is returned by login instead of tokens when the user
+// has two-factor authentication enabled.
+type MFAChallengeResponse struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+	ExpiresIn   int    `json:"expires_in"` // seconds
+}
+
+// MFALoginRequest is the body for POST /api/v1/auth/login/mfa.
+type MFALoginRequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code"      validate:"required"`
+}
+
+// ChangePasswordRequest is the body for POST /api/v1/users/me/password.
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password"     validate:"required,strongpass"`
+}
+
 // RefreshRequest is the body for POST /api/v1/auth/refresh.
 type RefreshRequest struct {
 	RefreshToken string `json:"refresh_token" validate:"required"`
 }
+
+// ForgotPasswordRequest is the body for POST /api/v1/auth/password/forgot.
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// ResetPasswordRequest is the body for POST /api/v1/auth/password/reset.
+type ResetPasswordRequest struct {
+	Token    string `json:"token"    validate:"required"`
+	Password string `json:"password" validate:"required,strongpass"`
+}
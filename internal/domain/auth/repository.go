@@ -9,16 +9,21 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-// Repository handles persistence for refresh tokens.
+// Repository handles persistence for refresh tokens and login attempts.
 type Repository struct {
-	col *mongo.Collection
+	col      *mongo.Collection
+	attempts *mongo.Collection
 }
 
 // NewRepository returns a new auth Repository.
 func NewRepository(db *mongo.Database) *Repository {
-	return &Repository{col: db.Collection("refresh_tokens")}
+	return &Repository{
+		col:      db.Collection("refresh_tokens"),
+		attempts: db.Collection("login_attempts"),
+	}
 }
 
 // CreateRefreshToken stores a new refresh token document.
@@ -36,16 +41,28 @@ func (r *Repository) CreateRefreshToken(ctx context.Context, rt *RefreshToken) e
 	return nil
 }
 
-// FindRefreshToken finds a valid (non-revoked, non-expired) refresh token.
+// FindRefreshToken finds a refresh token by value, looking it up by digest.
+// Revoked tokens are returned too so callers can detect reuse; expiry is left
+// to the caller.
+//
+// Tokens issued before hashing was introduced are stored in plaintext under
+// "token"; they are still honoured until the hash_legacy_refresh_tokens
+// migration has run or they expire.
 func (r *Repository) FindRefreshToken(ctx context.Context, token string) (*RefreshToken, error) {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
+	rt, err := r.findOne(ctx, bson.M{"token_hash": HashRefreshToken(token)})
+	if err != nil || rt != nil {
+		return rt, err
+	}
+	return r.findOne(ctx, bson.M{"token": token, "token_hash": bson.M{"$exists": false}})
+}
+
+// findOne decodes the first refresh token matching the filter, or nil.
+func (r *Repository) findOne(ctx context.Context, filter bson.M) (*RefreshToken, error) {
 	var rt RefreshToken
-	err := r.col.FindOne(ctx, bson.M{
-		"token":   token,
-		"revoked": false,
-	}).Decode(&rt)
+	err := r.col.FindOne(ctx, filter).Decode(&rt)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return nil, nil
@@ -55,14 +72,34 @@ func (r *Repository) FindRefreshToken(ctx context.Context, token string) (*Refre
 	return &rt, nil
 }
 
-// RevokeRefreshToken marks an existing refresh token as revoked.
-func (r *Repository) RevokeRefreshToken(ctx context.Context, id primitive.ObjectID) error {
+// RevokeRefreshToken marks an active refresh token as revoked. It reports
+// whether this call performed the revocation, so concurrent rotations of the
+// same token can be told apart.
+func (r *Repository) RevokeRefreshToken(ctx context.Context, id primitive.ObjectID) (bool, error) {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	_, err := r.col.UpdateByID(ctx, id, bson.M{"$set": bson.M{"revoked": true}})
+	res, err := r.col.UpdateOne(ctx,
+		bson.M{"_id": id, "revoked": false},
+		bson.M{"$set": bson.M{"revoked": true}},
+	)
 	if err != nil {
-		return fmt.Errorf("auth repo revoke: %w", err)
+		return false, fmt.Errorf("auth repo revoke: %w", err)
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+// RevokeFamily revokes every refresh token descended from the same login.
+func (r *Repository) RevokeFamily(ctx context.Context, familyID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := r.col.UpdateMany(ctx,
+		bson.M{"family_id": familyID, "revoked": false},
+		bson.M{"$set": bson.M{"revoked": true}},
+	)
+	if err != nil {
+		return fmt.Errorf("auth repo revokeFamily: %w", err)
 	}
 	return nil
 }
@@ -81,3 +118,114 @@ func (r *Repository) RevokeAllForUser(ctx context.Context, userID primitive.Obje
 	}
 	return nil
 }
+
+// RevokeAllForUserExcept revokes all refresh tokens for a user except those
+// in the given family, i.e. every session but the caller's.
+func (r *Repository) RevokeAllForUserExcept(ctx context.Context, userID, familyID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := r.col.UpdateMany(ctx,
+		bson.M{"user_id": userID, "revoked": false, "family_id": bson.M{"$ne": familyID}},
+		bson.M{"$set": bson.M{"revoked": true}},
+	)
+	if err != nil {
+		return fmt.Errorf("auth repo revokeAllExcept: %w", err)
+	}
+	return nil
+}
+
+// ListForUser returns every refresh token stored for a user, newest first.
+func (r *Repository) ListForUser(ctx context.Context, userID primitive.ObjectID) ([]RefreshToken, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := r.col.Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("auth repo listForUser: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var tokens []RefreshToken
+	if err = cursor.All(ctx, &tokens); err != nil {
+		return nil, fmt.Errorf("auth repo listForUser decode: %w", err)
+	}
+	return tokens, nil
+}
+
+// DeleteAllForUser removes every refresh token of a user, revoked or not.
+func (r *Repository) DeleteAllForUser(ctx context.Context, userID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if _, err := r.col.DeleteMany(ctx, bson.M{"user_id": userID}); err != nil {
+		return fmt.Errorf("auth repo deleteAllForUser: %w", err)
+	}
+	return nil
+}
+
+// FindLoginAttempts returns the failure record for a throttling key, or nil.
+func (r *Repository) FindLoginAttempts(ctx context.Context, key string) (*LoginAttempts, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var a LoginAttempts
+	err := r.attempts.FindOne(ctx, bson.M{"_id": key}).Decode(&a)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("auth repo findAttempts: %w", err)
+	}
+	return &a, nil
+}
+
+// RecordLoginFailure atomically increments the failure count for a key and
+// returns the updated record. The record expires at expiresAt unless more
+// failures follow.
+func (r *Repository) RecordLoginFailure(ctx context.Context, key string, now, expiresAt time.Time) (*LoginAttempts, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var a LoginAttempts
+	err := r.attempts.FindOneAndUpdate(ctx,
+		bson.M{"_id": key},
+		bson.M{
+			"$inc": bson.M{"failures": 1},
+			"$set": bson.M{"last_failed_at": now, "expires_at": expiresAt},
+		},
+		opts,
+	).Decode(&a)
+	if err != nil {
+		return nil, fmt.Errorf("auth repo recordFailure: %w", err)
+	}
+	return &a, nil
+}
+
+// LockLogin locks a throttling key until the given time.
+func (r *Repository) LockLogin(ctx context.Context, key string, until time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := r.attempts.UpdateOne(ctx,
+		bson.M{"_id": key},
+		bson.M{"$set": bson.M{"locked_until": until}, "$max": bson.M{"expires_at": until}},
+	)
+	if err != nil {
+		return fmt.Errorf("auth repo lock: %w", err)
+	}
+	return nil
+}
+
+// ClearLoginAttempts forgets all failures recorded for a key.
+func (r *Repository) ClearLoginAttempts(ctx context.Context, key string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if _, err := r.attempts.DeleteOne(ctx, bson.M{"_id": key}); err != nil {
+		return fmt.Errorf("auth repo clearAttempts: %w", err)
+	}
+	return nil
+}
@@ -2,44 +2,255 @@ package auth
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"github.com/one-backend-go/internal/config"
+	"github.com/one-backend-go/internal/domain/actiontoken"
+	"github.com/one-backend-go/internal/domain/role"
 	"github.com/one-backend-go/internal/domain/user"
+	"github.com/one-backend-go/internal/pkg/notify"
 )
 
+// RecentSignIn is how recently a user without a password must have signed
+// in to confirm a sensitive action; see ConfirmIdentity.
+const RecentSignIn = 5 * time.Minute
+
 // Service contains business logic for authentication.
 type Service struct {
 	jwt         *JWTManager
 	repo        *Repository
 	userService *user.Service
+	roleService *role.Service
+	tokens      *actiontoken.Service
+	notifier    notify.Notifier
+	lockout     LockoutPolicy
 	refreshTTL  time.Duration
+	resetTTL    time.Duration
+	mfaTTL      time.Duration
+	verifyLogin bool // refuse logins from unverified email addresses
 }
 
 // NewService creates a new auth Service.
-func NewService(cfg *config.Config, jwtMgr *JWTManager, repo *Repository, userSvc *user.Service) *Service {
+func NewService(
+	cfg *config.Config,
+	jwtMgr *JWTManager,
+	repo *Repository,
+	userSvc *user.Service,
+	roleSvc *role.Service,
+	tokens *actiontoken.Service,
+	notifier notify.Notifier,
+) *Service {
 	return &Service{
 		jwt:         jwtMgr,
 		repo:        repo,
 		userService: userSvc,
+		roleService: roleSvc,
+		tokens:      tokens,
+		notifier:    notifier,
+		lockout:     NewLockoutPolicy(cfg),
 		refreshTTL:  cfg.RefreshTokenTTL,
+		resetTTL:    cfg.PasswordResetTTL,
+		mfaTTL:      cfg.MFAChallengeTTL,
+		verifyLogin: cfg.EmailVerification == config.EmailVerificationLogin,
 	}
 }
 
-// Login authenticates the user and returns token pair.
-func (s *Service) Login(ctx context.Context, email, password string) (*TokenResponse, error) {
+// Login authenticates the user and returns token pair. Users with two-factor
+// authentication get an MFA challenge instead, to be completed with LoginMFA.
+// Failed attempts are throttled per account and per client IP; see
+// LockoutPolicy.
+func (s *Service) Login(ctx context.Context, email, password, clientIP string) (*TokenResponse, *MFAChallengeResponse, error) {
+	accountKey := attemptsKey(email)
+	ipKey := "ip:" + clientIP
+
+	if err := s.checkThrottle(ctx, accountKey, ipKey); err != nil {
+		return nil, nil, err
+	}
+
 	u, err := s.userService.Authenticate(ctx, email, password)
 	if err != nil {
+		if errors.Is(err, user.ErrInvalidCredentials) {
+			if ferr := s.recordFailure(ctx, accountKey, ipKey); ferr != nil {
+				return nil, nil, ferr
+			}
+		}
+		return nil, nil, err
+	}
+
+	if s.verifyLogin && !u.EmailVerified {
+		return nil, nil, user.ErrEmailNotVerified
+	}
+
+	// Failures are only forgiven after the second factor, so a known
+	// password cannot be used to reset the counter between code guesses.
+	if u.MFA.Enabled {
+		challenge, err := s.mfaChallenge(u)
+		return nil, challenge, err
+	}
+
+	// Only the account is forgiven; a valid login must not reset the
+	// counter of an IP that is guessing passwords for other accounts.
+	if err = s.repo.ClearLoginAttempts(ctx, accountKey); err != nil {
+		return nil, nil, fmt.Errorf("auth login clear attempts: %w", err)
+	}
+	tokens, err := s.issueTokens(ctx, u, primitive.NewObjectID(), false)
+	return tokens, nil, err
+}
+
+// LoginExternal logs in a user who was authenticated by an external identity
+// provider. The same rules as for password logins apply: disabled accounts
+// and, if configured, unverified addresses are refused, and users with
+// two-factor authentication get an MFA challenge.
+func (s *Service) LoginExternal(ctx context.Context, u *user.User) (*TokenResponse, *MFAChallengeResponse, error) {
+	if u.Disabled {
+		return nil, nil, user.ErrAccountDisabled
+	}
+	if s.verifyLogin && !u.EmailVerified {
+		return nil, nil, user.ErrEmailNotVerified
+	}
+
+	if u.MFA.Enabled {
+		challenge, err := s.mfaChallenge(u)
+		return nil, challenge, err
+	}
+	tokens, err := s.issueTokens(ctx, u, primitive.NewObjectID(), false)
+	return tokens, nil, err
+}
+
+// mfaChallenge issues the short-lived token that LoginMFA exchanges, together
+// with a second factor, for a token pair.
+func (s *Service) mfaChallenge(u *user.User) (*MFAChallengeResponse, error) {
+	token, err := s.jwt.GenerateMFAToken(u.ID.Hex(), s.mfaTTL)
+	if err != nil {
+		return nil, fmt.Errorf("auth login mfa challenge: %w", err)
+	}
+	return &MFAChallengeResponse{
+		MFARequired: true,
+		MFAToken:    token,
+		ExpiresIn:   int(s.mfaTTL.Seconds()),
+	}, nil
+}
+
+// LoginMFA completes a login by exchanging an MFA challenge token and a TOTP
+// or recovery code for a token pair. Wrong codes count as failed logins.
+func (s *Service) LoginMFA(ctx context.Context, mfaToken, code, clientIP string) (*TokenResponse, error) {
+	claims, err := s.jwt.ValidateMFAToken(mfaToken)
+	if err != nil {
+		return nil, ErrInvalidMFAToken
+	}
+	userID, err := primitive.ObjectIDFromHex(claims.Subject)
+	if err != nil {
+		return nil, ErrInvalidMFAToken
+	}
+
+	u, err := s.userService.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("auth login mfa: %w", err)
+	}
+	if u == nil || u.Disabled || !u.MFA.Enabled {
+		return nil, ErrInvalidMFAToken
+	}
+
+	accountKey := attemptsKey(u.Email)
+	ipKey := "ip:" + clientIP
+	if err = s.checkThrottle(ctx, accountKey, ipKey); err != nil {
+		return nil, err
+	}
+
+	if err = s.userService.VerifyMFA(ctx, u, code); err != nil {
+		if errors.Is(err, user.ErrInvalidMFACode) {
+			if ferr := s.recordFailure(ctx, accountKey, ipKey); ferr != nil {
+				return nil, ferr
+			}
+		}
 		return nil, err
 	}
-	return s.issueTokens(ctx, u.ID, u.Email)
+
+	if err = s.repo.ClearLoginAttempts(ctx, accountKey); err != nil {
+		return nil, fmt.Errorf("auth login clear attempts: %w", err)
+	}
+	return s.issueTokens(ctx, u, primitive.NewObjectID(), true)
+}
+
+// UnlockUser clears the lockout and failure history of a user's account.
+func (s *Service) UnlockUser(ctx context.Context, actorID, userID primitive.ObjectID) error {
+	if err := s.userService.CheckAuthority(ctx, actorID, userID); err != nil {
+		return err
+	}
+
+	u, err := s.userService.GetByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("auth unlock: %w", err)
+	}
+	if u == nil {
+		return user.ErrUserNotFound
+	}
+
+	if err = s.repo.ClearLoginAttempts(ctx, attemptsKey(u.Email)); err != nil {
+		return fmt.Errorf("auth unlock: %w", err)
+	}
+
+	slog.Info("account unlocked", "user_id", u.ID.Hex())
+	return nil
+}
+
+// checkThrottle refuses the attempt if the account or client must wait.
+func (s *Service) checkThrottle(ctx context.Context, accountKey, ipKey string) error {
+	now := time.Now().UTC()
+	for _, key := range []string{accountKey, ipKey} {
+		a, err := s.repo.FindLoginAttempts(ctx, key)
+		if err != nil {
+			return fmt.Errorf("auth login throttle: %w", err)
+		}
+		if wait, locked := s.lockout.Wait(a, now); wait > 0 {
+			return &ThrottleError{Locked: locked && key == accountKey, RetryAfter: wait}
+		}
+	}
+	return nil
+}
+
+// recordFailure counts a failed attempt against the account and the client,
+// locking whichever reached its limit.
+func (s *Service) recordFailure(ctx context.Context, accountKey, ipKey string) error {
+	now := time.Now().UTC()
+	limits := []struct {
+		key   string
+		limit int
+	}{
+		{accountKey, s.lockout.MaxFailures},
+		{ipKey, s.lockout.IPMaxFailures},
+	}
+
+	for _, l := range limits {
+		a, err := s.repo.RecordLoginFailure(ctx, l.key, now, now.Add(s.lockout.Window))
+		if err != nil {
+			return fmt.Errorf("auth login record failure: %w", err)
+		}
+		if !s.lockout.ShouldLock(a.Failures, l.limit) {
+			continue
+		}
+
+		if err = s.repo.LockLogin(ctx, l.key, now.Add(s.lockout.Lockout)); err != nil {
+			return fmt.Errorf("auth login lock: %w", err)
+		}
+		slog.Warn("security event: login locked",
+			"event", "login_lockout",
+			"key", l.key,
+			"failures", a.Failures,
+		)
+	}
+	return nil
 }
 
 // Refresh validates a refresh token, rotates it, and issues a new token pair.
+// Presenting a token that was already rotated or revoked is treated as
+// theft: the whole token family is revoked.
 func (s *Service) Refresh(ctx context.Context, refreshTokenStr string) (*TokenResponse, error) {
 	rt, err := s.repo.FindRefreshToken(ctx, refreshTokenStr)
 	if err != nil {
@@ -48,32 +259,367 @@ func (s *Service) Refresh(ctx context.Context, refreshTokenStr string) (*TokenRe
 	if rt == nil {
 		return nil, ErrInvalidRefreshToken
 	}
+	if rt.Revoked {
+		return nil, s.handleReuse(ctx, rt)
+	}
 	if time.Now().UTC().After(rt.ExpiresAt) {
 		return nil, ErrInvalidRefreshToken
 	}
 
-	// Revoke old token (rotation).
-	if err = s.repo.RevokeRefreshToken(ctx, rt.ID); err != nil {
+	// Rebuild the access token from the current user record so email, name
+	// and role changes are picked up. Deleted or disabled users lose the
+	// whole session.
+	u, err := s.userService.GetByID(ctx, rt.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("auth refresh user: %w", err)
+	}
+	if u == nil || u.Disabled {
+		if err = s.repo.RevokeFamily(ctx, familyOf(rt)); err != nil {
+			return nil, fmt.Errorf("auth refresh revoke: %w", err)
+		}
+		slog.Info("refresh refused for inactive user", "user_id", rt.UserID.Hex(), "deleted", u == nil)
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// Revoke old token (rotation). Losing the race to a concurrent rotation
+	// means the same token was presented twice.
+	revoked, err := s.repo.RevokeRefreshToken(ctx, rt.ID)
+	if err != nil {
 		return nil, fmt.Errorf("auth refresh revoke: %w", err)
 	}
+	if !revoked {
+		return nil, s.handleReuse(ctx, rt)
+	}
+
+	return s.issueTokens(ctx, u, familyOf(rt), rt.MFA)
+}
+
+// handleReuse revokes every token in the family of a replayed refresh token
+// and records a security event. It always returns ErrInvalidRefreshToken.
+func (s *Service) handleReuse(ctx context.Context, rt *RefreshToken) error {
+	slog.Warn("security event: refresh token reuse detected",
+		"event", "refresh_token_reuse",
+		"user_id", rt.UserID.Hex(),
+		"family_id", familyOf(rt).Hex(),
+		"token_id", rt.ID.Hex(),
+	)
+
+	// Tokens issued before families existed have no family to revoke;
+	// fall back to revoking every session of the user.
+	var err error
+	if rt.FamilyID.IsZero() {
+		err = s.repo.RevokeAllForUser(ctx, rt.UserID)
+	} else {
+		err = s.repo.RevokeFamily(ctx, rt.FamilyID)
+	}
+	if err != nil {
+		return fmt.Errorf("auth refresh reuse: %w", err)
+	}
+	return ErrInvalidRefreshToken
+}
+
+// familyOf returns the family a token belongs to. Legacy tokens without a
+// family start a new one rooted at their own ID.
+func familyOf(rt *RefreshToken) primitive.ObjectID {
+	if rt.FamilyID.IsZero() {
+		return rt.ID
+	}
+	return rt.FamilyID
+}
+
+// JWKS returns the public keys that verify access tokens.
+func (s *Service) JWKS() JWKSet {
+	return s.jwt.JWKS()
+}
+
+// Logout revokes the session backing the given refresh token.
+func (s *Service) Logout(ctx context.Context, refreshTokenStr string) error {
+	rt, err := s.repo.FindRefreshToken(ctx, refreshTokenStr)
+	if err != nil {
+		return fmt.Errorf("auth logout: %w", err)
+	}
+	if rt == nil || rt.Revoked {
+		return ErrInvalidRefreshToken
+	}
+
+	if _, err = s.repo.RevokeRefreshToken(ctx, rt.ID); err != nil {
+		return fmt.Errorf("auth logout revoke: %w", err)
+	}
+
+	slog.Info("session revoked", "user_id", rt.UserID.Hex())
+	return nil
+}
+
+// ForceLogout revokes every session of another user on an administrator's
+// behalf, provided the administrator holds every permission of the user's
+// role.
+func (s *Service) ForceLogout(ctx context.Context, actorID, userID primitive.ObjectID) error {
+	if err := s.userService.CheckAuthority(ctx, actorID, userID); err != nil {
+		return err
+	}
 
-	// Look up user to get current email (could have changed).
-	// We store user_id on the refresh token, so resolve from there.
-	return s.issueTokens(ctx, rt.UserID, "") // email resolved below
+	u, err := s.userService.GetByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("auth force logout: %w", err)
+	}
+	if u == nil {
+		return user.ErrUserNotFound
+	}
+	return s.LogoutAll(ctx, u.ID)
 }
 
-// issueTokens generates a new access + refresh token pair and stores the refresh token.
-func (s *Service) issueTokens(ctx context.Context, userID primitive.ObjectID, email string) (*TokenResponse, error) {
-	// If email is empty we could look it up; for simplicity we embed empty string
-	// (the JWT sub already contains the user ID). In the Refresh flow the caller
-	// can supply "" and we'll resolve it. Let's do a quick lookup in that case.
-	if email == "" {
-		// Minimal approach: we accept empty email for refresh and omit it from claims.
-		// A more complete implementation would look up the user.
-		email = "" // acceptable — the middleware resolves by sub
+// LogoutAll revokes every active session belonging to the user.
+func (s *Service) LogoutAll(ctx context.Context, userID primitive.ObjectID) error {
+	if err := s.repo.RevokeAllForUser(ctx, userID); err != nil {
+		return fmt.Errorf("auth logout all: %w", err)
 	}
 
-	accessToken, err := s.jwt.GenerateAccessToken(userID.Hex(), email)
+	slog.Info("all sessions revoked", "user_id", userID.Hex())
+	return nil
+}
+
+// ChangePassword sets a new password after checking the current one, then
+// revokes every session except the caller's (sessionID, which may be zero
+// for tokens issued before sessions were tracked). Wrong current passwords
+// count as failed logins.
+func (s *Service) ChangePassword(ctx context.Context, userID, sessionID primitive.ObjectID, current, newPassword, clientIP string) error {
+	u, err := s.userService.GetByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("auth change password: %w", err)
+	}
+	if u == nil {
+		return user.ErrUserNotFound
+	}
+
+	if err = s.checkPassword(ctx, u, current, clientIP); err != nil {
+		return err
+	}
+
+	if _, err = s.userService.SetPassword(ctx, u.ID, newPassword); err != nil {
+		return fmt.Errorf("auth change password: %w", err)
+	}
+
+	if sessionID.IsZero() {
+		err = s.repo.RevokeAllForUser(ctx, u.ID)
+	} else {
+		err = s.repo.RevokeAllForUserExcept(ctx, u.ID, sessionID)
+	}
+	if err != nil {
+		return fmt.Errorf("auth change password revoke: %w", err)
+	}
+
+	slog.Info("other sessions revoked after password change", "user_id", u.ID.Hex())
+	return nil
+}
+
+// ConfirmIdentity checks that a signed-in user is present before a sensitive
+// action. Users with a password must enter it; wrong passwords count as failed
+// logins. Users without one, who sign in through an identity provider, must
+// instead have started the session (sessionID) within RecentSignIn, i.e. sign
+// in again with their provider first.
+func (s *Service) ConfirmIdentity(ctx context.Context, userID, sessionID primitive.ObjectID, password, clientIP string) error {
+	u, err := s.userService.GetByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("auth confirm identity: %w", err)
+	}
+	if u == nil {
+		return user.ErrUserNotFound
+	}
+
+	if u.PasswordHash == "" {
+		// Session IDs are created at sign-in, so their timestamp is the
+		// sign-in time; refreshing the session keeps its ID.
+		if sessionID.IsZero() || time.Since(sessionID.Timestamp()) > RecentSignIn {
+			return ErrRecentSignInRequired
+		}
+		return nil
+	}
+	if password == "" {
+		return ErrPasswordRequired
+	}
+	return s.checkPassword(ctx, u, password, clientIP)
+}
+
+// checkPassword compares password with the user's, subject to the same
+// throttling as logins. It returns ErrWrongPassword on a mismatch. Accounts
+// without a password never match, but that is not recorded as a failure:
+// there is nothing to guess.
+func (s *Service) checkPassword(ctx context.Context, u *user.User, password, clientIP string) error {
+	if u.PasswordHash == "" {
+		return ErrWrongPassword
+	}
+
+	accountKey := attemptsKey(u.Email)
+	ipKey := "ip:" + clientIP
+	if err := s.checkThrottle(ctx, accountKey, ipKey); err != nil {
+		return err
+	}
+	if !user.CheckPassword(u.PasswordHash, password) {
+		if ferr := s.recordFailure(ctx, accountKey, ipKey); ferr != nil {
+			return ferr
+		}
+		return ErrWrongPassword
+	}
+	return nil
+}
+
+// ExportUserData returns a user's sessions for a data export.
+func (s *Service) ExportUserData(ctx context.Context, userID primitive.ObjectID) (interface{}, error) {
+	tokens, err := s.repo.ListForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]SessionExport, 0, len(tokens))
+	for _, rt := range tokens {
+		sessions = append(sessions, SessionExport{
+			SessionID: rt.FamilyID.Hex(),
+			CreatedAt: rt.CreatedAt,
+			ExpiresAt: rt.ExpiresAt,
+			Revoked:   rt.Revoked,
+			MFA:       rt.MFA,
+		})
+	}
+	return sessions, nil
+}
+
+// EraseUserData deletes a user's refresh tokens, signing them out everywhere,
+// and forgets failed logins recorded against their email address.
+func (s *Service) EraseUserData(ctx context.Context, userID primitive.ObjectID) error {
+	if err := s.repo.DeleteAllForUser(ctx, userID); err != nil {
+		return err
+	}
+
+	u, err := s.userService.GetByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("auth erase: %w", err)
+	}
+	if u != nil {
+		if err = s.repo.ClearLoginAttempts(ctx, attemptsKey(u.Email)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ForgotPassword issues a password reset token and sends it to the user.
+// Unknown and disabled accounts are ignored silently so the endpoint does not
+// reveal which emails are registered.
+func (s *Service) ForgotPassword(ctx context.Context, email string) error {
+	u, err := s.userService.GetByEmail(ctx, email)
+	if err != nil {
+		return fmt.Errorf("auth forgot password: %w", err)
+	}
+	if u == nil || u.Disabled {
+		slog.Info("password reset requested for unknown or disabled account")
+		return nil
+	}
+
+	token, err := s.tokens.Issue(ctx, u.ID, actiontoken.PurposePasswordReset, s.resetTTL)
+	if err != nil {
+		return fmt.Errorf("auth forgot password: %w", err)
+	}
+
+	err = s.notifier.Send(ctx, notify.Message{
+		To:      u.Email,
+		Kind:    actiontoken.PurposePasswordReset,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Use this token to reset your password: %s\nIt expires in %s. "+
+			"If you did not request a reset, you can ignore this message.", token, s.resetTTL),
+		Data: map[string]string{"token": token},
+	})
+	if err != nil {
+		return fmt.Errorf("auth forgot password notify: %w", err)
+	}
+
+	slog.Info("password reset issued", "user_id", u.ID.Hex())
+	return nil
+}
+
+// ResetPassword redeems a reset token and sets a new password. Every session
+// of the user is revoked and any login lockout is lifted.
+func (s *Service) ResetPassword(ctx context.Context, token, newPassword string) error {
+	userID, err := s.tokens.Consume(ctx, actiontoken.PurposePasswordReset, token)
+	if err != nil {
+		if errors.Is(err, actiontoken.ErrInvalidToken) {
+			return ErrInvalidResetToken
+		}
+		return fmt.Errorf("auth reset password: %w", err)
+	}
+
+	if err = s.replacePassword(ctx, userID, newPassword); err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			return ErrInvalidResetToken
+		}
+		return fmt.Errorf("auth reset password: %w", err)
+	}
+
+	slog.Info("password reset completed", "user_id", userID.Hex())
+	return nil
+}
+
+// AdminResetPassword sets a new password on an operator's behalf, e.g. from
+// the admin CLI. Like ResetPassword it revokes every session of the user and
+// lifts any login lockout.
+func (s *Service) AdminResetPassword(ctx context.Context, userID primitive.ObjectID, newPassword string) error {
+	if err := s.replacePassword(ctx, userID, newPassword); err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			return err
+		}
+		return fmt.Errorf("auth admin reset password: %w", err)
+	}
+
+	slog.Info("password reset by operator", "user_id", userID.Hex())
+	return nil
+}
+
+// replacePassword sets a new password, revokes every session and clears the
+// failed logins recorded against the account.
+func (s *Service) replacePassword(ctx context.Context, userID primitive.ObjectID, newPassword string) error {
+	u, err := s.userService.SetPassword(ctx, userID, newPassword)
+	if err != nil {
+		return err
+	}
+
+	if err = s.repo.RevokeAllForUser(ctx, u.ID); err != nil {
+		return fmt.Errorf("revoke: %w", err)
+	}
+	if err = s.repo.ClearLoginAttempts(ctx, attemptsKey(u.Email)); err != nil {
+		return fmt.Errorf("clear attempts: %w", err)
+	}
+	return nil
+}
+
+// attemptsKey is the key failed logins are recorded under for an account.
+func attemptsKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// issueTokens generates a new access + refresh token pair for the user and
+// stores the refresh token as part of the given token family. mfa records
+// whether the session passed a second factor; if the user's role requires
+// one and it did not, the access token carries no permissions.
+func (s *Service) issueTokens(ctx context.Context, u *user.User, familyID primitive.ObjectID, mfa bool) (*TokenResponse, error) {
+	perms, err := s.roleService.Permissions(ctx, u.Role)
+	if err != nil {
+		return nil, fmt.Errorf("auth issue permissions: %w", err)
+	}
+
+	mfaPending := !mfa && s.userService.MFARequired(u.Role)
+	if mfaPending {
+		perms = nil
+	}
+
+	accessToken, err := s.jwt.GenerateAccessToken(Identity{
+		UserID:        u.ID.Hex(),
+		Email:         u.Email,
+		EmailVerified: u.EmailVerified,
+		Name:          u.Name,
+		Role:          u.Role,
+		Permissions:   perms,
+		SessionID:     familyID.Hex(),
+		MFAPending:    mfaPending,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("auth issue access: %w", err)
 	}
@@ -84,16 +630,18 @@ func (s *Service) issueTokens(ctx context.Context, userID primitive.ObjectID, em
 	}
 
 	rt := &RefreshToken{
-		UserID:    userID,
-		Token:     refreshStr,
+		UserID:    u.ID,
+		FamilyID:  familyID,
+		TokenHash: HashRefreshToken(refreshStr),
 		ExpiresAt: time.Now().UTC().Add(s.refreshTTL),
 		Revoked:   false,
+		MFA:       mfa,
 	}
 	if err = s.repo.CreateRefreshToken(ctx, rt); err != nil {
 		return nil, fmt.Errorf("auth store refresh: %w", err)
 	}
 
-	slog.Info("tokens issued", "user_id", userID.Hex())
+	slog.Info("tokens issued", "user_id", u.ID.Hex())
 	return &TokenResponse{
 		AccessToken:          accessToken,
 		AccessTokenExpiresIn: s.jwt.AccessTTLSeconds(),
@@ -104,3 +652,20 @@ func (s *Service) issueTokens(ctx context.Context, userID primitive.ObjectID, em
 
 // ErrInvalidRefreshToken indicates the refresh token is missing, revoked, or expired.
 var ErrInvalidRefreshToken = fmt.Errorf("invalid or expired refresh token")
+
+// ErrWrongPassword indicates the current password given to confirm a change is wrong.
+var ErrWrongPassword = fmt.Errorf("current password is incorrect")
+
+// ErrPasswordRequired indicates a user with a password did not enter it to
+// confirm a sensitive action.
+var ErrPasswordRequired = fmt.Errorf("password is required")
+
+// ErrRecentSignInRequired indicates a user without a password must sign in
+// again before a sensitive action.
+var ErrRecentSignInRequired = fmt.Errorf("sign in again to confirm this action")
+
+// ErrInvalidMFAToken indicates the MFA challenge token is malformed or expired.
+var ErrInvalidMFAToken = fmt.Errorf("invalid or expired MFA token")
+
+// ErrInvalidResetToken indicates the password reset token is unknown, used, or expired.
+var ErrInvalidResetToken = fmt.Errorf("invalid or expired reset token")
@@ -0,0 +1,65 @@
+package category
+
+import "time"
+
+// ── Request DTOs ───────────────────────────────────────────────────────────────
+
+// CreateRequest is the body for POST /api/v1/categories.
+type CreateRequest struct {
+	Name     string `json:"name"      validate:"required,min=2,max=60"`
+	ParentID string `json:"parent_id"`
+	Position int    `json:"position"  validate:"gte=0"`
+	Icon     string `json:"icon"      validate:"max=200"`
+	Active   *bool  `json:"active"`
+}
+
+// UpdateRequest is the body for PUT /api/v1/categories/:id. An empty
+// parent_id moves the category to the top level.
+type UpdateRequest struct {
+	Name     *string `json:"name"      validate:"omitempty,min=2,max=60"`
+	ParentID *string `json:"parent_id"`
+	Position *int    `json:"position"  validate:"omitempty,gte=0"`
+	Icon     *string `json:"icon"      validate:"omitempty,max=200"`
+	Active   *bool   `json:"active"`
+}
+
+// ── Response DTOs ──────────────────────────────────────────────────────────────
+
+// Response is the API representation of a category.
+type Response struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Slug      string    `json:"slug"`
+	ParentID  string    `json:"parent_id,omitempty"`
+	Position  int       `json:"position"`
+	Icon      string    `json:"icon,omitempty"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TreeNode is a category in the category tree. ProductCount includes the
+// products of every subcategory.
+type TreeNode struct {
+	Response
+	ProductCount int64      `json:"product_count"`
+	Children     []TreeNode `json:"children"`
+}
+
+// ToResponse converts a Category model to its public response form.
+func (c *Category) ToResponse() Response {
+	out := Response{
+		ID:        c.ID.Hex(),
+		Name:      c.Name,
+		Slug:      c.Slug,
+		Position:  c.Position,
+		Icon:      c.Icon,
+		Active:    c.Active,
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+	}
+	if c.ParentID != nil {
+		out.ParentID = c.ParentID.Hex()
+	}
+	return out
+}
@@ -0,0 +1,128 @@
+package category
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/one-backend-go/internal/pkg/resp"
+	"github.com/one-backend-go/internal/pkg/validate"
+)
+
+// Handler holds HTTP handlers for category endpoints.
+type Handler struct {
+	svc      *Service
+	validate *validate.Validator
+}
+
+// NewHandler creates a new category Handler.
+func NewHandler(svc *Service, v *validate.Validator) *Handler {
+	return &Handler{svc: svc, validate: v}
+}
+
+// Tree handles GET /api/v1/categories. Only active categories are listed.
+func (h *Handler) Tree(c *gin.Context) {
+	h.tree(c, false)
+}
+
+// AdminTree handles GET /api/v1/admin/categories, which also lists inactive
+// categories.
+func (h *Handler) AdminTree(c *gin.Context) {
+	h.tree(c, true)
+}
+
+func (h *Handler) tree(c *gin.Context, inactive bool) {
+	tree, err := h.svc.Tree(c.Request.Context(), inactive)
+	if err != nil {
+		resp.InternalError(c)
+		return
+	}
+
+	resp.Success(c, http.StatusOK, gin.H{"items": tree})
+}
+
+// Get handles GET /api/v1/categories/:id.
+func (h *Handler) Get(c *gin.Context) {
+	cat, err := h.svc.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+
+	resp.Success(c, http.StatusOK, cat.ToResponse())
+}
+
+// Create handles POST /api/v1/categories.
+func (h *Handler) Create(c *gin.Context) {
+	var req CreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.Fail(c, http.StatusBadRequest, "BAD_REQUEST", "invalid JSON body", nil)
+		return
+	}
+
+	if errs := h.validate.Struct(req); errs != nil {
+		resp.ValidationError(c, errs)
+		return
+	}
+
+	cat, err := h.svc.Create(c.Request.Context(), req)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+
+	resp.Success(c, http.StatusCreated, cat.ToResponse())
+}
+
+// Update handles PUT /api/v1/categories/:id.
+func (h *Handler) Update(c *gin.Context) {
+	var req UpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.Fail(c, http.StatusBadRequest, "BAD_REQUEST", "invalid JSON body", nil)
+		return
+	}
+
+	if errs := h.validate.Struct(req); errs != nil {
+		resp.ValidationError(c, errs)
+		return
+	}
+
+	cat, err := h.svc.Update(c.Request.Context(), c.Param("id"), req)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+
+	resp.Success(c, http.StatusOK, cat.ToResponse())
+}
+
+// Delete handles DELETE /api/v1/categories/:id.
+func (h *Handler) Delete(c *gin.Context) {
+	if err := h.svc.Delete(c.Request.Context(), c.Param("id")); err != nil {
+		h.fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "category deleted"})
+}
+
+// fail maps category service errors to HTTP responses.
+func (h *Handler) fail(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrCategoryNotFound):
+		resp.NotFound(c, "category not found")
+	case errors.Is(err, ErrCategoryExists):
+		resp.Conflict(c, err.Error())
+	case errors.Is(err, ErrCategoryInUse):
+		resp.Conflict(c, err.Error())
+	case errors.Is(err, ErrParentNotFound), errors.Is(err, ErrInvalidParent):
+		resp.ValidationError(c, map[string]string{"parent_id": err.Error()})
+	case errors.Is(err, ErrInvalidName):
+		resp.ValidationError(c, map[string]string{"name": err.Error()})
+	case errors.Is(err, ErrEmptyUpdate):
+		resp.Fail(c, http.StatusBadRequest, "BAD_REQUEST", err.Error(), nil)
+	default:
+		resp.InternalError(c)
+	}
+}
@@ -0,0 +1,26 @@
+// Package category organizes the menu into a tree of categories that
+// products belong to.
+package category
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Category is a menu section stored in MongoDB. Top-level categories have no
+// parent.
+type Category struct {
+	ID        primitive.ObjectID  `bson:"_id,omitempty"`
+	Name      string              `bson:"name"`
+	Slug      string              `bson:"slug"` // unique, made from the name
+	ParentID  *primitive.ObjectID `bson:"parent_id"`
+	Position  int                 `bson:"position"` // display order among siblings
+	Icon      string              `bson:"icon,omitempty"`
+	Active    bool                `bson:"active"`
+	CreatedAt time.Time           `bson:"created_at"`
+	UpdatedAt time.Time           `bson:"updated_at"`
+}
+
+// maxDepth limits how deeply categories can be nested.
+const maxDepth = 4
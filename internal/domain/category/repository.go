@@ -0,0 +1,137 @@
+package category
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Repository provides persistence operations for categories.
+type Repository struct {
+	col *mongo.Collection
+}
+
+// NewRepository returns a new category Repository.
+func NewRepository(db *mongo.Database) *Repository {
+	return &Repository{col: db.Collection("categories")}
+}
+
+// Create inserts a new category document.
+func (r *Repository) Create(ctx context.Context, c *Category) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	c.ID = primitive.NewObjectID()
+	now := time.Now().UTC()
+	c.CreatedAt = now
+	c.UpdatedAt = now
+
+	if _, err := r.col.InsertOne(ctx, c); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrCategoryExists
+		}
+		return fmt.Errorf("category repo create: %w", err)
+	}
+	return nil
+}
+
+// FindByID retrieves a category by ID.
+func (r *Repository) FindByID(ctx context.Context, id primitive.ObjectID) (*Category, error) {
+	return r.findOne(ctx, bson.M{"_id": id}, "findByID")
+}
+
+// FindBySlug retrieves a category by slug.
+func (r *Repository) FindBySlug(ctx context.Context, slug string) (*Category, error) {
+	return r.findOne(ctx, bson.M{"slug": slug}, "findBySlug")
+}
+
+func (r *Repository) findOne(ctx context.Context, filter bson.M, op string) (*Category, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var c Category
+	err := r.col.FindOne(ctx, filter).Decode(&c)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("category repo %s: %w", op, err)
+	}
+	return &c, nil
+}
+
+// List returns every category in display order. Menus have at most a few
+// hundred categories, so the tree is always built from the full list.
+func (r *Repository) List(ctx context.Context) ([]Category, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "position", Value: 1}, {Key: "name", Value: 1}})
+	cursor, err := r.col.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("category repo list: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var categories []Category
+	if err = cursor.All(ctx, &categories); err != nil {
+		return nil, fmt.Errorf("category repo decode: %w", err)
+	}
+	return categories, nil
+}
+
+// Update applies a $set of the given fields and returns the updated
+// category, or nil if it does not exist.
+func (r *Repository) Update(ctx context.Context, id primitive.ObjectID, fields bson.M) (*Category, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	fields["updated_at"] = time.Now().UTC()
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var c Category
+	err := r.col.FindOneAndUpdate(ctx, bson.M{"_id": id}, bson.M{"$set": fields}, opts).Decode(&c)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrCategoryExists
+		}
+		return nil, fmt.Errorf("category repo update: %w", err)
+	}
+	return &c, nil
+}
+
+// HasChildren reports whether any category has the given parent.
+func (r *Repository) HasChildren(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	n, err := r.col.CountDocuments(ctx, bson.M{"parent_id": id}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, fmt.Errorf("category repo hasChildren: %w", err)
+	}
+	return n > 0, nil
+}
+
+// Delete removes a category. Returns true if a document was deleted.
+func (r *Repository) Delete(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res, err := r.col.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return false, fmt.Errorf("category repo delete: %w", err)
+	}
+	return res.DeletedCount > 0, nil
+}
+
+// ErrCategoryExists indicates another category already has the same slug.
+var ErrCategoryExists = fmt.Errorf("a category with this name already exists")
@@ -0,0 +1,349 @@
+package category
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/one-backend-go/internal/pkg/slug"
+)
+
+// ProductCounter counts the products in categories. It is implemented by the
+// product repository.
+type ProductCounter interface {
+	CountByCategory(ctx context.Context) (map[primitive.ObjectID]int64, error)
+	HasProductsInCategory(ctx context.Context, id primitive.ObjectID) (bool, error)
+}
+
+// Service contains business logic for categories.
+type Service struct {
+	repo     *Repository
+	products ProductCounter
+}
+
+// NewService creates a new category Service.
+func NewService(repo *Repository, products ProductCounter) *Service {
+	return &Service{repo: repo, products: products}
+}
+
+// Tree returns the category tree with product counts. Unless inactive is
+// set, inactive categories are left out along with everything below them.
+func (s *Service) Tree(ctx context.Context, inactive bool) ([]TreeNode, error) {
+	categories, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("category service tree: %w", err)
+	}
+	counts, err := s.products.CountByCategory(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("category service tree: %w", err)
+	}
+	return buildTree(categories, counts, inactive), nil
+}
+
+// buildTree arranges categories, already in display order, under their
+// parents. Categories whose parent is missing are shown at the top level.
+func buildTree(categories []Category, counts map[primitive.ObjectID]int64, inactive bool) []TreeNode {
+	known := make(map[primitive.ObjectID]bool, len(categories))
+	for _, c := range categories {
+		known[c.ID] = true
+	}
+	children := make(map[primitive.ObjectID][]Category)
+	var roots []Category
+	for _, c := range categories {
+		if c.ParentID == nil || !known[*c.ParentID] {
+			roots = append(roots, c)
+		} else {
+			children[*c.ParentID] = append(children[*c.ParentID], c)
+		}
+	}
+
+	var build func(level []Category, depth int) []TreeNode
+	build = func(level []Category, depth int) []TreeNode {
+		nodes := make([]TreeNode, 0, len(level))
+		for i := range level {
+			c := &level[i]
+			if !c.Active && !inactive {
+				continue
+			}
+			node := TreeNode{Response: c.ToResponse(), ProductCount: counts[c.ID], Children: []TreeNode{}}
+			// The depth check stops at stored cycles, which Update prevents
+			// but a hand-edited database could still contain.
+			if depth < maxDepth {
+				node.Children = build(children[c.ID], depth+1)
+			}
+			for _, child := range node.Children {
+				node.ProductCount += child.ProductCount
+			}
+			nodes = append(nodes, node)
+		}
+		return nodes
+	}
+	return build(roots, 1)
+}
+
+// Get returns a single category.
+func (s *Service) Get(ctx context.Context, idHex string) (*Category, error) {
+	id, err := primitive.ObjectIDFromHex(idHex)
+	if err != nil {
+		return nil, ErrCategoryNotFound
+	}
+
+	c, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("category service get: %w", err)
+	}
+	if c == nil {
+		return nil, ErrCategoryNotFound
+	}
+	return c, nil
+}
+
+// Exists reports whether a category with the given ID exists. Products can
+// be assigned to inactive categories.
+func (s *Service) Exists(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	c, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return false, fmt.Errorf("category service exists: %w", err)
+	}
+	return c != nil, nil
+}
+
+// Resolve returns the IDs of the category identified by ref, an ID or a
+// slug, and of all categories below it. It returns nil if there is no such
+// category.
+func (s *Service) Resolve(ctx context.Context, ref string) ([]primitive.ObjectID, error) {
+	categories, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("category service resolve: %w", err)
+	}
+
+	var root *Category
+	id, idErr := primitive.ObjectIDFromHex(ref)
+	for i := range categories {
+		if (idErr == nil && categories[i].ID == id) || categories[i].Slug == ref {
+			root = &categories[i]
+			break
+		}
+	}
+	if root == nil {
+		return nil, nil
+	}
+
+	ids := []primitive.ObjectID{root.ID}
+	seen := map[primitive.ObjectID]bool{root.ID: true}
+	for i := 0; i < len(ids); i++ {
+		for _, c := range categories {
+			if c.ParentID != nil && *c.ParentID == ids[i] && !seen[c.ID] {
+				seen[c.ID] = true
+				ids = append(ids, c.ID)
+			}
+		}
+	}
+	return ids, nil
+}
+
+// Create adds a new category.
+func (s *Service) Create(ctx context.Context, req CreateRequest) (*Category, error) {
+	c := &Category{
+		Name:     strings.TrimSpace(req.Name),
+		Position: req.Position,
+		Icon:     req.Icon,
+		Active:   true,
+	}
+	if req.Active != nil {
+		c.Active = *req.Active
+	}
+	if c.Slug = slug.Make(c.Name); c.Slug == "" {
+		return nil, ErrInvalidName
+	}
+
+	if req.ParentID != "" {
+		parent, err := s.parent(ctx, req.ParentID, primitive.NilObjectID)
+		if err != nil {
+			return nil, err
+		}
+		c.ParentID = &parent
+	}
+
+	if err := s.repo.Create(ctx, c); err != nil {
+		return nil, err
+	}
+	slog.Info("category created", "id", c.ID.Hex(), "slug", c.Slug)
+	return c, nil
+}
+
+// Update modifies an existing category.
+func (s *Service) Update(ctx context.Context, idHex string, req UpdateRequest) (*Category, error) {
+	id, err := primitive.ObjectIDFromHex(idHex)
+	if err != nil {
+		return nil, ErrCategoryNotFound
+	}
+
+	update := bson.M{}
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		newSlug := slug.Make(name)
+		if newSlug == "" {
+			return nil, ErrInvalidName
+		}
+		update["name"] = name
+		update["slug"] = newSlug
+	}
+	if req.ParentID != nil {
+		if *req.ParentID == "" {
+			update["parent_id"] = nil
+		} else {
+			parent, err := s.parent(ctx, *req.ParentID, id)
+			if err != nil {
+				return nil, err
+			}
+			update["parent_id"] = parent
+		}
+	}
+	if req.Position != nil {
+		update["position"] = *req.Position
+	}
+	if req.Icon != nil {
+		update["icon"] = *req.Icon
+	}
+	if req.Active != nil {
+		update["active"] = *req.Active
+	}
+
+	if len(update) == 0 {
+		return nil, ErrEmptyUpdate
+	}
+
+	c, err := s.repo.Update(ctx, id, update)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, ErrCategoryNotFound
+	}
+	return c, nil
+}
+
+// parent validates a parent ID for the category self, which is nil for a new
+// category. The parent must exist, must not be self or below it, and must
+// leave room for self and its subcategories within maxDepth.
+func (s *Service) parent(ctx context.Context, parentHex string, self primitive.ObjectID) (primitive.ObjectID, error) {
+	parentID, err := primitive.ObjectIDFromHex(parentHex)
+	if err != nil {
+		return primitive.NilObjectID, ErrParentNotFound
+	}
+	if parentID == self {
+		return primitive.NilObjectID, ErrInvalidParent
+	}
+
+	categories, err := s.repo.List(ctx)
+	if err != nil {
+		return primitive.NilObjectID, fmt.Errorf("category service parent: %w", err)
+	}
+	byID := make(map[primitive.ObjectID]*Category, len(categories))
+	for i := range categories {
+		byID[categories[i].ID] = &categories[i]
+	}
+	if byID[parentID] == nil {
+		return primitive.NilObjectID, ErrParentNotFound
+	}
+
+	// Walk up from the new parent; meeting self means self would become its
+	// own ancestor.
+	depth := 1
+	for id := &parentID; id != nil && byID[*id] != nil; id = byID[*id].ParentID {
+		if *id == self || depth > maxDepth {
+			return primitive.NilObjectID, ErrInvalidParent
+		}
+		depth++
+	}
+	if depth+subtreeHeight(categories, self) > maxDepth+1 {
+		return primitive.NilObjectID, ErrInvalidParent
+	}
+	return parentID, nil
+}
+
+// subtreeHeight returns how many levels the category id spans together with
+// its subcategories, or 1 for a category that does not exist yet.
+func subtreeHeight(categories []Category, id primitive.ObjectID) int {
+	height := 1
+	level := []primitive.ObjectID{id}
+	for len(level) > 0 && height <= maxDepth {
+		var next []primitive.ObjectID
+		for _, c := range categories {
+			if c.ParentID == nil {
+				continue
+			}
+			for _, p := range level {
+				if *c.ParentID == p {
+					next = append(next, c.ID)
+				}
+			}
+		}
+		if len(next) == 0 {
+			break
+		}
+		level = next
+		height++
+	}
+	return height
+}
+
+// Delete removes a category. Categories that still have subcategories or
+// products cannot be deleted.
+func (s *Service) Delete(ctx context.Context, idHex string) error {
+	id, err := primitive.ObjectIDFromHex(idHex)
+	if err != nil {
+		return ErrCategoryNotFound
+	}
+
+	hasChildren, err := s.repo.HasChildren(ctx, id)
+	if err != nil {
+		return err
+	}
+	if hasChildren {
+		return ErrCategoryInUse
+	}
+	hasProducts, err := s.products.HasProductsInCategory(ctx, id)
+	if err != nil {
+		return fmt.Errorf("category service delete: %w", err)
+	}
+	if hasProducts {
+		return ErrCategoryInUse
+	}
+
+	deleted, err := s.repo.Delete(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrCategoryNotFound
+	}
+	slog.Info("category deleted", "id", idHex)
+	return nil
+}
+
+// ErrCategoryNotFound indicates the category does not exist.
+var ErrCategoryNotFound = fmt.Errorf("category not found")
+
+// ErrParentNotFound indicates the requested parent category does not exist.
+var ErrParentNotFound = fmt.Errorf("parent category not found")
+
+// ErrInvalidParent indicates the requested parent would put the category
+// below itself or nest categories too deeply.
+var ErrInvalidParent = fmt.Errorf("invalid parent category")
+
+// ErrInvalidName indicates the name has no letters or digits to make a slug
+// from.
+var ErrInvalidName = fmt.Errorf("name must contain letters or digits")
+
+// ErrCategoryInUse indicates the category still has subcategories or
+// products.
+var ErrCategoryInUse = fmt.Errorf("category has subcategories or products")
+
+// ErrEmptyUpdate indicates an update request contained no fields.
+var ErrEmptyUpdate = fmt.Errorf("no fields to update")
@@ -0,0 +1,99 @@
+package inventory
+
+import (
+	"time"
+)
+
+// AdjustRequest is the payload for an admin stock adjustment. The first
+// adjustment of a variant starts tracking its stock.
+type AdjustRequest struct {
+	SKU    string `json:"sku"    validate:"required,sku"`
+	Delta  int64  `json:"delta"  validate:"required,gte=-100000,lte=100000"`
+	Reason string `json:"reason" validate:"required,oneof=restock return waste damage correction"`
+	Note   string `json:"note"   validate:"max=200"`
+}
+
+// ThresholdRequest is the payload for changing a variant's low-stock
+// threshold.
+type ThresholdRequest struct {
+	SKU               string `json:"sku"                 validate:"required,sku"`
+	LowStockThreshold int64  `json:"low_stock_threshold" validate:"gte=0,lte=100000"`
+}
+
+// LevelResponse is the public representation of a stock level.
+type LevelResponse struct {
+	SKU               string    `json:"sku"`
+	Tracked           bool      `json:"tracked"`
+	OnHand            int64     `json:"on_hand"`
+	Reserved          int64     `json:"reserved"`
+	Available         int64     `json:"available"`
+	LowStockThreshold int64     `json:"low_stock_threshold"`
+	InStock           bool      `json:"in_stock"`
+	UpdatedAt         time.Time `json:"updated_at,omitempty"`
+}
+
+// ToResponse converts a Level to a LevelResponse.
+func (l *Level) ToResponse() LevelResponse {
+	return LevelResponse{
+		SKU:               l.SKU,
+		Tracked:           true,
+		OnHand:            l.OnHand,
+		Reserved:          l.Reserved,
+		Available:         l.Available(),
+		LowStockThreshold: l.LowStockThreshold,
+		InStock:           l.InStock(),
+		UpdatedAt:         l.UpdatedAt,
+	}
+}
+
+// EntryResponse is the public representation of a ledger entry.
+type EntryResponse struct {
+	ID            string    `json:"id"`
+	SKU           string    `json:"sku"`
+	Reason        string    `json:"reason"`
+	Delta         int64     `json:"delta"`
+	ReservedDelta int64     `json:"reserved_delta"`
+	OnHand        int64     `json:"on_hand"`
+	Reserved      int64     `json:"reserved"`
+	Note          string    `json:"note,omitempty"`
+	ReservationID string    `json:"reservation_id,omitempty"`
+	ActorID       string    `json:"actor_id,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// ToResponse converts an Entry to an EntryResponse.
+func (e *Entry) ToResponse() EntryResponse {
+	r := EntryResponse{
+		ID:            e.ID.Hex(),
+		SKU:           e.SKU,
+		Reason:        e.Reason,
+		Delta:         e.Delta,
+		ReservedDelta: e.ReservedDelta,
+		OnHand:        e.OnHand,
+		Reserved:      e.Reserved,
+		Note:          e.Note,
+		CreatedAt:     e.CreatedAt,
+	}
+	if e.ReservationID != nil {
+		r.ReservationID = e.ReservationID.Hex()
+	}
+	if e.ActorID != nil {
+		r.ActorID = e.ActorID.Hex()
+	}
+	return r
+}
+
+// AdjustResponse is returned by a stock adjustment.
+type AdjustResponse struct {
+	Level LevelResponse `json:"level"`
+	Entry EntryResponse `json:"entry"`
+}
+
+// LedgerResponse is the paginated ledger envelope.
+type LedgerResponse struct {
+	Items      []EntryResponse `json:"items"`
+	Page       int64           `json:"page"`
+	PageSize   int64           `json:"page_size"`
+	Total      int64           `json:"total"`
+	TotalPages int64           `json:"total_pages"`
+}
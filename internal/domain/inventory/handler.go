@@ -0,0 +1,121 @@
+package inventory
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/one-backend-go/internal/pkg/pagination"
+	"github.com/one-backend-go/internal/pkg/reqctx"
+	"github.com/one-backend-go/internal/pkg/resp"
+	"github.com/one-backend-go/internal/pkg/validate"
+)
+
+// Handler holds HTTP handlers for the stock endpoints.
+type Handler struct {
+	svc      *Service
+	validate *validate.Validator
+}
+
+// NewHandler creates a new inventory Handler.
+func NewHandler(svc *Service, v *validate.Validator) *Handler {
+	return &Handler{svc: svc, validate: v}
+}
+
+// Levels handles GET /api/v1/admin/products/:id/stock.
+func (h *Handler) Levels(c *gin.Context) {
+	items, err := h.svc.Levels(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+
+	resp.Success(c, http.StatusOK, gin.H{"items": items})
+}
+
+// Adjust handles POST /api/v1/admin/products/:id/stock/adjustments.
+func (h *Handler) Adjust(c *gin.Context) {
+	var req AdjustRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.Fail(c, http.StatusBadRequest, "BAD_REQUEST", "invalid JSON body", nil)
+		return
+	}
+
+	if errs := h.validate.Struct(req); errs != nil {
+		resp.ValidationError(c, errs)
+		return
+	}
+
+	actorID, _ := reqctx.UserID(c)
+	l, e, err := h.svc.Adjust(c.Request.Context(), actorID, c.Param("id"), req)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+
+	resp.Success(c, http.StatusCreated, AdjustResponse{Level: l.ToResponse(), Entry: e.ToResponse()})
+}
+
+// SetThreshold handles PUT /api/v1/admin/products/:id/stock/threshold.
+func (h *Handler) SetThreshold(c *gin.Context) {
+	var req ThresholdRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.Fail(c, http.StatusBadRequest, "BAD_REQUEST", "invalid JSON body", nil)
+		return
+	}
+
+	if errs := h.validate.Struct(req); errs != nil {
+		resp.ValidationError(c, errs)
+		return
+	}
+
+	l, err := h.svc.SetThreshold(c.Request.Context(), c.Param("id"), req)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+
+	resp.Success(c, http.StatusOK, l.ToResponse())
+}
+
+// Ledger handles GET /api/v1/admin/products/:id/stock/ledger.
+func (h *Handler) Ledger(c *gin.Context) {
+	p := pagination.DefaultParams()
+
+	if v := c.Query("page"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			p.Page = n
+		}
+	}
+	if v := c.Query("page_size"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			p.PageSize = n
+		}
+	}
+
+	result, err := h.svc.Ledger(c.Request.Context(), c.Param("id"), c.Query("sku"), p)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+
+	resp.Success(c, http.StatusOK, result)
+}
+
+// fail maps inventory service errors to HTTP responses.
+func (h *Handler) fail(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrProductNotFound):
+		resp.NotFound(c, "product not found")
+	case errors.Is(err, ErrUnknownSKU):
+		resp.ValidationError(c, map[string]string{"sku": err.Error()})
+	case errors.Is(err, ErrReasonDirection):
+		resp.ValidationError(c, map[string]string{"delta": err.Error()})
+	case errors.Is(err, ErrInsufficientStock):
+		resp.Conflict(c, "not enough unreserved stock to remove")
+	default:
+		resp.InternalError(c)
+	}
+}
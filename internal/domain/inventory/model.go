@@ -0,0 +1,77 @@
+// Package inventory tracks stock counts and reservations for product
+// variants and keeps their availability in step with the stock.
+package inventory
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Level is the stock of one product variant. Variants without a level are
+// not tracked and never run out. A product sold in one size is tracked
+// through its single variant.
+type Level struct {
+	ID                primitive.ObjectID `bson:"_id,omitempty"`
+	ProductID         primitive.ObjectID `bson:"product_id"`
+	SKU               string             `bson:"sku"`
+	OnHand            int64              `bson:"on_hand"`
+	Reserved          int64              `bson:"reserved"` // held for orders not yet fulfilled
+	LowStockThreshold int64              `bson:"low_stock_threshold"`
+	UpdatedAt         time.Time          `bson:"updated_at"`
+}
+
+// Available returns the stock that can still be reserved.
+func (l *Level) Available() int64 {
+	return l.OnHand - l.Reserved
+}
+
+// InStock reports whether the variant can be sold: once the available stock
+// falls to the low-stock threshold the variant is taken off sale, keeping
+// the rest back for orders already being prepared.
+func (l *Level) InStock() bool {
+	return l.Available() > l.LowStockThreshold
+}
+
+// Reservation holds stock for an order until it is committed as a sale or
+// released. Reservations that are neither expire and are released by
+// ReleaseExpired.
+type Reservation struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	ProductID primitive.ObjectID `bson:"product_id"`
+	SKU       string             `bson:"sku"`
+	Quantity  int64              `bson:"quantity"`
+	ExpiresAt time.Time          `bson:"expires_at"`
+	CreatedAt time.Time          `bson:"created_at"`
+}
+
+// Reasons recorded in the stock ledger. Admins adjust stock with the first
+// group; the second is recorded by reservations and sales.
+const (
+	ReasonRestock    = "restock"    // delivery received
+	ReasonReturn     = "return"     // returned unused
+	ReasonWaste      = "waste"      // expired or spoiled
+	ReasonDamage     = "damage"     // broken or dropped
+	ReasonCorrection = "correction" // count did not match
+
+	ReasonReservation = "reservation"
+	ReasonRelease     = "release"
+	ReasonSale        = "sale"
+)
+
+// Entry is one change in the stock ledger. Entries are never updated, so
+// the ledger explains every count a level has had.
+type Entry struct {
+	ID            primitive.ObjectID  `bson:"_id,omitempty"`
+	ProductID     primitive.ObjectID  `bson:"product_id"`
+	SKU           string              `bson:"sku"`
+	Reason        string              `bson:"reason"`
+	Delta         int64               `bson:"delta"`          // change in on_hand
+	ReservedDelta int64               `bson:"reserved_delta"` // change in reserved
+	OnHand        int64               `bson:"on_hand"`        // after the change
+	Reserved      int64               `bson:"reserved"`       // after the change
+	Note          string              `bson:"note,omitempty"`
+	ReservationID *primitive.ObjectID `bson:"reservation_id,omitempty"`
+	ActorID       *primitive.ObjectID `bson:"actor_id,omitempty"` // user or API key that made an adjustment
+	CreatedAt     time.Time           `bson:"created_at"`
+}
@@ -0,0 +1,313 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/one-backend-go/internal/pkg/pagination"
+)
+
+// Repository provides persistence for stock levels, reservations and the
+// stock ledger.
+//
+// Every change to a level is a single conditional update whose filter
+// states the stock the change needs, so concurrent orders can never take
+// the counts below zero: the update that would do so matches nothing and
+// the caller gets nil back.
+type Repository struct {
+	col          *mongo.Collection
+	reservations *mongo.Collection
+	ledger       *mongo.Collection
+}
+
+// NewRepository returns a new inventory Repository.
+func NewRepository(db *mongo.Database) *Repository {
+	return &Repository{
+		col:          db.Collection("stock_levels"),
+		reservations: db.Collection("stock_reservations"),
+		ledger:       db.Collection("stock_ledger"),
+	}
+}
+
+// Find retrieves the level of a variant, or nil if it is not tracked.
+func (r *Repository) Find(ctx context.Context, productID primitive.ObjectID, sku string) (*Level, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var l Level
+	err := r.col.FindOne(ctx, levelKey(productID, sku)).Decode(&l)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("inventory repo find: %w", err)
+	}
+	return &l, nil
+}
+
+// ListByProduct returns the levels of a product's tracked variants.
+func (r *Repository) ListByProduct(ctx context.Context, productID primitive.ObjectID) ([]Level, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	cursor, err := r.col.Find(ctx, bson.M{"product_id": productID}, options.Find().SetSort(bson.D{{Key: "sku", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("inventory repo listByProduct: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	levels := []Level{}
+	if err = cursor.All(ctx, &levels); err != nil {
+		return nil, fmt.Errorf("inventory repo decode: %w", err)
+	}
+	return levels, nil
+}
+
+// Track starts tracking a variant with no stock, unless it already is.
+func (r *Repository) Track(ctx context.Context, productID primitive.ObjectID, sku string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := r.col.UpdateOne(ctx,
+		levelKey(productID, sku),
+		bson.M{"$setOnInsert": bson.M{
+			"on_hand":             int64(0),
+			"reserved":            int64(0),
+			"low_stock_threshold": int64(0),
+			"updated_at":          time.Now().UTC(),
+		}},
+		options.Update().SetUpsert(true),
+	)
+	// Two first adjustments racing both upsert; the loser's insert hits the
+	// unique index and the level it wanted exists anyway.
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("inventory repo track: %w", err)
+	}
+	return nil
+}
+
+// Adjust changes the stock on hand by delta, which may be negative as long
+// as no reserved stock is taken. Returns nil if the variant is not tracked
+// or has too little stock.
+func (r *Repository) Adjust(ctx context.Context, productID primitive.ObjectID, sku string, delta int64) (*Level, error) {
+	return r.update(ctx, productID, sku,
+		bson.M{"$gte": bson.A{bson.M{"$add": bson.A{"$on_hand", delta}}, "$reserved"}},
+		bson.M{"$inc": bson.M{"on_hand": delta}},
+		"adjust",
+	)
+}
+
+// Reserve holds quantity units if that many are available. Returns nil if
+// the variant is not tracked or has too little stock.
+func (r *Repository) Reserve(ctx context.Context, productID primitive.ObjectID, sku string, quantity int64) (*Level, error) {
+	return r.update(ctx, productID, sku,
+		bson.M{"$gte": bson.A{bson.M{"$subtract": bson.A{"$on_hand", "$reserved"}}, quantity}},
+		bson.M{"$inc": bson.M{"reserved": quantity}},
+		"reserve",
+	)
+}
+
+// Unreserve returns quantity held units to the available stock.
+func (r *Repository) Unreserve(ctx context.Context, productID primitive.ObjectID, sku string, quantity int64) (*Level, error) {
+	return r.update(ctx, productID, sku,
+		bson.M{"$gte": bson.A{"$reserved", quantity}},
+		bson.M{"$inc": bson.M{"reserved": -quantity}},
+		"unreserve",
+	)
+}
+
+// Consume removes quantity held units from the stock on hand once they
+// have been sold.
+func (r *Repository) Consume(ctx context.Context, productID primitive.ObjectID, sku string, quantity int64) (*Level, error) {
+	return r.update(ctx, productID, sku,
+		bson.M{"$and": bson.A{
+			bson.M{"$gte": bson.A{"$reserved", quantity}},
+			bson.M{"$gte": bson.A{"$on_hand", quantity}},
+		}},
+		bson.M{"$inc": bson.M{"on_hand": -quantity, "reserved": -quantity}},
+		"consume",
+	)
+}
+
+// SetThreshold changes the low-stock threshold of a tracked variant.
+func (r *Repository) SetThreshold(ctx context.Context, productID primitive.ObjectID, sku string, threshold int64) (*Level, error) {
+	return r.update(ctx, productID, sku, true,
+		bson.M{"$set": bson.M{"low_stock_threshold": threshold}},
+		"setThreshold",
+	)
+}
+
+// update applies change to a level if cond, an aggregation expression over
+// the level, holds. Returns the level after the change, or nil if the level
+// does not exist or cond is false.
+func (r *Repository) update(ctx context.Context, productID primitive.ObjectID, sku string, cond interface{}, change bson.M, op string) (*Level, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := levelKey(productID, sku)
+	filter["$expr"] = cond
+	if set, ok := change["$set"].(bson.M); ok {
+		set["updated_at"] = time.Now().UTC()
+	} else {
+		change["$set"] = bson.M{"updated_at": time.Now().UTC()}
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var l Level
+	err := r.col.FindOneAndUpdate(ctx, filter, change, opts).Decode(&l)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("inventory repo %s: %w", op, err)
+	}
+	return &l, nil
+}
+
+func levelKey(productID primitive.ObjectID, sku string) bson.M {
+	return bson.M{"product_id": productID, "sku": sku}
+}
+
+// Held returns those of the product's SKUs that still have stock on hand or
+// reserved.
+func (r *Repository) Held(ctx context.Context, productID primitive.ObjectID, skus []string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"product_id": productID,
+		"sku":        bson.M{"$in": skus},
+		"$or":        bson.A{bson.M{"on_hand": bson.M{"$gt": 0}}, bson.M{"reserved": bson.M{"$gt": 0}}},
+	}
+	cursor, err := r.col.Find(ctx, filter, options.Find().SetProjection(bson.M{"sku": 1}).SetSort(bson.D{{Key: "sku", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("inventory repo held: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var levels []Level
+	if err = cursor.All(ctx, &levels); err != nil {
+		return nil, fmt.Errorf("inventory repo decode: %w", err)
+	}
+	held := make([]string, 0, len(levels))
+	for _, l := range levels {
+		held = append(held, l.SKU)
+	}
+	return held, nil
+}
+
+// Forget deletes the stock levels and reservations of the product's SKUs,
+// or of all its variants if skus is nil. The ledger keeps their history.
+func (r *Repository) Forget(ctx context.Context, productID primitive.ObjectID, skus []string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"product_id": productID}
+	if skus != nil {
+		filter["sku"] = bson.M{"$in": skus}
+	}
+	if _, err := r.reservations.DeleteMany(ctx, filter); err != nil {
+		return fmt.Errorf("inventory repo forget reservations: %w", err)
+	}
+	if _, err := r.col.DeleteMany(ctx, filter); err != nil {
+		return fmt.Errorf("inventory repo forget levels: %w", err)
+	}
+	return nil
+}
+
+// CreateReservation stores a new reservation.
+func (r *Repository) CreateReservation(ctx context.Context, res *Reservation) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res.ID = primitive.NewObjectID()
+	res.CreatedAt = time.Now().UTC()
+
+	if _, err := r.reservations.InsertOne(ctx, res); err != nil {
+		return fmt.Errorf("inventory repo createReservation: %w", err)
+	}
+	return nil
+}
+
+// ClaimReservation deletes a reservation and returns it, or nil if it does
+// not exist. Only one caller can claim a reservation, so its stock is
+// released or sold exactly once.
+func (r *Repository) ClaimReservation(ctx context.Context, id primitive.ObjectID) (*Reservation, error) {
+	return r.claim(ctx, bson.M{"_id": id}, "claimReservation")
+}
+
+// ClaimExpired deletes and returns one reservation that expired at or
+// before now, or nil if there is none.
+func (r *Repository) ClaimExpired(ctx context.Context, now time.Time) (*Reservation, error) {
+	return r.claim(ctx, bson.M{"expires_at": bson.M{"$lte": now}}, "claimExpired")
+}
+
+func (r *Repository) claim(ctx context.Context, filter bson.M, op string) (*Reservation, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var res Reservation
+	err := r.reservations.FindOneAndDelete(ctx, filter).Decode(&res)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("inventory repo %s: %w", op, err)
+	}
+	return &res, nil
+}
+
+// AddEntry appends an entry to the stock ledger.
+func (r *Repository) AddEntry(ctx context.Context, e *Entry) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	e.ID = primitive.NewObjectID()
+	e.CreatedAt = time.Now().UTC()
+
+	if _, err := r.ledger.InsertOne(ctx, e); err != nil {
+		return fmt.Errorf("inventory repo addEntry: %w", err)
+	}
+	return nil
+}
+
+// ListEntries returns a page of a product's ledger, newest first, limited
+// to one variant if sku is not empty.
+func (r *Repository) ListEntries(ctx context.Context, productID primitive.ObjectID, sku string, p pagination.Params) ([]Entry, int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	f := bson.M{"product_id": productID}
+	if sku != "" {
+		f["sku"] = sku
+	}
+
+	total, err := r.ledger.CountDocuments(ctx, f)
+	if err != nil {
+		return nil, 0, fmt.Errorf("inventory repo count: %w", err)
+	}
+
+	opts := options.Find().
+		SetSkip(p.Skip()).
+		SetLimit(p.PageSize).
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}})
+
+	cursor, err := r.ledger.Find(ctx, f, opts)
+	if err != nil {
+		return nil, 0, fmt.Errorf("inventory repo listEntries: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var entries []Entry
+	if err = cursor.All(ctx, &entries); err != nil {
+		return nil, 0, fmt.Errorf("inventory repo decode: %w", err)
+	}
+	return entries, total, nil
+}
@@ -0,0 +1,400 @@
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/one-backend-go/internal/domain/product"
+	"github.com/one-backend-go/internal/pkg/pagination"
+)
+
+// Products looks up products and keeps their availability in step with
+// their stock. It is implemented by the product repository.
+type Products interface {
+	FindByID(ctx context.Context, id primitive.ObjectID) (*product.Product, error)
+	SetStockAvailability(ctx context.Context, id primitive.ObjectID, sku string, inStock bool) error
+}
+
+// Service contains business logic for stock levels and reservations.
+type Service struct {
+	repo     *Repository
+	products Products
+}
+
+// NewService creates a new inventory Service.
+func NewService(repo *Repository, products Products) *Service {
+	return &Service{repo: repo, products: products}
+}
+
+// Levels returns the stock of every variant of a product, in the product's
+// variant order. Untracked variants are included with Tracked false.
+func (s *Service) Levels(ctx context.Context, productIDHex string) ([]LevelResponse, error) {
+	p, err := s.product(ctx, productIDHex)
+	if err != nil {
+		return nil, err
+	}
+
+	levels, err := s.repo.ListByProduct(ctx, p.ID)
+	if err != nil {
+		return nil, fmt.Errorf("inventory service levels: %w", err)
+	}
+	bySKU := make(map[string]Level, len(levels))
+	for _, l := range levels {
+		bySKU[l.SKU] = l
+	}
+
+	items := make([]LevelResponse, 0, len(p.Variants))
+	for _, v := range p.Variants {
+		l, ok := bySKU[v.SKU]
+		if !ok {
+			items = append(items, LevelResponse{SKU: v.SKU, InStock: true})
+			continue
+		}
+		items = append(items, l.ToResponse())
+	}
+	return items, nil
+}
+
+// Adjust changes the stock on hand of a variant and records why in the
+// ledger. The first adjustment of a variant starts tracking its stock, unless
+// it would remove stock the variant does not have. Stock held by reservations
+// cannot be adjusted away.
+func (s *Service) Adjust(ctx context.Context, actorID primitive.ObjectID, productIDHex string, req AdjustRequest) (*Level, *Entry, error) {
+	if !reasonAllows(req.Reason, req.Delta) {
+		return nil, nil, ErrReasonDirection
+	}
+	p, sku, err := s.variant(ctx, productIDHex, req.SKU)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// An untracked variant has nothing to remove. Tracking it first would
+	// leave it tracked at zero, out of stock but still on sale.
+	tracked, err := s.repo.Find(ctx, p.ID, sku)
+	if err != nil {
+		return nil, nil, fmt.Errorf("inventory service adjust: %w", err)
+	}
+	if tracked == nil {
+		if req.Delta < 0 {
+			return nil, nil, ErrInsufficientStock
+		}
+		if err = s.repo.Track(ctx, p.ID, sku); err != nil {
+			return nil, nil, fmt.Errorf("inventory service adjust: %w", err)
+		}
+	}
+	l, err := s.repo.Adjust(ctx, p.ID, sku, req.Delta)
+	if err != nil {
+		return nil, nil, fmt.Errorf("inventory service adjust: %w", err)
+	}
+	if l == nil {
+		return nil, nil, ErrInsufficientStock
+	}
+
+	e := &Entry{Reason: req.Reason, Delta: req.Delta, Note: strings.TrimSpace(req.Note)}
+	if !actorID.IsZero() {
+		e.ActorID = &actorID
+	}
+	s.record(ctx, l, e)
+	slog.Info("stock adjusted", "product", p.ID.Hex(), "sku", sku, "delta", req.Delta, "reason", req.Reason, "on_hand", l.OnHand)
+	return l, e, nil
+}
+
+// reasonAllows reports whether an adjustment reason fits the direction of
+// delta: deliveries and returns add stock, waste and damage remove it, and
+// corrections go either way.
+func reasonAllows(reason string, delta int64) bool {
+	switch reason {
+	case ReasonRestock, ReasonReturn:
+		return delta > 0
+	case ReasonWaste, ReasonDamage:
+		return delta < 0
+	default:
+		return true
+	}
+}
+
+// SetThreshold changes the low-stock threshold of a variant, tracking its
+// stock if it was not yet, and takes it off sale or puts it back to match.
+func (s *Service) SetThreshold(ctx context.Context, productIDHex string, req ThresholdRequest) (*Level, error) {
+	p, sku, err := s.variant(ctx, productIDHex, req.SKU)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.repo.Track(ctx, p.ID, sku); err != nil {
+		return nil, fmt.Errorf("inventory service set threshold: %w", err)
+	}
+	l, err := s.repo.SetThreshold(ctx, p.ID, sku, req.LowStockThreshold)
+	if err != nil {
+		return nil, fmt.Errorf("inventory service set threshold: %w", err)
+	}
+	if l == nil {
+		return nil, ErrUnknownSKU
+	}
+
+	s.syncAvailability(ctx, l)
+	slog.Info("low-stock threshold changed", "product", p.ID.Hex(), "sku", sku, "threshold", l.LowStockThreshold)
+	return l, nil
+}
+
+// Ledger returns a page of a product's stock ledger, newest first, limited
+// to one variant if sku is not empty. Entries outlive the product.
+func (s *Service) Ledger(ctx context.Context, productIDHex, sku string, p pagination.Params) (*LedgerResponse, error) {
+	id, err := primitive.ObjectIDFromHex(productIDHex)
+	if err != nil {
+		return nil, ErrProductNotFound
+	}
+	p.Clamp()
+
+	entries, total, err := s.repo.ListEntries(ctx, id, strings.ToUpper(sku), p)
+	if err != nil {
+		return nil, fmt.Errorf("inventory service ledger: %w", err)
+	}
+
+	items := make([]EntryResponse, 0, len(entries))
+	for i := range entries {
+		items = append(items, entries[i].ToResponse())
+	}
+
+	return &LedgerResponse{
+		Items:      items,
+		Page:       p.Page,
+		PageSize:   p.PageSize,
+		Total:      total,
+		TotalPages: pagination.TotalPages(total, p.PageSize),
+	}, nil
+}
+
+// Reserve holds quantity units of a variant for ttl, until the reservation
+// is committed or released. It fails with ErrInsufficientStock if fewer
+// are available, however many orders are reserving at once. Untracked
+// variants never run out, so nothing is held for them and Reserve returns
+// nil.
+func (s *Service) Reserve(ctx context.Context, productID primitive.ObjectID, sku string, quantity int64, ttl time.Duration) (*Reservation, error) {
+	if quantity <= 0 {
+		return nil, ErrInvalidQuantity
+	}
+	sku = strings.ToUpper(sku)
+
+	l, err := s.repo.Reserve(ctx, productID, sku, quantity)
+	if err != nil {
+		return nil, fmt.Errorf("inventory service reserve: %w", err)
+	}
+	if l == nil {
+		tracked, err := s.repo.Find(ctx, productID, sku)
+		if err != nil {
+			return nil, fmt.Errorf("inventory service reserve: %w", err)
+		}
+		if tracked == nil {
+			return nil, nil
+		}
+		return nil, ErrInsufficientStock
+	}
+
+	res := &Reservation{ProductID: productID, SKU: sku, Quantity: quantity, ExpiresAt: time.Now().UTC().Add(ttl)}
+	if err = s.repo.CreateReservation(ctx, res); err != nil {
+		// Availability has not been touched yet, so undoing the hold is
+		// enough.
+		if _, uerr := s.repo.Unreserve(ctx, productID, sku, quantity); uerr != nil {
+			slog.Error("failed to undo stock reservation", "product", productID.Hex(), "sku", sku, "error", uerr)
+		}
+		return nil, fmt.Errorf("inventory service reserve: %w", err)
+	}
+
+	s.record(ctx, l, &Entry{Reason: ReasonReservation, ReservedDelta: quantity, ReservationID: &res.ID})
+	return res, nil
+}
+
+// Commit turns a reservation into a sale, removing its units from the
+// stock on hand.
+func (s *Service) Commit(ctx context.Context, id primitive.ObjectID) error {
+	res, err := s.repo.ClaimReservation(ctx, id)
+	if err != nil {
+		return fmt.Errorf("inventory service commit: %w", err)
+	}
+	if res == nil {
+		return ErrReservationNotFound
+	}
+
+	l, err := s.repo.Consume(ctx, res.ProductID, res.SKU, res.Quantity)
+	if err != nil {
+		return fmt.Errorf("inventory service commit: %w", err)
+	}
+	if l == nil {
+		// Only happens if the level was removed behind our back.
+		return fmt.Errorf("inventory service commit: no held stock for %s", res.SKU)
+	}
+
+	s.record(ctx, l, &Entry{Reason: ReasonSale, Delta: -res.Quantity, ReservedDelta: -res.Quantity, ReservationID: &res.ID})
+	return nil
+}
+
+// Release returns the units of a reservation to the available stock.
+func (s *Service) Release(ctx context.Context, id primitive.ObjectID) error {
+	res, err := s.repo.ClaimReservation(ctx, id)
+	if err != nil {
+		return fmt.Errorf("inventory service release: %w", err)
+	}
+	if res == nil {
+		return ErrReservationNotFound
+	}
+	return s.release(ctx, res)
+}
+
+// ReleaseExpired releases every reservation that has expired and returns
+// how many there were.
+func (s *Service) ReleaseExpired(ctx context.Context) (int, error) {
+	n := 0
+	for {
+		res, err := s.repo.ClaimExpired(ctx, time.Now().UTC())
+		if err != nil {
+			return n, fmt.Errorf("inventory service release expired: %w", err)
+		}
+		if res == nil {
+			return n, nil
+		}
+		if err = s.release(ctx, res); err != nil {
+			return n, err
+		}
+		n++
+	}
+}
+
+// ReleaseExpiredEvery calls ReleaseExpired every interval until ctx is
+// done.
+func (s *Service) ReleaseExpiredEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.ReleaseExpired(ctx)
+			if err != nil {
+				slog.Error("failed to release expired reservations", "error", err)
+			}
+			if n > 0 {
+				slog.Info("expired reservations released", "count", n)
+			}
+		}
+	}
+}
+
+// release returns the units of a claimed reservation to the available
+// stock.
+func (s *Service) release(ctx context.Context, res *Reservation) error {
+	l, err := s.repo.Unreserve(ctx, res.ProductID, res.SKU, res.Quantity)
+	if err != nil {
+		return fmt.Errorf("inventory service release: %w", err)
+	}
+	if l == nil {
+		return fmt.Errorf("inventory service release: no held stock for %s", res.SKU)
+	}
+
+	s.record(ctx, l, &Entry{Reason: ReasonRelease, ReservedDelta: -res.Quantity, ReservationID: &res.ID})
+	return nil
+}
+
+// record appends the change that produced l to the ledger and, if the
+// change moved the variant across its low-stock threshold, takes it off
+// sale or puts it back.
+//
+// The stock has already changed by now, so a failure to write the ledger
+// is logged rather than returned: a retry would change the stock again.
+func (s *Service) record(ctx context.Context, l *Level, e *Entry) {
+	e.ProductID, e.SKU = l.ProductID, l.SKU
+	e.OnHand, e.Reserved = l.OnHand, l.Reserved
+	if err := s.repo.AddEntry(ctx, e); err != nil {
+		slog.Error("failed to record stock change", "product", l.ProductID.Hex(), "sku", l.SKU, "reason", e.Reason, "error", err)
+	}
+
+	before := Level{OnHand: l.OnHand - e.Delta, Reserved: l.Reserved - e.ReservedDelta, LowStockThreshold: l.LowStockThreshold}
+	if before.InStock() != l.InStock() {
+		s.syncAvailability(ctx, l)
+	}
+}
+
+// syncAvailability puts a variant on or off sale to match its level.
+// Another change may cross the threshold back between reading the level and
+// updating the product, so the level is read again afterwards and the
+// product updated once more if it no longer matches.
+func (s *Service) syncAvailability(ctx context.Context, l *Level) {
+	for i := 0; i < 3; i++ {
+		inStock := l.InStock()
+		if err := s.products.SetStockAvailability(ctx, l.ProductID, l.SKU, inStock); err != nil {
+			slog.Error("failed to update availability from stock", "product", l.ProductID.Hex(), "sku", l.SKU, "error", err)
+			return
+		}
+		if !inStock {
+			slog.Info("variant out of stock", "product", l.ProductID.Hex(), "sku", l.SKU, "available", l.Available())
+		}
+
+		fresh, err := s.repo.Find(ctx, l.ProductID, l.SKU)
+		if err != nil || fresh == nil || fresh.InStock() == inStock {
+			return
+		}
+		l = fresh
+	}
+}
+
+// product returns the product with the given ID.
+func (s *Service) product(ctx context.Context, idHex string) (*product.Product, error) {
+	id, err := primitive.ObjectIDFromHex(idHex)
+	if err != nil {
+		return nil, ErrProductNotFound
+	}
+
+	p, err := s.products.FindByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("inventory service product: %w", err)
+	}
+	if p == nil {
+		return nil, ErrProductNotFound
+	}
+	return p, nil
+}
+
+// variant returns the product with the given ID and the SKU, in upper case,
+// of its variant.
+func (s *Service) variant(ctx context.Context, productIDHex, sku string) (*product.Product, string, error) {
+	p, err := s.product(ctx, productIDHex)
+	if err != nil {
+		return nil, "", err
+	}
+
+	sku = strings.ToUpper(sku)
+	for _, v := range p.Variants {
+		if v.SKU == sku {
+			return p, sku, nil
+		}
+	}
+	return nil, "", ErrUnknownSKU
+}
+
+// ErrProductNotFound indicates the product does not exist.
+var ErrProductNotFound = fmt.Errorf("product not found")
+
+// ErrUnknownSKU indicates the product has no variant with the SKU.
+var ErrUnknownSKU = fmt.Errorf("product has no variant with this sku")
+
+// ErrInsufficientStock indicates there is not enough unreserved stock for
+// the change.
+var ErrInsufficientStock = fmt.Errorf("not enough stock")
+
+// ErrReasonDirection indicates an adjustment's reason does not fit the sign
+// of its delta, such as a restock that removes stock.
+var ErrReasonDirection = fmt.Errorf("restock and return must add stock; waste and damage must remove it")
+
+// ErrInvalidQuantity indicates a reservation for no units.
+var ErrInvalidQuantity = fmt.Errorf("quantity must be positive")
+
+// ErrReservationNotFound indicates the reservation does not exist, or was
+// already committed, released or expired.
+var ErrReservationNotFound = fmt.Errorf("reservation not found")
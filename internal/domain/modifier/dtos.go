@@ -0,0 +1,73 @@
+package modifier
+
+import "time"
+
+// ── Request DTOs ───────────────────────────────────────────────────────────────
+
+// CreateRequest is the body for POST /api/v1/modifier-groups.
+type CreateRequest struct {
+	Name      string          `json:"name"       validate:"required,min=2,max=60"`
+	MinSelect int             `json:"min_select" validate:"gte=0,lte=30"`
+	MaxSelect int             `json:"max_select" validate:"gte=0,lte=30"`
+	Required  bool            `json:"required"`
+	Options   []OptionRequest `json:"options"    validate:"required,min=1,max=30,dive"`
+}
+
+// UpdateRequest is the body for PUT /api/v1/modifier-groups/:id. Options
+// replaces all options.
+type UpdateRequest struct {
+	Name      *string          `json:"name"       validate:"omitempty,min=2,max=60"`
+	MinSelect *int             `json:"min_select" validate:"omitempty,gte=0,lte=30"`
+	MaxSelect *int             `json:"max_select" validate:"omitempty,gte=0,lte=30"`
+	Required  *bool            `json:"required"`
+	Options   *[]OptionRequest `json:"options"    validate:"omitempty,min=1,max=30,dive"`
+}
+
+// OptionRequest describes one option of a group.
+type OptionRequest struct {
+	Name            string `json:"name"              validate:"required,max=60"`
+	PriceDeltaCents int64  `json:"price_delta_cents" validate:"gte=-100000,lte=100000"`
+	IsAvailable     *bool  `json:"is_available"`
+}
+
+// Selection is a customer's choice of options in one group.
+type Selection struct {
+	GroupID   string   `json:"group_id"   validate:"required"`
+	OptionIDs []string `json:"option_ids" validate:"max=30"`
+}
+
+// ── Response DTOs ──────────────────────────────────────────────────────────────
+
+// Response is the API representation of a modifier group.
+type Response struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	MinSelect int       `json:"min_select"`
+	MaxSelect int       `json:"max_select"`
+	Required  bool      `json:"required"`
+	Options   []Option  `json:"options"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PricedOption is an option chosen by a customer, with its price delta.
+type PricedOption struct {
+	GroupID         string `json:"group_id"`
+	OptionID        string `json:"option_id"`
+	Name            string `json:"name"`
+	PriceDeltaCents int64  `json:"price_delta_cents"`
+}
+
+// ToResponse converts a Group model to its public response form.
+func (g *Group) ToResponse() Response {
+	return Response{
+		ID:        g.ID.Hex(),
+		Name:      g.Name,
+		MinSelect: g.MinSelect,
+		MaxSelect: g.MaxSelect,
+		Required:  g.Required,
+		Options:   g.Options,
+		CreatedAt: g.CreatedAt,
+		UpdatedAt: g.UpdatedAt,
+	}
+}
@@ -0,0 +1,116 @@
+package modifier
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/one-backend-go/internal/pkg/resp"
+	"github.com/one-backend-go/internal/pkg/validate"
+)
+
+// Handler holds HTTP handlers for modifier group endpoints.
+type Handler struct {
+	svc      *Service
+	validate *validate.Validator
+}
+
+// NewHandler creates a new modifier group Handler.
+func NewHandler(svc *Service, v *validate.Validator) *Handler {
+	return &Handler{svc: svc, validate: v}
+}
+
+// List handles GET /api/v1/modifier-groups.
+func (h *Handler) List(c *gin.Context) {
+	items, err := h.svc.List(c.Request.Context())
+	if err != nil {
+		resp.InternalError(c)
+		return
+	}
+
+	resp.Success(c, http.StatusOK, gin.H{"items": items})
+}
+
+// Get handles GET /api/v1/modifier-groups/:id.
+func (h *Handler) Get(c *gin.Context) {
+	g, err := h.svc.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+
+	resp.Success(c, http.StatusOK, g.ToResponse())
+}
+
+// Create handles POST /api/v1/modifier-groups.
+func (h *Handler) Create(c *gin.Context) {
+	var req CreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.Fail(c, http.StatusBadRequest, "BAD_REQUEST", "invalid JSON body", nil)
+		return
+	}
+
+	if errs := h.validate.Struct(req); errs != nil {
+		resp.ValidationError(c, errs)
+		return
+	}
+
+	g, err := h.svc.Create(c.Request.Context(), req)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+
+	resp.Success(c, http.StatusCreated, g.ToResponse())
+}
+
+// Update handles PUT /api/v1/modifier-groups/:id.
+func (h *Handler) Update(c *gin.Context) {
+	var req UpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.Fail(c, http.StatusBadRequest, "BAD_REQUEST", "invalid JSON body", nil)
+		return
+	}
+
+	if errs := h.validate.Struct(req); errs != nil {
+		resp.ValidationError(c, errs)
+		return
+	}
+
+	g, err := h.svc.Update(c.Request.Context(), c.Param("id"), req)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+
+	resp.Success(c, http.StatusOK, g.ToResponse())
+}
+
+// Delete handles DELETE /api/v1/modifier-groups/:id.
+func (h *Handler) Delete(c *gin.Context) {
+	if err := h.svc.Delete(c.Request.Context(), c.Param("id")); err != nil {
+		h.fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "modifier group deleted"})
+}
+
+// fail maps modifier group service errors to HTTP responses.
+func (h *Handler) fail(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrGroupNotFound):
+		resp.NotFound(c, "modifier group not found")
+	case errors.Is(err, ErrGroupInUse):
+		resp.Conflict(c, err.Error())
+	case errors.Is(err, ErrInvalidLimits):
+		resp.ValidationError(c, map[string]string{"min_select": err.Error()})
+	case errors.Is(err, ErrDuplicateOption):
+		resp.ValidationError(c, map[string]string{"options": err.Error()})
+	case errors.Is(err, ErrEmptyUpdate):
+		resp.Fail(c, http.StatusBadRequest, "BAD_REQUEST", err.Error(), nil)
+	default:
+		resp.InternalError(c)
+	}
+}
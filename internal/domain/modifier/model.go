@@ -0,0 +1,62 @@
+// Package modifier provides reusable groups of options, such as toppings or
+// removals, that customers choose from when ordering a product.
+package modifier
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Group is a set of options with rules for how many a customer picks, for
+// example "Extra toppings: choose up to 3". Groups are attached to any number
+// of products.
+type Group struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	Name      string             `bson:"name"`
+	MinSelect int                `bson:"min_select"`
+	MaxSelect int                `bson:"max_select"` // 0: no limit
+	Required  bool               `bson:"required"`   // at least one option, even if MinSelect is 0
+	Options   []Option           `bson:"options"`
+	CreatedAt time.Time          `bson:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at"`
+}
+
+// Option is one choice in a group. Its price delta is added to the product's
+// price and may be negative or zero, as for "no onions".
+type Option struct {
+	ID              string `bson:"id"                json:"id"` // slug of the name, unique within the group
+	Name            string `bson:"name"              json:"name"`
+	PriceDeltaCents int64  `bson:"price_delta_cents" json:"price_delta_cents"`
+	IsAvailable     bool   `bson:"is_available"      json:"is_available"`
+}
+
+// minSelections returns how many options a customer must choose.
+func (g *Group) minSelections() int {
+	if g.Required {
+		return max(g.MinSelect, 1)
+	}
+	return g.MinSelect
+}
+
+// option returns the option with the given ID, or nil.
+func (g *Group) option(id string) *Option {
+	for i := range g.Options {
+		if g.Options[i].ID == id {
+			return &g.Options[i]
+		}
+	}
+	return nil
+}
+
+// checkLimits reports whether the selection limits can be met with the
+// group's options.
+func (g *Group) checkLimits() error {
+	if g.MaxSelect != 0 && g.MaxSelect < g.minSelections() {
+		return ErrInvalidLimits
+	}
+	if g.minSelections() > len(g.Options) {
+		return ErrInvalidLimits
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package modifier
+
+import "fmt"
+
+// Price checks a customer's selections against groups, the modifier groups
+// of one product, and returns the chosen options in group order with the sum
+// of their price deltas. Every group's limits apply, including groups the
+// customer made no selection in; each option can be chosen once.
+func Price(groups []Group, selections []Selection) ([]PricedOption, int64, error) {
+	byID := make(map[string]*Group, len(groups))
+	for i := range groups {
+		byID[groups[i].ID.Hex()] = &groups[i]
+	}
+
+	chosen := make(map[string][]string, len(selections))
+	for _, sel := range selections {
+		g := byID[sel.GroupID]
+		if g == nil {
+			return nil, 0, fmt.Errorf("%w: modifier group %s does not apply to this product", ErrInvalidSelection, sel.GroupID)
+		}
+		if _, dup := chosen[sel.GroupID]; dup {
+			return nil, 0, fmt.Errorf("%w: %s: selected more than once", ErrInvalidSelection, g.Name)
+		}
+		chosen[sel.GroupID] = sel.OptionIDs
+	}
+
+	var options []PricedOption
+	var delta int64
+	for i := range groups {
+		g := &groups[i]
+		ids := chosen[g.ID.Hex()]
+		if n := g.minSelections(); len(ids) < n {
+			return nil, 0, fmt.Errorf("%w: %s: choose at least %d", ErrInvalidSelection, g.Name, n)
+		}
+		if g.MaxSelect > 0 && len(ids) > g.MaxSelect {
+			return nil, 0, fmt.Errorf("%w: %s: choose at most %d", ErrInvalidSelection, g.Name, g.MaxSelect)
+		}
+
+		seen := make(map[string]bool, len(ids))
+		for _, id := range ids {
+			o := g.option(id)
+			switch {
+			case o == nil:
+				return nil, 0, fmt.Errorf("%w: %s: unknown option %q", ErrInvalidSelection, g.Name, id)
+			case !o.IsAvailable:
+				return nil, 0, fmt.Errorf("%w: %s: %s is not available", ErrInvalidSelection, g.Name, o.Name)
+			case seen[id]:
+				return nil, 0, fmt.Errorf("%w: %s: %s chosen more than once", ErrInvalidSelection, g.Name, o.Name)
+			}
+			seen[id] = true
+			options = append(options, PricedOption{
+				GroupID:         g.ID.Hex(),
+				OptionID:        o.ID,
+				Name:            o.Name,
+				PriceDeltaCents: o.PriceDeltaCents,
+			})
+			delta += o.PriceDeltaCents
+		}
+	}
+	return options, delta, nil
+}
+
+// ErrInvalidSelection indicates a customer's choice of options breaks the
+// rules of a modifier group.
+var ErrInvalidSelection = fmt.Errorf("invalid modifier selection")
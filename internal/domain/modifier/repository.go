@@ -0,0 +1,112 @@
+package modifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Repository provides persistence operations for modifier groups.
+type Repository struct {
+	col *mongo.Collection
+}
+
+// NewRepository returns a new modifier group Repository.
+func NewRepository(db *mongo.Database) *Repository {
+	return &Repository{col: db.Collection("modifier_groups")}
+}
+
+// Create inserts a new modifier group.
+func (r *Repository) Create(ctx context.Context, g *Group) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	g.ID = primitive.NewObjectID()
+	now := time.Now().UTC()
+	g.CreatedAt = now
+	g.UpdatedAt = now
+
+	if _, err := r.col.InsertOne(ctx, g); err != nil {
+		return fmt.Errorf("modifier repo create: %w", err)
+	}
+	return nil
+}
+
+// FindByID retrieves a modifier group by ID.
+func (r *Repository) FindByID(ctx context.Context, id primitive.ObjectID) (*Group, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var g Group
+	err := r.col.FindOne(ctx, bson.M{"_id": id}).Decode(&g)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("modifier repo findByID: %w", err)
+	}
+	return &g, nil
+}
+
+// FindByIDs retrieves the modifier groups with the given IDs, in no
+// particular order. Unknown IDs are skipped.
+func (r *Repository) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]Group, error) {
+	return r.find(ctx, bson.M{"_id": bson.M{"$in": ids}}, "findByIDs")
+}
+
+// List returns all modifier groups sorted by name.
+func (r *Repository) List(ctx context.Context) ([]Group, error) {
+	return r.find(ctx, bson.M{}, "list")
+}
+
+func (r *Repository) find(ctx context.Context, filter bson.M, op string) ([]Group, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	cursor, err := r.col.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("modifier repo %s: %w", op, err)
+	}
+	defer cursor.Close(ctx)
+
+	groups := []Group{}
+	if err = cursor.All(ctx, &groups); err != nil {
+		return nil, fmt.Errorf("modifier repo decode: %w", err)
+	}
+	return groups, nil
+}
+
+// Replace stores all fields of an existing group and returns it with its
+// new update time, or nil if it no longer exists.
+func (r *Repository) Replace(ctx context.Context, g *Group) (*Group, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	g.UpdatedAt = time.Now().UTC()
+	res, err := r.col.ReplaceOne(ctx, bson.M{"_id": g.ID}, g)
+	if err != nil {
+		return nil, fmt.Errorf("modifier repo replace: %w", err)
+	}
+	if res.MatchedCount == 0 {
+		return nil, nil
+	}
+	return g, nil
+}
+
+// Delete removes a modifier group. Returns true if a document was deleted.
+func (r *Repository) Delete(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res, err := r.col.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return false, fmt.Errorf("modifier repo delete: %w", err)
+	}
+	return res.DeletedCount > 0, nil
+}
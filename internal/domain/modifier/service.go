@@ -0,0 +1,217 @@
+package modifier
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/one-backend-go/internal/pkg/slug"
+)
+
+// ProductUsage reports whether products use a modifier group. It is
+// implemented by the product repository.
+type ProductUsage interface {
+	HasProductsWithModifierGroup(ctx context.Context, id primitive.ObjectID) (bool, error)
+}
+
+// Service contains business logic for modifier groups.
+type Service struct {
+	repo     *Repository
+	products ProductUsage
+}
+
+// NewService creates a new modifier group Service.
+func NewService(repo *Repository, products ProductUsage) *Service {
+	return &Service{repo: repo, products: products}
+}
+
+// List returns all modifier groups.
+func (s *Service) List(ctx context.Context) ([]Response, error) {
+	groups, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("modifier service list: %w", err)
+	}
+
+	items := make([]Response, 0, len(groups))
+	for i := range groups {
+		items = append(items, groups[i].ToResponse())
+	}
+	return items, nil
+}
+
+// Get returns a single modifier group.
+func (s *Service) Get(ctx context.Context, idHex string) (*Group, error) {
+	id, err := primitive.ObjectIDFromHex(idHex)
+	if err != nil {
+		return nil, ErrGroupNotFound
+	}
+
+	g, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("modifier service get: %w", err)
+	}
+	if g == nil {
+		return nil, ErrGroupNotFound
+	}
+	return g, nil
+}
+
+// Find returns the modifier groups with the given IDs in the same order,
+// skipping IDs that do not exist.
+func (s *Service) Find(ctx context.Context, ids []primitive.ObjectID) ([]Group, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	found, err := s.repo.FindByIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("modifier service find: %w", err)
+	}
+
+	byID := make(map[primitive.ObjectID]Group, len(found))
+	for _, g := range found {
+		byID[g.ID] = g
+	}
+	groups := make([]Group, 0, len(ids))
+	for _, id := range ids {
+		if g, ok := byID[id]; ok {
+			groups = append(groups, g)
+		}
+	}
+	return groups, nil
+}
+
+// Create adds a new modifier group.
+func (s *Service) Create(ctx context.Context, req CreateRequest) (*Group, error) {
+	options, err := buildOptions(req.Options)
+	if err != nil {
+		return nil, err
+	}
+	g := &Group{
+		Name:      strings.TrimSpace(req.Name),
+		MinSelect: req.MinSelect,
+		MaxSelect: req.MaxSelect,
+		Required:  req.Required,
+		Options:   options,
+	}
+	if err = g.checkLimits(); err != nil {
+		return nil, err
+	}
+
+	if err = s.repo.Create(ctx, g); err != nil {
+		return nil, err
+	}
+	slog.Info("modifier group created", "id", g.ID.Hex(), "name", g.Name)
+	return g, nil
+}
+
+// Update modifies an existing modifier group. The limits are checked against
+// the group as it will be stored.
+func (s *Service) Update(ctx context.Context, idHex string, req UpdateRequest) (*Group, error) {
+	g, err := s.Get(ctx, idHex)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name == nil && req.MinSelect == nil && req.MaxSelect == nil && req.Required == nil && req.Options == nil {
+		return nil, ErrEmptyUpdate
+	}
+	if req.Name != nil {
+		g.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.MinSelect != nil {
+		g.MinSelect = *req.MinSelect
+	}
+	if req.MaxSelect != nil {
+		g.MaxSelect = *req.MaxSelect
+	}
+	if req.Required != nil {
+		g.Required = *req.Required
+	}
+	if req.Options != nil {
+		if g.Options, err = buildOptions(*req.Options); err != nil {
+			return nil, err
+		}
+	}
+	if err = g.checkLimits(); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.repo.Replace(ctx, g)
+	if err != nil {
+		return nil, err
+	}
+	if updated == nil {
+		return nil, ErrGroupNotFound
+	}
+	return updated, nil
+}
+
+// buildOptions converts option requests to options identified by the slug
+// of their name.
+func buildOptions(reqs []OptionRequest) ([]Option, error) {
+	options := make([]Option, 0, len(reqs))
+	seen := make(map[string]bool, len(reqs))
+	for _, r := range reqs {
+		o := Option{
+			ID:              slug.Make(r.Name),
+			Name:            strings.TrimSpace(r.Name),
+			PriceDeltaCents: r.PriceDeltaCents,
+			IsAvailable:     true,
+		}
+		if r.IsAvailable != nil {
+			o.IsAvailable = *r.IsAvailable
+		}
+		if o.ID == "" || seen[o.ID] {
+			return nil, ErrDuplicateOption
+		}
+		seen[o.ID] = true
+		options = append(options, o)
+	}
+	return options, nil
+}
+
+// Delete removes a modifier group that no product uses.
+func (s *Service) Delete(ctx context.Context, idHex string) error {
+	id, err := primitive.ObjectIDFromHex(idHex)
+	if err != nil {
+		return ErrGroupNotFound
+	}
+
+	inUse, err := s.products.HasProductsWithModifierGroup(ctx, id)
+	if err != nil {
+		return fmt.Errorf("modifier service delete: %w", err)
+	}
+	if inUse {
+		return ErrGroupInUse
+	}
+
+	deleted, err := s.repo.Delete(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrGroupNotFound
+	}
+	slog.Info("modifier group deleted", "id", idHex)
+	return nil
+}
+
+// ErrGroupNotFound indicates the modifier group does not exist.
+var ErrGroupNotFound = fmt.Errorf("modifier group not found")
+
+// ErrGroupInUse indicates products still use the modifier group.
+var ErrGroupInUse = fmt.Errorf("modifier group is used by products")
+
+// ErrInvalidLimits indicates the selection limits contradict each other or
+// need more options than the group has.
+var ErrInvalidLimits = fmt.Errorf("selection limits cannot be met with these options")
+
+// ErrDuplicateOption indicates two options of a group have names that make
+// the same ID, or a name has no letters or digits.
+var ErrDuplicateOption = fmt.Errorf("options need distinct names with letters or digits")
+
+// ErrEmptyUpdate indicates an update request contained no fields.
+var ErrEmptyUpdate = fmt.Errorf("no fields to update")
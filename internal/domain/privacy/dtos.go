@@ -0,0 +1,7 @@
+package privacy
+
+// EraseRequest is the body for DELETE /api/v1/users/me. Accounts without a
+// password, created by single sign-on, leave Password empty.
+type EraseRequest struct {
+	Password string `json:"password"`
+}
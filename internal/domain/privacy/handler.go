@@ -0,0 +1,139 @@
+package privacy
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/one-backend-go/internal/domain/auth"
+	"github.com/one-backend-go/internal/domain/user"
+	"github.com/one-backend-go/internal/pkg/reqctx"
+	"github.com/one-backend-go/internal/pkg/resp"
+	"github.com/one-backend-go/internal/pkg/validate"
+)
+
+// Handler holds HTTP handlers for data export and erasure.
+type Handler struct {
+	svc      *Service
+	validate *validate.Validator
+}
+
+// NewHandler creates a new privacy Handler.
+func NewHandler(svc *Service, v *validate.Validator) *Handler {
+	return &Handler{svc: svc, validate: v}
+}
+
+// ExportMe handles GET /api/v1/users/me/export (authenticated).
+func (h *Handler) ExportMe(c *gin.Context) {
+	uid, ok := reqctx.UserID(c)
+	if !ok {
+		resp.Unauthorized(c, "authentication required")
+		return
+	}
+
+	archive, err := h.svc.Export(c.Request.Context(), uid)
+	h.export(c, uid, archive, err)
+}
+
+// EraseMe handles DELETE /api/v1/users/me (authenticated).
+func (h *Handler) EraseMe(c *gin.Context) {
+	uid, ok := reqctx.UserID(c)
+	if !ok {
+		resp.Unauthorized(c, "authentication required")
+		return
+	}
+
+	var req EraseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.Fail(c, http.StatusBadRequest, "BAD_REQUEST", "invalid JSON body", nil)
+		return
+	}
+
+	if errs := h.validate.Struct(req); errs != nil {
+		resp.ValidationError(c, errs)
+		return
+	}
+
+	sessionID, _ := reqctx.SessionID(c)
+	err := h.svc.EraseOwn(c.Request.Context(), uid, sessionID, req.Password, c.ClientIP())
+	if err != nil {
+		var throttled *auth.ThrottleError
+		switch {
+		case errors.As(err, &throttled):
+			auth.FailThrottled(c, throttled)
+		case errors.Is(err, auth.ErrWrongPassword):
+			resp.Fail(c, http.StatusBadRequest, "WRONG_PASSWORD", "password is incorrect", nil)
+		case errors.Is(err, auth.ErrPasswordRequired):
+			resp.ValidationError(c, map[string]string{"password": "password is required"})
+		case errors.Is(err, auth.ErrRecentSignInRequired):
+			resp.Fail(c, http.StatusForbidden, "RECENT_SIGN_IN_REQUIRED", "sign in again to confirm erasing your account", nil)
+		case errors.Is(err, user.ErrUserNotFound):
+			resp.Unauthorized(c, "user not found")
+		default:
+			resp.InternalError(c)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "account erased"})
+}
+
+// Export handles GET /api/v1/admin/users/:id/export.
+func (h *Handler) Export(c *gin.Context) {
+	actorID, _ := reqctx.UserID(c)
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		resp.NotFound(c, "user not found")
+		return
+	}
+
+	archive, err := h.svc.ExportUser(c.Request.Context(), actorID, id)
+	h.export(c, id, archive, err)
+}
+
+// Erase handles DELETE /api/v1/admin/users/:id.
+func (h *Handler) Erase(c *gin.Context) {
+	actorID, _ := reqctx.UserID(c)
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		resp.NotFound(c, "user not found")
+		return
+	}
+
+	if err = h.svc.EraseUser(c.Request.Context(), actorID, id); err != nil {
+		switch {
+		case errors.Is(err, user.ErrUserNotFound):
+			resp.NotFound(c, "user not found")
+		case errors.Is(err, user.ErrSelfModification):
+			resp.Forbidden(c, "use DELETE /api/v1/users/me to erase your own account")
+		case errors.Is(err, user.ErrInsufficientAuthority):
+			resp.Forbidden(c, "you cannot erase a user with permissions you do not hold")
+		default:
+			resp.InternalError(c)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "account erased"})
+}
+
+// export responds with a user's archive as a JSON file download, or with
+// the error that prevented the export.
+func (h *Handler) export(c *gin.Context, id primitive.ObjectID, archive *Archive, err error) {
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrUserNotFound):
+			resp.NotFound(c, "user not found")
+		case errors.Is(err, user.ErrInsufficientAuthority):
+			resp.Forbidden(c, "you cannot export a user with permissions you do not hold")
+		default:
+			resp.InternalError(c)
+		}
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="user-`+id.Hex()+`-export.json"`)
+	resp.Success(c, http.StatusOK, archive)
+}
@@ -0,0 +1,151 @@
+// Package privacy implements data subject requests: exporting everything the
+// service holds about a user, and erasing it. Each domain that stores
+// personal data contributes an Exporter and an Eraser.
+package privacy
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/one-backend-go/internal/domain/auth"
+	"github.com/one-backend-go/internal/domain/user"
+)
+
+// Exporter returns one domain's data about a user. The result is encoded as
+// JSON under the name the exporter was registered with; nil is omitted.
+type Exporter interface {
+	ExportUserData(ctx context.Context, userID primitive.ObjectID) (interface{}, error)
+}
+
+// Eraser deletes or anonymizes one domain's data about a user. Erasers must
+// be idempotent: a failed erasure is retried from the start.
+type Eraser interface {
+	EraseUserData(ctx context.Context, userID primitive.ObjectID) error
+}
+
+// Archive is a user's data export.
+type Archive struct {
+	UserID      string                 `json:"user_id"`
+	GeneratedAt time.Time              `json:"generated_at"`
+	Data        map[string]interface{} `json:"data"`
+}
+
+// Service runs exports and erasures across the registered domains.
+type Service struct {
+	users     *user.Service
+	auth      *auth.Service
+	exporters []namedExporter
+	erasers   []namedEraser
+}
+
+type namedExporter struct {
+	name string
+	Exporter
+}
+
+type namedEraser struct {
+	name string
+	Eraser
+}
+
+// NewService creates a privacy Service with no exporters or erasers.
+func NewService(users *user.Service, authSvc *auth.Service) *Service {
+	return &Service{users: users, auth: authSvc}
+}
+
+// RegisterExporter adds a section to every export.
+func (s *Service) RegisterExporter(name string, e Exporter) {
+	s.exporters = append(s.exporters, namedExporter{name: name, Exporter: e})
+}
+
+// RegisterEraser adds a step to every erasure. Erasers run in registration
+// order, so register the one that anonymizes the account itself last: others
+// may still need to look the user up.
+func (s *Service) RegisterEraser(name string, e Eraser) {
+	s.erasers = append(s.erasers, namedEraser{name: name, Eraser: e})
+}
+
+// Export collects every registered domain's data about a user.
+func (s *Service) Export(ctx context.Context, userID primitive.ObjectID) (*Archive, error) {
+	if err := s.ensureUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	archive := &Archive{
+		UserID:      userID.Hex(),
+		GeneratedAt: time.Now().UTC(),
+		Data:        make(map[string]interface{}, len(s.exporters)),
+	}
+	for _, e := range s.exporters {
+		data, err := e.ExportUserData(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("privacy export %s: %w", e.name, err)
+		}
+		if data != nil {
+			archive.Data[e.name] = data
+		}
+	}
+
+	slog.Info("user data exported", "user_id", userID.Hex())
+	return archive, nil
+}
+
+// ExportUser collects a user's data on an administrator's behalf, provided
+// the administrator holds every permission of the user's role.
+func (s *Service) ExportUser(ctx context.Context, actorID, userID primitive.ObjectID) (*Archive, error) {
+	if err := s.users.CheckAuthority(ctx, actorID, userID); err != nil {
+		return nil, err
+	}
+	return s.Export(ctx, userID)
+}
+
+// EraseOwn erases the caller's own data once auth.Service.ConfirmIdentity
+// accepts the password, or for accounts without one, the recent sign-in of
+// the session. Wrong passwords count as failed logins.
+func (s *Service) EraseOwn(ctx context.Context, userID, sessionID primitive.ObjectID, password, clientIP string) error {
+	if err := s.auth.ConfirmIdentity(ctx, userID, sessionID, password, clientIP); err != nil {
+		return err
+	}
+	return s.erase(ctx, userID, userID)
+}
+
+// EraseUser erases a user's data on an administrator's behalf, provided the
+// administrator holds every permission of the user's role. Administrators
+// erase their own account through EraseOwn instead.
+func (s *Service) EraseUser(ctx context.Context, actorID, userID primitive.ObjectID) error {
+	if actorID == userID {
+		return user.ErrSelfModification
+	}
+	if err := s.users.CheckAuthority(ctx, actorID, userID); err != nil {
+		return err
+	}
+	return s.erase(ctx, actorID, userID)
+}
+
+// erase runs every registered eraser, stopping at the first failure.
+func (s *Service) erase(ctx context.Context, actorID, userID primitive.ObjectID) error {
+	for _, e := range s.erasers {
+		if err := e.EraseUserData(ctx, userID); err != nil {
+			return fmt.Errorf("privacy erase %s: %w", e.name, err)
+		}
+	}
+
+	slog.Info("user data erased", "user_id", userID.Hex(), "by", actorID.Hex())
+	return nil
+}
+
+// ensureUser returns user.ErrUserNotFound unless the user exists.
+func (s *Service) ensureUser(ctx context.Context, userID primitive.ObjectID) error {
+	u, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if u == nil {
+		return user.ErrUserNotFound
+	}
+	return nil
+}
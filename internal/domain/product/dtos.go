@@ -1,42 +1,88 @@
 package product
 
-import "time"
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/one-backend-go/internal/domain/modifier"
+)
 
 // ── Request DTOs ───────────────────────────────────────────────────────────────
 
-// CreateRequest is the body for POST /api/v1/products (admin only).
+// CreateRequest is the body for POST /api/v1/products (admin only). Products
+// sold in several sizes list them as variants; otherwise price_cents gives
+// the price of a single "Regular" variant.
 type CreateRequest struct {
-	Name        string `json:"name"         validate:"required,min=2,max=80"`
-	Description string `json:"description"  validate:"max=1000"`
-	PriceCents  int64  `json:"price_cents"  validate:"gte=0"`
-	Category    string `json:"category"     validate:"required"`
-	ImageURL    string `json:"image_url"    validate:"omitempty,url"`
-	IsAvailable *bool  `json:"is_available"`
+	Name             string           `json:"name"               validate:"required,min=2,max=80"`
+	Description      string           `json:"description"        validate:"max=1000"`
+	PriceCents       int64            `json:"price_cents"        validate:"gte=0"`
+	Variants         []VariantRequest `json:"variants"           validate:"max=20,dive"`
+	CategoryID       string           `json:"category_id"        validate:"required"`
+	ImageURL         string           `json:"image_url"          validate:"omitempty,url"`
+	IsAvailable      *bool            `json:"is_available"`
+	ModifierGroupIDs []string         `json:"modifier_group_ids" validate:"max=10"`
 }
 
 // UpdateRequest is the body for PUT /api/v1/products/:id (admin only).
+// Variants replaces all variants; price_cents changes the price of a product
+// with a single variant.
 type UpdateRequest struct {
-	Name        *string `json:"name"         validate:"omitempty,min=2,max=80"`
-	Description *string `json:"description"  validate:"omitempty,max=1000"`
-	PriceCents  *int64  `json:"price_cents"  validate:"omitempty,gte=0"`
-	Category    *string `json:"category"     validate:"omitempty,min=1"`
-	ImageURL    *string `json:"image_url"    validate:"omitempty,url"`
-	IsAvailable *bool   `json:"is_available"`
+	Name             *string           `json:"name"               validate:"omitempty,min=2,max=80"`
+	Description      *string           `json:"description"        validate:"omitempty,max=1000"`
+	PriceCents       *int64            `json:"price_cents"        validate:"omitempty,gte=0"`
+	Variants         *[]VariantRequest `json:"variants"           validate:"omitempty,min=1,max=20,dive"`
+	CategoryID       *string           `json:"category_id"        validate:"omitempty,min=1"`
+	ImageURL         *string           `json:"image_url"          validate:"omitempty,url"`
+	IsAvailable      *bool             `json:"is_available"`
+	ModifierGroupIDs *[]string         `json:"modifier_group_ids" validate:"omitempty,max=10"`
+}
+
+// VariantRequest describes one variant of a product. SKUs are stored in
+// upper case.
+type VariantRequest struct {
+	SKU         string `json:"sku"          validate:"required,sku"`
+	Name        string `json:"name"         validate:"required,max=40"`
+	PriceCents  int64  `json:"price_cents"  validate:"gte=0"`
+	IsAvailable *bool  `json:"is_available"`
+}
+
+// QuoteRequest is the body for POST /api/v1/products/:id/price. SKU picks
+// the variant and may be left out for products with a single variant.
+type QuoteRequest struct {
+	SKU        string               `json:"sku"`
+	Quantity   int64                `json:"quantity"   validate:"gte=0,lte=99"`
+	Selections []modifier.Selection `json:"selections" validate:"max=10,dive"`
 }
 
 // ── Response DTOs ──────────────────────────────────────────────────────────────
 
 // Response is the API representation of a product.
 type Response struct {
-	ID          string    `json:"id"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	PriceCents  int64     `json:"price_cents"`
-	Category    string    `json:"category"`
-	ImageURL    string    `json:"image_url,omitempty"`
-	IsAvailable bool      `json:"is_available"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID               string    `json:"id"`
+	Name             string    `json:"name"`
+	Slug             string    `json:"slug"`
+	Description      string    `json:"description"`
+	PriceCents       int64     `json:"price_cents"` // lowest variant price
+	Variants         []Variant `json:"variants"`
+	CategoryID       string    `json:"category_id"`
+	ModifierGroupIDs []string  `json:"modifier_group_ids"`
+	ImageURL         string    `json:"image_url,omitempty"`
+	IsAvailable      bool      `json:"is_available"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// Quote is the price of a product as configured by a customer.
+type Quote struct {
+	ProductID      string                  `json:"product_id"`
+	SKU            string                  `json:"sku"`
+	VariantName    string                  `json:"variant_name"`
+	Quantity       int64                   `json:"quantity"`
+	BasePriceCents int64                   `json:"base_price_cents"` // variant price
+	Options        []modifier.PricedOption `json:"options"`
+	UnitPriceCents int64                   `json:"unit_price_cents"` // base plus option deltas, never negative
+	TotalCents     int64                   `json:"total_cents"`
 }
 
 // ListResponse is the paginated product list envelope.
@@ -51,14 +97,27 @@ type ListResponse struct {
 // ToResponse converts a Product model to its public response form.
 func (p *Product) ToResponse() Response {
 	return Response{
-		ID:          p.ID.Hex(),
-		Name:        p.Name,
-		Description: p.Description,
-		PriceCents:  p.PriceCents,
-		Category:    p.Category,
-		ImageURL:    p.ImageURL,
-		IsAvailable: p.IsAvailable,
-		CreatedAt:   p.CreatedAt,
-		UpdatedAt:   p.UpdatedAt,
+		ID:               p.ID.Hex(),
+		Name:             p.Name,
+		Slug:             p.Slug,
+		Description:      p.Description,
+		PriceCents:       p.PriceCents,
+		Variants:         p.Variants,
+		CategoryID:       p.CategoryID.Hex(),
+		ModifierGroupIDs: hexIDs(p.ModifierGroupIDs),
+		ImageURL:         p.ImageURL,
+		IsAvailable:      p.IsAvailable,
+		CreatedAt:        p.CreatedAt,
+		UpdatedAt:        p.UpdatedAt,
+	}
+}
+
+// hexIDs converts ObjectIDs to their hex form, returning an empty list for
+// none.
+func hexIDs(ids []primitive.ObjectID) []string {
+	out := make([]string, len(ids))
+	for i, id := range ids {
+		out[i] = id.Hex()
 	}
+	return out
 }
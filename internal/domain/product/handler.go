@@ -8,6 +8,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/one-backend-go/internal/domain/modifier"
 	"github.com/one-backend-go/internal/pkg/pagination"
 	"github.com/one-backend-go/internal/pkg/resp"
 	"github.com/one-backend-go/internal/pkg/validate"
@@ -60,6 +61,76 @@ func (h *Handler) List(c *gin.Context) {
 	resp.Success(c, http.StatusOK, result)
 }
 
+// Get handles GET /api/v1/products/:id.
+func (h *Handler) Get(c *gin.Context) {
+	p, err := h.svc.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, ErrProductNotFound) {
+			resp.NotFound(c, "product not found")
+			return
+		}
+		resp.InternalError(c)
+		return
+	}
+
+	resp.Success(c, http.StatusOK, p.ToResponse())
+}
+
+// GetBySlug handles GET /api/v1/products/by-slug/:slug. Slugs a product had
+// before it was renamed redirect to its current slug.
+func (h *Handler) GetBySlug(c *gin.Context) {
+	slug := c.Param("slug")
+	p, moved, err := h.svc.GetBySlug(c.Request.Context(), slug)
+	if err != nil {
+		if errors.Is(err, ErrProductNotFound) {
+			resp.NotFound(c, "product not found")
+			return
+		}
+		resp.InternalError(c)
+		return
+	}
+
+	if moved {
+		c.Redirect(http.StatusMovedPermanently, strings.TrimSuffix(c.Request.URL.Path, slug)+p.Slug)
+		return
+	}
+	resp.Success(c, http.StatusOK, p.ToResponse())
+}
+
+// Price handles POST /api/v1/products/:id/price. It checks a customer's
+// choice of variant and modifier options and returns what it costs.
+func (h *Handler) Price(c *gin.Context) {
+	var req QuoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.Fail(c, http.StatusBadRequest, "BAD_REQUEST", "invalid JSON body", nil)
+		return
+	}
+
+	if errs := h.validate.Struct(req); errs != nil {
+		resp.ValidationError(c, errs)
+		return
+	}
+
+	quote, err := h.svc.Quote(c.Request.Context(), c.Param("id"), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrProductNotFound):
+			resp.NotFound(c, "product not found")
+		case errors.Is(err, ErrUnknownVariant):
+			resp.ValidationError(c, map[string]string{"sku": err.Error()})
+		case errors.Is(err, modifier.ErrInvalidSelection):
+			resp.ValidationError(c, map[string]string{"selections": err.Error()})
+		case errors.Is(err, ErrUnavailable):
+			resp.Conflict(c, err.Error())
+		default:
+			resp.InternalError(c)
+		}
+		return
+	}
+
+	resp.Success(c, http.StatusOK, quote)
+}
+
 // Create handles POST /api/v1/products (admin only).
 func (h *Handler) Create(c *gin.Context) {
 	var req CreateRequest
@@ -75,6 +146,30 @@ func (h *Handler) Create(c *gin.Context) {
 
 	p, err := h.svc.Create(c.Request.Context(), req)
 	if err != nil {
+		if errors.Is(err, ErrSlugExists) {
+			resp.Conflict(c, "another product took this slug, try again")
+			return
+		}
+		if errors.Is(err, ErrUnknownCategory) {
+			resp.ValidationError(c, map[string]string{"category_id": err.Error()})
+			return
+		}
+		if errors.Is(err, ErrSKUExists) {
+			resp.Conflict(c, err.Error())
+			return
+		}
+		if errors.Is(err, ErrDuplicateVariant) {
+			resp.ValidationError(c, map[string]string{"variants": err.Error()})
+			return
+		}
+		if errors.Is(err, ErrPriceOnVariants) {
+			resp.ValidationError(c, map[string]string{"price_cents": err.Error()})
+			return
+		}
+		if errors.Is(err, ErrUnknownModifierGroup) {
+			resp.ValidationError(c, map[string]string{"modifier_group_ids": err.Error()})
+			return
+		}
 		resp.InternalError(c)
 		return
 	}
@@ -103,6 +198,38 @@ func (h *Handler) Update(c *gin.Context) {
 			resp.NotFound(c, "product not found")
 			return
 		}
+		if errors.Is(err, ErrSlugExists) {
+			resp.Conflict(c, "another product took this slug, try again")
+			return
+		}
+		if errors.Is(err, ErrUnknownCategory) {
+			resp.ValidationError(c, map[string]string{"category_id": err.Error()})
+			return
+		}
+		if errors.Is(err, ErrSKUExists) {
+			resp.Conflict(c, err.Error())
+			return
+		}
+		if errors.Is(err, ErrDuplicateVariant) {
+			resp.ValidationError(c, map[string]string{"variants": err.Error()})
+			return
+		}
+		if errors.Is(err, ErrVariantStocked) {
+			resp.Conflict(c, err.Error())
+			return
+		}
+		if errors.Is(err, ErrProductChanged) {
+			resp.Conflict(c, err.Error())
+			return
+		}
+		if errors.Is(err, ErrPriceOnVariants) {
+			resp.ValidationError(c, map[string]string{"price_cents": err.Error()})
+			return
+		}
+		if errors.Is(err, ErrUnknownModifierGroup) {
+			resp.ValidationError(c, map[string]string{"modifier_group_ids": err.Error()})
+			return
+		}
 		resp.InternalError(c)
 		return
 	}
@@ -9,13 +9,52 @@ import (
 
 // Product represents a food item in the catalog.
 type Product struct {
-	ID          primitive.ObjectID `bson:"_id,omitempty"  json:"id"`
-	Name        string             `bson:"name"           json:"name"`
-	Description string             `bson:"description"    json:"description"`
-	PriceCents  int64              `bson:"price_cents"    json:"price_cents"`
-	Category    string             `bson:"category"       json:"category"`
-	ImageURL    string             `bson:"image_url"      json:"image_url,omitempty"`
-	IsAvailable bool               `bson:"is_available"   json:"is_available"`
-	CreatedAt   time.Time          `bson:"created_at"     json:"created_at"`
-	UpdatedAt   time.Time          `bson:"updated_at"     json:"updated_at"`
+	ID               primitive.ObjectID   `bson:"_id,omitempty"                json:"id"`
+	Name             string               `bson:"name"                         json:"name"`
+	Slug             string               `bson:"slug"                         json:"slug"`
+	OldSlugs         []string             `bson:"old_slugs,omitempty"          json:"-"` // earlier slugs, redirected to Slug
+	Description      string               `bson:"description"                  json:"description"`
+	PriceCents       int64                `bson:"price_cents"                  json:"price_cents"` // lowest variant price
+	Variants         []Variant            `bson:"variants"                     json:"variants"`
+	CategoryID       primitive.ObjectID   `bson:"category_id"                  json:"category_id"`
+	ModifierGroupIDs []primitive.ObjectID `bson:"modifier_group_ids,omitempty" json:"modifier_group_ids,omitempty"` // in display order
+	ImageURL         string               `bson:"image_url"                    json:"image_url,omitempty"`
+	IsAvailable      bool                 `bson:"is_available"                 json:"is_available"`
+	StockDisabled    bool                 `bson:"stock_disabled,omitempty"     json:"-"` // IsAvailable turned off because every variant ran out
+	CreatedAt        time.Time            `bson:"created_at"                   json:"created_at"`
+	UpdatedAt        time.Time            `bson:"updated_at"                   json:"updated_at"`
 }
+
+// Variant is a size or other version of a product with its own price, such
+// as a large pizza or a 500ml drink. Products sold in one size have a single
+// variant.
+type Variant struct {
+	SKU           string `bson:"sku"                      json:"sku"` // unique across the catalog, upper case
+	Name          string `bson:"name"                     json:"name"`
+	PriceCents    int64  `bson:"price_cents"              json:"price_cents"`
+	IsAvailable   bool   `bson:"is_available"             json:"is_available"`
+	StockDisabled bool   `bson:"stock_disabled,omitempty" json:"-"` // IsAvailable turned off because the variant ran out
+}
+
+// defaultVariant returns the single variant of a product sold in one size.
+// Its SKU is normally the product's slug in upper case.
+func defaultVariant(sku string, priceCents int64) Variant {
+	return Variant{SKU: sku, Name: "Regular", PriceCents: priceCents, IsAvailable: true}
+}
+
+// minPrice returns the lowest price among variants, which is stored as the
+// product's price so that listings can sort by it.
+func minPrice(variants []Variant) int64 {
+	if len(variants) == 0 {
+		return 0
+	}
+	lowest := variants[0].PriceCents
+	for _, v := range variants[1:] {
+		lowest = min(lowest, v.PriceCents)
+	}
+	return lowest
+}
+
+// fallbackSlug is used for products whose name has no letters or digits that
+// can be spelled in ASCII.
+const fallbackSlug = "product"
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -27,7 +28,11 @@ func NewRepository(db *mongo.Database) *Repository {
 // ListFilter holds optional filters for the product listing.
 type ListFilter struct {
 	Query    string // text search
-	Category string // exact match
+	Category string // category ID or slug, resolved by the service
+
+	// CategoryIDs restricts the listing to the category named by Category
+	// and its subcategories.
+	CategoryIDs []primitive.ObjectID
 }
 
 // List returns a paginated, filtered, and sorted list of products.
@@ -39,8 +44,8 @@ func (r *Repository) List(ctx context.Context, filter ListFilter, p pagination.P
 	if filter.Query != "" {
 		f["$text"] = bson.M{"$search": filter.Query}
 	}
-	if filter.Category != "" {
-		f["category"] = filter.Category
+	if filter.CategoryIDs != nil {
+		f["category_id"] = bson.M{"$in": filter.CategoryIDs}
 	}
 
 	total, err := r.col.CountDocuments(ctx, f)
@@ -92,6 +97,9 @@ func (r *Repository) Create(ctx context.Context, p *Product) error {
 
 	_, err := r.col.InsertOne(ctx, p)
 	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return duplicateKey(err)
+		}
 		return fmt.Errorf("product repo create: %w", err)
 	}
 	return nil
@@ -113,6 +121,73 @@ func (r *Repository) FindByID(ctx context.Context, id primitive.ObjectID) (*Prod
 	return &p, nil
 }
 
+// FindBySlug retrieves the product with the given current slug.
+func (r *Repository) FindBySlug(ctx context.Context, slug string) (*Product, error) {
+	return r.findOne(ctx, bson.M{"slug": slug}, "findBySlug")
+}
+
+// FindByOldSlug retrieves the product that most recently gave up the given
+// slug.
+func (r *Repository) FindByOldSlug(ctx context.Context, slug string) (*Product, error) {
+	return r.findOne(ctx, bson.M{"old_slugs": slug}, "findByOldSlug")
+}
+
+func (r *Repository) findOne(ctx context.Context, filter bson.M, op string) (*Product, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	opts := options.FindOne().SetSort(bson.D{{Key: "updated_at", Value: -1}})
+	var p Product
+	err := r.col.FindOne(ctx, filter, opts).Decode(&p)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("product repo %s: %w", op, err)
+	}
+	return &p, nil
+}
+
+// SlugTaken reports whether a product other than except currently uses slug.
+func (r *Repository) SlugTaken(ctx context.Context, slug string, except primitive.ObjectID) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	n, err := r.col.CountDocuments(ctx, bson.M{"slug": slug, "_id": bson.M{"$ne": except}}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, fmt.Errorf("product repo slugTaken: %w", err)
+	}
+	return n > 0, nil
+}
+
+// SKUTaken reports whether any product has a variant with the given SKU.
+func (r *Repository) SKUTaken(ctx context.Context, sku string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	n, err := r.col.CountDocuments(ctx, bson.M{"variants.sku": sku}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, fmt.Errorf("product repo skuTaken: %w", err)
+	}
+	return n > 0, nil
+}
+
+// ReleaseOldSlug removes slug from the old slugs of every product except
+// owner, once owner has taken it as its current slug.
+func (r *Repository) ReleaseOldSlug(ctx context.Context, slug string, owner primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := r.col.UpdateMany(ctx,
+		bson.M{"old_slugs": slug, "_id": bson.M{"$ne": owner}},
+		bson.M{"$pull": bson.M{"old_slugs": slug}},
+	)
+	if err != nil {
+		return fmt.Errorf("product repo releaseOldSlug: %w", err)
+	}
+	return nil
+}
+
 // Update modifies an existing product document.
 func (r *Repository) Update(ctx context.Context, id primitive.ObjectID, update bson.M) (*Product, error) {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
@@ -126,6 +201,37 @@ func (r *Repository) Update(ctx context.Context, id primitive.ObjectID, update b
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return nil, nil
 		}
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, duplicateKey(err)
+		}
+		return nil, fmt.Errorf("product repo update: %w", err)
+	}
+	return &p, nil
+}
+
+// UpdateIfUnchanged applies update like Update, but only if the product was
+// last updated at since, i.e. has not changed since it was read. It returns
+// nil if the product changed or does not exist.
+func (r *Repository) UpdateIfUnchanged(ctx context.Context, id primitive.ObjectID, since time.Time, update bson.M) (*Product, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": id, "updated_at": since}
+	if since.IsZero() {
+		// Products written before updated_at was kept have none.
+		filter["updated_at"] = bson.M{"$in": bson.A{nil, since}}
+	}
+	update["updated_at"] = time.Now().UTC()
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var p Product
+	err := r.col.FindOneAndUpdate(ctx, filter, bson.M{"$set": update}, opts).Decode(&p)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, duplicateKey(err)
+		}
 		return nil, fmt.Errorf("product repo update: %w", err)
 	}
 	return &p, nil
@@ -143,7 +249,55 @@ func (r *Repository) Delete(ctx context.Context, id primitive.ObjectID) (bool, e
 	return res.DeletedCount > 0, nil
 }
 
-// InsertMany bulk-inserts products (used for seeding).
+// SetStockAvailability takes a variant off sale when it runs out of stock,
+// or puts it back when it is restocked. Only variants that stock took off
+// sale are put back, so a variant an admin disabled stays disabled. The
+// product follows: it is taken off sale when its last available variant
+// runs out, and put back when stock makes a variant available again.
+//
+// Each step is a conditional update on the variants as they are now, so
+// concurrent calls cannot leave the product disagreeing with its variants.
+func (r *Repository) SetStockAvailability(ctx context.Context, id primitive.ObjectID, sku string, inStock bool) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	now := time.Now().UTC()
+	var variant, product bson.M
+	var variantUpdate, productUpdate bson.M
+	if inStock {
+		variant = bson.M{"_id": id, "variants": bson.M{"$elemMatch": bson.M{"sku": sku, "stock_disabled": true}}}
+		variantUpdate = bson.M{
+			"$set":   bson.M{"variants.$.is_available": true, "updated_at": now},
+			"$unset": bson.M{"variants.$.stock_disabled": ""},
+		}
+		product = bson.M{"_id": id, "stock_disabled": true, "variants.is_available": true}
+		productUpdate = bson.M{
+			"$set":   bson.M{"is_available": true, "updated_at": now},
+			"$unset": bson.M{"stock_disabled": ""},
+		}
+	} else {
+		variant = bson.M{"_id": id, "variants": bson.M{"$elemMatch": bson.M{"sku": sku, "is_available": true}}}
+		variantUpdate = bson.M{"$set": bson.M{
+			"variants.$.is_available":   false,
+			"variants.$.stock_disabled": true,
+			"updated_at":                now,
+		}}
+		product = bson.M{"_id": id, "is_available": true, "variants.is_available": bson.M{"$ne": true}}
+		productUpdate = bson.M{"$set": bson.M{"is_available": false, "stock_disabled": true, "updated_at": now}}
+	}
+
+	if _, err := r.col.UpdateOne(ctx, variant, variantUpdate); err != nil {
+		return fmt.Errorf("product repo setStockAvailability: %w", err)
+	}
+	if _, err := r.col.UpdateOne(ctx, product, productUpdate); err != nil {
+		return fmt.Errorf("product repo setStockAvailability: %w", err)
+	}
+	return nil
+}
+
+// InsertMany bulk-inserts products (used for seeding). Products without a
+// slug get one made from their name, which must then be unique, and products
+// without variants get a single variant at their price.
 func (r *Repository) InsertMany(ctx context.Context, products []Product) error {
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
@@ -152,6 +306,13 @@ func (r *Repository) InsertMany(ctx context.Context, products []Product) error {
 	now := time.Now().UTC()
 	for i := range products {
 		products[i].ID = primitive.NewObjectID()
+		if products[i].Slug == "" {
+			products[i].Slug = BaseSlug(products[i].Name)
+		}
+		if len(products[i].Variants) == 0 {
+			products[i].Variants = []Variant{defaultVariant(strings.ToUpper(products[i].Slug), products[i].PriceCents)}
+		}
+		products[i].PriceCents = minPrice(products[i].Variants)
 		products[i].CreatedAt = now
 		products[i].UpdatedAt = now
 		docs[i] = products[i]
@@ -163,3 +324,88 @@ func (r *Repository) InsertMany(ctx context.Context, products []Product) error {
 	}
 	return nil
 }
+
+// CountByCategory returns the number of products in each category that has
+// any.
+func (r *Repository) CountByCategory(ctx context.Context) (map[primitive.ObjectID]int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	cursor, err := r.col.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{"_id": "$category_id", "count": bson.M{"$sum": 1}}}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("product repo countByCategory: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		ID    primitive.ObjectID `bson:"_id"`
+		Count int64              `bson:"count"`
+	}
+	if err = cursor.All(ctx, &rows); err != nil {
+		return nil, fmt.Errorf("product repo decode: %w", err)
+	}
+
+	counts := make(map[primitive.ObjectID]int64, len(rows))
+	for _, row := range rows {
+		counts[row.ID] = row.Count
+	}
+	return counts, nil
+}
+
+// HasProductsInCategory reports whether any product belongs directly to the
+// given category.
+func (r *Repository) HasProductsInCategory(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	n, err := r.col.CountDocuments(ctx, bson.M{"category_id": id}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, fmt.Errorf("product repo hasProductsInCategory: %w", err)
+	}
+	return n > 0, nil
+}
+
+// HasProductsWithModifierGroup reports whether any product uses the given
+// modifier group.
+func (r *Repository) HasProductsWithModifierGroup(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	n, err := r.col.CountDocuments(ctx, bson.M{"modifier_group_ids": id}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, fmt.Errorf("product repo hasProductsWithModifierGroup: %w", err)
+	}
+	return n > 0, nil
+}
+
+// duplicateKey returns the error for a duplicate key on the slug or SKU
+// index, telling them apart by the key pattern the server reports with the
+// error.
+func duplicateKey(err error) error {
+	var raws []bson.Raw
+	var we mongo.WriteException
+	var ce mongo.CommandError
+	switch {
+	case errors.As(err, &we):
+		for _, e := range we.WriteErrors {
+			raws = append(raws, e.Raw)
+		}
+	case errors.As(err, &ce):
+		raws = append(raws, ce.Raw)
+	}
+
+	for _, raw := range raws {
+		if _, lerr := raw.LookupErr("keyPattern", "variants.sku"); lerr == nil {
+			return ErrSKUExists
+		}
+	}
+	return ErrSlugExists
+}
+
+// ErrSlugExists indicates another product already uses the slug.
+var ErrSlugExists = fmt.Errorf("slug already exists")
+
+// ErrSKUExists indicates another product already has a variant with the SKU.
+var ErrSKUExists = fmt.Errorf("sku already used by another product")
@@ -2,28 +2,76 @@ package product
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"strings"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
+	"github.com/one-backend-go/internal/domain/modifier"
 	"github.com/one-backend-go/internal/pkg/pagination"
+	"github.com/one-backend-go/internal/pkg/slug"
 )
 
+// slugAttempts bounds how many numbered variants of a slug or SKU are tried
+// before falling back to one made unique by an ObjectID.
+const slugAttempts = 20
+
+// updateAttempts bounds how often an update computed from a product that
+// changed before it was written is recomputed.
+const updateAttempts = 3
+
+// Categories looks up the categories products belong to. It is implemented
+// by the category service.
+type Categories interface {
+	Exists(ctx context.Context, id primitive.ObjectID) (bool, error)
+	Resolve(ctx context.Context, ref string) ([]primitive.ObjectID, error)
+}
+
+// ModifierGroups looks up the modifier groups attached to products. It is
+// implemented by the modifier service.
+type ModifierGroups interface {
+	Find(ctx context.Context, ids []primitive.ObjectID) ([]modifier.Group, error)
+}
+
+// Stock looks up and removes the tracked stock of product variants. It is
+// implemented by the inventory repository.
+type Stock interface {
+	Held(ctx context.Context, productID primitive.ObjectID, skus []string) ([]string, error)
+	Forget(ctx context.Context, productID primitive.ObjectID, skus []string) error
+}
+
 // Service contains business logic for products.
 type Service struct {
-	repo *Repository
+	repo       *Repository
+	categories Categories
+	modifiers  ModifierGroups
+	stock      Stock
 }
 
 // NewService creates a new product Service.
-func NewService(repo *Repository) *Service {
-	return &Service{repo: repo}
+func NewService(repo *Repository, categories Categories, modifiers ModifierGroups, stock Stock) *Service {
+	return &Service{repo: repo, categories: categories, modifiers: modifiers, stock: stock}
 }
 
-// List returns a paginated, filtered product listing.
+// List returns a paginated, filtered product listing. Filtering by a
+// category includes the products of its subcategories.
 func (s *Service) List(ctx context.Context, filter ListFilter, p pagination.Params) (*ListResponse, error) {
 	p.Clamp()
 
+	if filter.Category != "" {
+		ids, err := s.categories.Resolve(ctx, filter.Category)
+		if err != nil {
+			return nil, fmt.Errorf("product service list: %w", err)
+		}
+		if ids == nil {
+			ids = []primitive.ObjectID{} // unknown category: nothing matches
+		}
+		filter.CategoryIDs = ids
+	}
+
 	products, total, err := s.repo.List(ctx, filter, p)
 	if err != nil {
 		return nil, fmt.Errorf("product service list: %w", err)
@@ -43,67 +91,538 @@ func (s *Service) List(ctx context.Context, filter ListFilter, p pagination.Para
 	}, nil
 }
 
-// Create adds a new product to the catalog.
+// Get returns a single product.
+func (s *Service) Get(ctx context.Context, idHex string) (*Product, error) {
+	id, err := primitive.ObjectIDFromHex(idHex)
+	if err != nil {
+		return nil, ErrProductNotFound
+	}
+
+	p, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("product service get: %w", err)
+	}
+	if p == nil {
+		return nil, ErrProductNotFound
+	}
+	return p, nil
+}
+
+// GetBySlug returns the product with the given slug. If the slug is one the
+// product had before it was renamed, moved reports that clients should use
+// the product's current slug instead.
+func (s *Service) GetBySlug(ctx context.Context, slug string) (p *Product, moved bool, err error) {
+	p, err = s.repo.FindBySlug(ctx, slug)
+	if err != nil {
+		return nil, false, fmt.Errorf("product service get by slug: %w", err)
+	}
+	if p != nil {
+		return p, false, nil
+	}
+
+	p, err = s.repo.FindByOldSlug(ctx, slug)
+	if err != nil {
+		return nil, false, fmt.Errorf("product service get by slug: %w", err)
+	}
+	if p == nil {
+		return nil, false, ErrProductNotFound
+	}
+	return p, true, nil
+}
+
+// Create adds a new product to the catalog under a slug made from its name.
 func (s *Service) Create(ctx context.Context, req CreateRequest) (*Product, error) {
 	available := true
 	if req.IsAvailable != nil {
 		available = *req.IsAvailable
 	}
 
-	p := &Product{
-		Name:        req.Name,
-		Description: req.Description,
-		PriceCents:  req.PriceCents,
-		Category:    req.Category,
-		ImageURL:    req.ImageURL,
-		IsAvailable: available,
+	categoryID, err := s.category(ctx, req.CategoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	var variants []Variant
+	if len(req.Variants) > 0 {
+		if req.PriceCents != 0 {
+			return nil, ErrPriceOnVariants
+		}
+		if variants, err = buildVariants(req.Variants); err != nil {
+			return nil, err
+		}
 	}
 
-	if err := s.repo.Create(ctx, p); err != nil {
+	groupIDs, err := s.modifierGroups(ctx, req.ModifierGroupIDs)
+	if err != nil {
 		return nil, err
 	}
+
+	p := &Product{
+		Name:             req.Name,
+		Description:      req.Description,
+		Variants:         variants,
+		CategoryID:       categoryID,
+		ModifierGroupIDs: groupIDs,
+		ImageURL:         req.ImageURL,
+		IsAvailable:      available,
+	}
+
+	// Another product may take the chosen slug or default SKU before the
+	// insert; the unique indexes catch that, and the next attempt picks
+	// another.
+	for attempt := 0; ; attempt++ {
+		if p.Slug, err = s.freeSlug(ctx, req.Name, primitive.NilObjectID); err != nil {
+			return nil, err
+		}
+		if variants == nil {
+			sku, err := s.freeSKU(ctx, strings.ToUpper(p.Slug))
+			if err != nil {
+				return nil, err
+			}
+			p.Variants = []Variant{defaultVariant(sku, req.PriceCents)}
+		}
+		p.PriceCents = minPrice(p.Variants)
+		err = s.repo.Create(ctx, p)
+		if err == nil {
+			break
+		}
+		retry := errors.Is(err, ErrSlugExists) || (variants == nil && errors.Is(err, ErrSKUExists))
+		if !retry || attempt == 2 {
+			return nil, err
+		}
+	}
+
+	if err := s.repo.ReleaseOldSlug(ctx, p.Slug, p.ID); err != nil {
+		slog.Error("failed to release old slug", "slug", p.Slug, "error", err)
+	}
 	return p, nil
 }
 
-// Update modifies an existing product.
+// Update modifies an existing product. Changes computed from the stored
+// product, such as variants carrying over their stock state, are written only
+// if the product has not changed since it was read, and recomputed if it has.
 func (s *Service) Update(ctx context.Context, idHex string, req UpdateRequest) (*Product, error) {
 	id, err := primitive.ObjectIDFromHex(idHex)
 	if err != nil {
 		return nil, fmt.Errorf("invalid product id")
 	}
 
+	if req.Variants != nil && req.PriceCents != nil {
+		return nil, ErrPriceOnVariants
+	}
+
+	for attempt := 0; attempt < updateAttempts; attempt++ {
+		p, removed, err := s.update(ctx, id, req)
+		if errors.Is(err, errStaleProduct) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if len(removed) > 0 {
+			if err = s.stock.Forget(ctx, p.ID, removed); err != nil {
+				slog.Error("failed to remove stock of deleted variants", "product", p.ID.Hex(), "skus", removed, "error", err)
+			}
+		}
+		return p, nil
+	}
+	return nil, ErrProductChanged
+}
+
+// update applies req once. It returns the SKUs of the variants the update
+// dropped, and errStaleProduct if the product changed after it was read.
+func (s *Service) update(ctx context.Context, id primitive.ObjectID, req UpdateRequest) (*Product, []string, error) {
+	// Renames, price and variant changes depend on the stored product.
+	var (
+		current *Product
+		err     error
+	)
+	if req.Name != nil || req.PriceCents != nil || req.Variants != nil {
+		if current, err = s.repo.FindByID(ctx, id); err != nil {
+			return nil, nil, fmt.Errorf("product service update: %w", err)
+		}
+		if current == nil {
+			return nil, nil, ErrProductNotFound
+		}
+	}
+
 	update := bson.M{}
+	var removed []string
 	if req.Name != nil {
 		update["name"] = *req.Name
+		if err = s.renameSlug(ctx, current, *req.Name, update); err != nil {
+			return nil, nil, err
+		}
 	}
 	if req.Description != nil {
 		update["description"] = *req.Description
 	}
+	if req.Variants != nil {
+		variants, err := buildVariants(*req.Variants)
+		if err != nil {
+			return nil, nil, err
+		}
+		if removed, err = s.removableVariants(ctx, current, variants); err != nil {
+			return nil, nil, err
+		}
+		keepStockState(current, variants, update, req.IsAvailable == nil)
+		update["variants"] = variants
+		update["price_cents"] = minPrice(variants)
+	}
 	if req.PriceCents != nil {
+		if len(current.Variants) > 1 {
+			return nil, nil, ErrPriceOnVariants
+		}
+		variant := defaultVariant(strings.ToUpper(current.Slug), *req.PriceCents)
+		if len(current.Variants) == 1 {
+			variant = current.Variants[0]
+			variant.PriceCents = *req.PriceCents
+		}
+		update["variants"] = []Variant{variant}
 		update["price_cents"] = *req.PriceCents
 	}
-	if req.Category != nil {
-		update["category"] = *req.Category
+	if req.CategoryID != nil {
+		categoryID, err := s.category(ctx, *req.CategoryID)
+		if err != nil {
+			return nil, nil, err
+		}
+		update["category_id"] = categoryID
+	}
+	if req.ModifierGroupIDs != nil {
+		groupIDs, err := s.modifierGroups(ctx, *req.ModifierGroupIDs)
+		if err != nil {
+			return nil, nil, err
+		}
+		update["modifier_group_ids"] = groupIDs
 	}
 	if req.ImageURL != nil {
 		update["image_url"] = *req.ImageURL
 	}
 	if req.IsAvailable != nil {
+		// An admin's choice overrides stock: a product they take off sale
+		// stays off when it is restocked.
 		update["is_available"] = *req.IsAvailable
+		update["stock_disabled"] = false
 	}
 
 	if len(update) == 0 {
-		return nil, fmt.Errorf("no fields to update")
+		return nil, nil, fmt.Errorf("no fields to update")
 	}
 
-	p, err := s.repo.Update(ctx, id, update)
+	var p *Product
+	if current != nil {
+		p, err = s.repo.UpdateIfUnchanged(ctx, id, current.UpdatedAt, update)
+	} else {
+		p, err = s.repo.Update(ctx, id, update)
+	}
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	if p == nil && current != nil {
+		return nil, nil, errStaleProduct
 	}
 	if p == nil {
-		return nil, ErrProductNotFound
+		return nil, nil, ErrProductNotFound
 	}
-	return p, nil
+
+	if _, renamed := update["slug"]; renamed {
+		slog.Info("product slug changed", "id", p.ID.Hex(), "slug", p.Slug)
+		if err = s.repo.ReleaseOldSlug(ctx, p.Slug, p.ID); err != nil {
+			slog.Error("failed to release old slug", "slug", p.Slug, "error", err)
+		}
+	}
+	return p, removed, nil
+}
+
+// buildVariants converts variant requests to variants. SKUs are stored in
+// upper case, and neither SKUs nor names may repeat within a product.
+func buildVariants(reqs []VariantRequest) ([]Variant, error) {
+	variants := make([]Variant, 0, len(reqs))
+	seen := make(map[string]bool, 2*len(reqs))
+	for _, r := range reqs {
+		v := Variant{
+			SKU:         strings.ToUpper(r.SKU),
+			Name:        strings.TrimSpace(r.Name),
+			PriceCents:  r.PriceCents,
+			IsAvailable: true,
+		}
+		if r.IsAvailable != nil {
+			v.IsAvailable = *r.IsAvailable
+		}
+		nameKey := "name:" + strings.ToLower(v.Name)
+		if v.Name == "" || seen["sku:"+v.SKU] || seen[nameKey] {
+			return nil, ErrDuplicateVariant
+		}
+		seen["sku:"+v.SKU] = true
+		seen[nameKey] = true
+		variants = append(variants, v)
+	}
+	return variants, nil
+}
+
+// removableVariants returns the SKUs of current that variants drops. A
+// variant still holding stock on hand or reserved cannot be dropped.
+func (s *Service) removableVariants(ctx context.Context, current *Product, variants []Variant) ([]string, error) {
+	kept := make(map[string]bool, len(variants))
+	for _, v := range variants {
+		kept[v.SKU] = true
+	}
+	var removed []string
+	for _, v := range current.Variants {
+		if !kept[v.SKU] {
+			removed = append(removed, v.SKU)
+		}
+	}
+	if len(removed) == 0 {
+		return nil, nil
+	}
+
+	held, err := s.stock.Held(ctx, current.ID, removed)
+	if err != nil {
+		return nil, fmt.Errorf("product service update: %w", err)
+	}
+	if len(held) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrVariantStocked, strings.Join(held, ", "))
+	}
+	return removed, nil
+}
+
+// keepStockState carries over the stock state of current to the variants
+// replacing its own: a variant that stock took off sale stays off until it
+// is restocked. Unless the admin sets the product's availability, the
+// product follows its variants the way a stock change would.
+func keepStockState(current *Product, variants []Variant, update bson.M, followStock bool) {
+	disabled := make(map[string]bool, len(current.Variants))
+	for _, v := range current.Variants {
+		disabled[v.SKU] = v.StockDisabled
+	}
+
+	anyAvailable, anyDisabled := false, false
+	for i := range variants {
+		if disabled[variants[i].SKU] && variants[i].IsAvailable {
+			variants[i].IsAvailable = false
+			variants[i].StockDisabled = true
+		}
+		anyAvailable = anyAvailable || variants[i].IsAvailable
+		anyDisabled = anyDisabled || variants[i].StockDisabled
+	}
+	if !followStock {
+		return
+	}
+
+	switch {
+	case current.StockDisabled && anyAvailable:
+		update["is_available"] = true
+		update["stock_disabled"] = false
+	case current.IsAvailable && !anyAvailable && anyDisabled:
+		update["is_available"] = false
+		update["stock_disabled"] = true
+	}
+}
+
+// category parses and checks the category ID of a product.
+func (s *Service) category(ctx context.Context, idHex string) (primitive.ObjectID, error) {
+	id, err := primitive.ObjectIDFromHex(idHex)
+	if err != nil {
+		return primitive.NilObjectID, ErrUnknownCategory
+	}
+	exists, err := s.categories.Exists(ctx, id)
+	if err != nil {
+		return primitive.NilObjectID, fmt.Errorf("product service category: %w", err)
+	}
+	if !exists {
+		return primitive.NilObjectID, ErrUnknownCategory
+	}
+	return id, nil
+}
+
+// modifierGroups parses and checks the modifier group IDs of a product,
+// keeping their order and dropping repeats.
+func (s *Service) modifierGroups(ctx context.Context, hexes []string) ([]primitive.ObjectID, error) {
+	ids := make([]primitive.ObjectID, 0, len(hexes))
+	seen := make(map[primitive.ObjectID]bool, len(hexes))
+	for _, h := range hexes {
+		id, err := primitive.ObjectIDFromHex(h)
+		if err != nil {
+			return nil, ErrUnknownModifierGroup
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	groups, err := s.modifiers.Find(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("product service modifier groups: %w", err)
+	}
+	if len(groups) != len(ids) {
+		return nil, ErrUnknownModifierGroup
+	}
+	return ids, nil
+}
+
+// Quote prices a product as configured by a customer: the chosen variant,
+// plus the price deltas of the chosen modifier options, times the quantity.
+// The selection must satisfy the rules of every modifier group on the
+// product.
+func (s *Service) Quote(ctx context.Context, idHex string, req QuoteRequest) (*Quote, error) {
+	p, err := s.Get(ctx, idHex)
+	if err != nil {
+		return nil, err
+	}
+	if !p.IsAvailable {
+		return nil, ErrUnavailable
+	}
+
+	variant, err := pickVariant(p.Variants, req.SKU)
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := s.modifiers.Find(ctx, p.ModifierGroupIDs)
+	if err != nil {
+		return nil, fmt.Errorf("product service quote: %w", err)
+	}
+	options, delta, err := modifier.Price(groups, req.Selections)
+	if err != nil {
+		return nil, err
+	}
+
+	quantity := max(req.Quantity, 1)
+	unit := max(variant.PriceCents+delta, 0)
+	if options == nil {
+		options = []modifier.PricedOption{}
+	}
+	return &Quote{
+		ProductID:      p.ID.Hex(),
+		SKU:            variant.SKU,
+		VariantName:    variant.Name,
+		Quantity:       quantity,
+		BasePriceCents: variant.PriceCents,
+		Options:        options,
+		UnitPriceCents: unit,
+		TotalCents:     unit * quantity,
+	}, nil
+}
+
+// pickVariant returns the available variant with the given SKU, or the only
+// variant if sku is empty.
+func pickVariant(variants []Variant, sku string) (*Variant, error) {
+	var v *Variant
+	switch {
+	case sku != "":
+		for i := range variants {
+			if variants[i].SKU == strings.ToUpper(sku) {
+				v = &variants[i]
+				break
+			}
+		}
+		if v == nil {
+			return nil, ErrUnknownVariant
+		}
+	case len(variants) == 1:
+		v = &variants[0]
+	default:
+		return nil, ErrUnknownVariant
+	}
+	if !v.IsAvailable {
+		return nil, ErrUnavailable
+	}
+	return v, nil
+}
+
+// renameSlug adds the slug changes for renaming the current product to
+// update. The slug is kept if the new name still produces it, as when only
+// the case changes; otherwise the product gets a new slug and the current
+// one is kept so that it redirects.
+func (s *Service) renameSlug(ctx context.Context, current *Product, name string, update bson.M) error {
+	if hasBase(current.Slug, BaseSlug(name)) {
+		return nil
+	}
+
+	next, err := s.freeSlug(ctx, name, current.ID)
+	if err != nil {
+		return err
+	}
+	old := make([]string, 0, len(current.OldSlugs)+1)
+	for _, o := range current.OldSlugs {
+		if o != next && o != current.Slug {
+			old = append(old, o)
+		}
+	}
+	if current.Slug != "" {
+		old = append(old, current.Slug)
+	}
+	update["slug"] = next
+	update["old_slugs"] = old
+	return nil
+}
+
+// freeSlug returns the first slug for name that no product other than self
+// uses: the plain slug, then numbered variants.
+func (s *Service) freeSlug(ctx context.Context, name string, self primitive.ObjectID) (string, error) {
+	return firstFree(BaseSlug(name), func(candidate string) (bool, error) {
+		return s.repo.SlugTaken(ctx, candidate, self)
+	})
+}
+
+// freeSKU returns base, or a numbered variant of it, that no product uses as
+// a SKU. Default SKUs come from slugs, and a slug given up by a renamed
+// product can be taken by a new one while the old SKU stays.
+func (s *Service) freeSKU(ctx context.Context, base string) (string, error) {
+	return firstFree(base, func(candidate string) (bool, error) {
+		return s.repo.SKUTaken(ctx, candidate)
+	})
+}
+
+// firstFree returns base or the first numbered variant of it that taken
+// reports as free.
+func firstFree(base string, taken func(string) (bool, error)) (string, error) {
+	for n := 1; n <= slugAttempts; n++ {
+		candidate := base
+		if n > 1 {
+			candidate = slug.WithSuffix(base, n)
+		}
+		used, err := taken(candidate)
+		if err != nil {
+			return "", fmt.Errorf("product service free name: %w", err)
+		}
+		if !used {
+			return candidate, nil
+		}
+	}
+	// Give up on short suffixes and use the counter from a new ObjectID,
+	// which no other product in this process can have drawn.
+	oid := primitive.NewObjectID()
+	return slug.WithSuffix(base, int(oid[9])<<16|int(oid[10])<<8|int(oid[11])), nil
+}
+
+// BaseSlug returns the slug for a product name before deduplication, or
+// "product" for names with nothing to slugify.
+func BaseSlug(name string) string {
+	if b := slug.Make(name); b != "" {
+		return b
+	}
+	return fallbackSlug
+}
+
+// hasBase reports whether s is base or a numbered variant of it.
+func hasBase(s, base string) bool {
+	if s == base {
+		return true
+	}
+	rest, ok := strings.CutPrefix(s, base+"-")
+	if !ok || rest == "" {
+		return false
+	}
+	for _, r := range rest {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
 }
 
 // Delete removes a product from the catalog.
@@ -120,8 +639,47 @@ func (s *Service) Delete(ctx context.Context, idHex string) error {
 	if !deleted {
 		return ErrProductNotFound
 	}
+
+	if err = s.stock.Forget(ctx, id, nil); err != nil {
+		slog.Error("failed to remove stock of deleted product", "product", idHex, "error", err)
+	}
 	return nil
 }
 
 // ErrProductNotFound indicates the product does not exist.
 var ErrProductNotFound = fmt.Errorf("product not found")
+
+// ErrProductChanged indicates the product kept changing, through stock
+// changes for instance, while an update was being applied.
+var ErrProductChanged = fmt.Errorf("product changed while updating, try again")
+
+// errStaleProduct indicates the product changed after an update read it.
+var errStaleProduct = fmt.Errorf("product changed since it was read")
+
+// ErrDuplicateVariant indicates two variants of a product share a SKU or
+// name, or a variant has a blank name.
+var ErrDuplicateVariant = fmt.Errorf("variants need distinct SKUs and names")
+
+// ErrVariantStocked indicates a product update drops a variant that still
+// has stock on hand or reserved.
+var ErrVariantStocked = fmt.Errorf("variant still has stock on hand or reserved")
+
+// ErrPriceOnVariants indicates price_cents was given for a product whose
+// prices are set per variant.
+var ErrPriceOnVariants = fmt.Errorf("price is set per variant for this product")
+
+// ErrUnknownModifierGroup indicates a product was given a modifier group
+// that does not exist.
+var ErrUnknownModifierGroup = fmt.Errorf("modifier group not found")
+
+// ErrUnknownVariant indicates a quote named no variant, or one the product
+// does not have.
+var ErrUnknownVariant = fmt.Errorf("choose one of the product's variants by sku")
+
+// ErrUnavailable indicates the product or the chosen variant cannot be
+// ordered right now.
+var ErrUnavailable = fmt.Errorf("product is not available")
+
+// ErrUnknownCategory indicates a product was given a category that does not
+// exist.
+var ErrUnknownCategory = fmt.Errorf("category not found")
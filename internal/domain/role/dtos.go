@@ -0,0 +1,16 @@
+package role
+
+// ── Request DTOs ───────────────────────────────────────────────────────────────
+
+// CreateRequest is the body for POST /api/v1/admin/roles.
+type CreateRequest struct {
+	Name        string   `json:"name"        validate:"required,min=2,max=40,slug"`
+	Description string   `json:"description" validate:"max=200"`
+	Permissions []string `json:"permissions" validate:"dive,required"`
+}
+
+// UpdateRequest is the body for PUT /api/v1/admin/roles/:name.
+type UpdateRequest struct {
+	Description *string   `json:"description" validate:"omitempty,max=200"`
+	Permissions *[]string `json:"permissions" validate:"omitempty,dive,required"`
+}
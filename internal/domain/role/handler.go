@@ -0,0 +1,128 @@
+package role
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/one-backend-go/internal/pkg/reqctx"
+	"github.com/one-backend-go/internal/pkg/resp"
+	"github.com/one-backend-go/internal/pkg/validate"
+)
+
+// Handler holds HTTP handlers for role management endpoints.
+type Handler struct {
+	svc      *Service
+	validate *validate.Validator
+}
+
+// NewHandler creates a new role Handler.
+func NewHandler(svc *Service, v *validate.Validator) *Handler {
+	return &Handler{svc: svc, validate: v}
+}
+
+// List handles GET /api/v1/admin/roles.
+func (h *Handler) List(c *gin.Context) {
+	roles, err := h.svc.List(c.Request.Context())
+	if err != nil {
+		resp.InternalError(c)
+		return
+	}
+
+	resp.Success(c, http.StatusOK, gin.H{"items": roles})
+}
+
+// Get handles GET /api/v1/admin/roles/:name.
+func (h *Handler) Get(c *gin.Context) {
+	r, err := h.svc.Get(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		if errors.Is(err, ErrRoleNotFound) {
+			resp.NotFound(c, "role not found")
+			return
+		}
+		resp.InternalError(c)
+		return
+	}
+
+	resp.Success(c, http.StatusOK, r)
+}
+
+// Create handles POST /api/v1/admin/roles.
+func (h *Handler) Create(c *gin.Context) {
+	var req CreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.Fail(c, http.StatusBadRequest, "BAD_REQUEST", "invalid JSON body", nil)
+		return
+	}
+
+	if errs := h.validate.Struct(req); errs != nil {
+		resp.ValidationError(c, errs)
+		return
+	}
+
+	actorID, _ := reqctx.UserID(c)
+	r, err := h.svc.Create(c.Request.Context(), actorID, req)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+
+	resp.Success(c, http.StatusCreated, r)
+}
+
+// Update handles PUT /api/v1/admin/roles/:name.
+func (h *Handler) Update(c *gin.Context) {
+	var req UpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.Fail(c, http.StatusBadRequest, "BAD_REQUEST", "invalid JSON body", nil)
+		return
+	}
+
+	if errs := h.validate.Struct(req); errs != nil {
+		resp.ValidationError(c, errs)
+		return
+	}
+
+	actorID, _ := reqctx.UserID(c)
+	r, err := h.svc.Update(c.Request.Context(), actorID, c.Param("name"), req)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+
+	resp.Success(c, http.StatusOK, r)
+}
+
+// Delete handles DELETE /api/v1/admin/roles/:name.
+func (h *Handler) Delete(c *gin.Context) {
+	actorID, _ := reqctx.UserID(c)
+	if err := h.svc.Delete(c.Request.Context(), actorID, c.Param("name")); err != nil {
+		h.fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "role deleted"})
+}
+
+// fail maps role service errors to HTTP responses.
+func (h *Handler) fail(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrRoleNotFound):
+		resp.NotFound(c, "role not found")
+	case errors.Is(err, ErrRoleExists):
+		resp.Conflict(c, "a role with this name already exists")
+	case errors.Is(err, ErrBuiltInRole):
+		resp.Forbidden(c, "built-in roles cannot be modified")
+	case errors.Is(err, ErrInsufficientAuthority):
+		resp.Forbidden(c, err.Error())
+	case errors.Is(err, ErrOwnRole):
+		resp.Forbidden(c, err.Error())
+	case errors.Is(err, ErrEmptyUpdate):
+		resp.Fail(c, http.StatusBadRequest, "BAD_REQUEST", "no fields to update", nil)
+	case errors.Is(err, ErrUnknownPermission):
+		resp.ValidationError(c, map[string]string{"permissions": err.Error()})
+	default:
+		resp.InternalError(c)
+	}
+}
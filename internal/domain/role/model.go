@@ -0,0 +1,95 @@
+// Package role contains the role and permission model used for authorization.
+package role
+
+import (
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Permissions checked by the API. A role may also grant "*" (everything) or
+// "<resource>:*" (every action on a resource).
+const (
+	PermProductsWrite   = "products:write"
+	PermInventoryManage = "inventory:manage"
+	PermOrdersRefund    = "orders:refund"
+	PermUsersManage     = "users:manage"
+	PermRolesManage     = "roles:manage"
+	PermAPIKeysManage   = "apikeys:manage"
+
+	// PermAll grants every permission.
+	PermAll = "*"
+)
+
+// KnownPermissions lists every concrete permission a role may be granted.
+var KnownPermissions = []string{
+	PermProductsWrite,
+	PermInventoryManage,
+	PermOrdersRefund,
+	PermUsersManage,
+	PermRolesManage,
+	PermAPIKeysManage,
+}
+
+// Built-in role names. Built-in roles are defined in code and cannot be
+// modified or deleted through the API.
+const (
+	User  = "user"
+	Admin = "admin"
+)
+
+// Role is a named set of permissions. Custom roles are stored in MongoDB.
+type Role struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Name        string             `bson:"name"          json:"name"`
+	Description string             `bson:"description"   json:"description"`
+	Permissions []string           `bson:"permissions"   json:"permissions"`
+	BuiltIn     bool               `bson:"-"             json:"built_in"`
+	CreatedAt   time.Time          `bson:"created_at"    json:"created_at,omitempty"`
+	UpdatedAt   time.Time          `bson:"updated_at"    json:"updated_at,omitempty"`
+}
+
+// builtIns returns the roles defined in code, in display order.
+func builtIns() []Role {
+	return []Role{
+		{Name: User, Description: "Default role for registered customers", Permissions: []string{}, BuiltIn: true},
+		{Name: Admin, Description: "Full access to every resource", Permissions: []string{PermAll}, BuiltIn: true},
+	}
+}
+
+// builtIn returns the built-in role with the given name, if any.
+func builtIn(name string) (Role, bool) {
+	for _, r := range builtIns() {
+		if r.Name == name {
+			return r, true
+		}
+	}
+	return Role{}, false
+}
+
+// HasPermission reports whether the granted permissions satisfy want,
+// honouring the "*" and "<resource>:*" wildcards.
+func HasPermission(granted []string, want string) bool {
+	resource, _, _ := strings.Cut(want, ":")
+	for _, g := range granted {
+		if g == want || g == PermAll || g == resource+":*" {
+			return true
+		}
+	}
+	return false
+}
+
+// isValidPermission reports whether p is a known permission or wildcard.
+func isValidPermission(p string) bool {
+	if p == PermAll {
+		return true
+	}
+	for _, k := range KnownPermissions {
+		resource, _, _ := strings.Cut(k, ":")
+		if p == k || p == resource+":*" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package role
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Repository provides persistence operations for custom roles.
+type Repository struct {
+	col *mongo.Collection
+}
+
+// NewRepository returns a new role Repository.
+func NewRepository(db *mongo.Database) *Repository {
+	return &Repository{col: db.Collection("roles")}
+}
+
+// Create inserts a new role document.
+func (r *Repository) Create(ctx context.Context, role *Role) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	role.ID = primitive.NewObjectID()
+	now := time.Now().UTC()
+	role.CreatedAt = now
+	role.UpdatedAt = now
+
+	_, err := r.col.InsertOne(ctx, role)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrRoleExists
+		}
+		return fmt.Errorf("role repo create: %w", err)
+	}
+	return nil
+}
+
+// FindByName retrieves a custom role by name.
+func (r *Repository) FindByName(ctx context.Context, name string) (*Role, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var role Role
+	err := r.col.FindOne(ctx, bson.M{"name": name}).Decode(&role)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("role repo findByName: %w", err)
+	}
+	return &role, nil
+}
+
+// List returns all custom roles ordered by name.
+func (r *Repository) List(ctx context.Context) ([]Role, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	cursor, err := r.col.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("role repo list: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var roles []Role
+	if err = cursor.All(ctx, &roles); err != nil {
+		return nil, fmt.Errorf("role repo decode: %w", err)
+	}
+	return roles, nil
+}
+
+// Update modifies an existing custom role by name.
+func (r *Repository) Update(ctx context.Context, name string, update bson.M) (*Role, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	update["updated_at"] = time.Now().UTC()
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var role Role
+	err := r.col.FindOneAndUpdate(ctx, bson.M{"name": name}, bson.M{"$set": update}, opts).Decode(&role)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("role repo update: %w", err)
+	}
+	return &role, nil
+}
+
+// Delete removes a custom role by name. Returns true if a document was deleted.
+func (r *Repository) Delete(ctx context.Context, name string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res, err := r.col.DeleteOne(ctx, bson.M{"name": name})
+	if err != nil {
+		return false, fmt.Errorf("role repo delete: %w", err)
+	}
+	return res.DeletedCount > 0, nil
+}
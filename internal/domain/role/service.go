@@ -0,0 +1,242 @@
+package role
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Members looks up the role a user holds. It is implemented by the user
+// repository.
+type Members interface {
+	RoleOf(ctx context.Context, userID primitive.ObjectID) (string, error)
+}
+
+// Service contains business logic for roles and permissions.
+type Service struct {
+	repo    *Repository
+	members Members
+}
+
+// NewService creates a new role Service.
+func NewService(repo *Repository, members Members) *Service {
+	return &Service{repo: repo, members: members}
+}
+
+// List returns the built-in roles followed by all custom roles.
+func (s *Service) List(ctx context.Context) ([]Role, error) {
+	custom, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("role service list: %w", err)
+	}
+	return append(builtIns(), custom...), nil
+}
+
+// Get returns the role with the given name.
+func (s *Service) Get(ctx context.Context, name string) (*Role, error) {
+	if r, ok := builtIn(name); ok {
+		return &r, nil
+	}
+
+	r, err := s.repo.FindByName(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("role service get: %w", err)
+	}
+	if r == nil {
+		return nil, ErrRoleNotFound
+	}
+	return r, nil
+}
+
+// Exists reports whether a role with the given name is defined.
+func (s *Service) Exists(ctx context.Context, name string) (bool, error) {
+	_, err := s.Get(ctx, name)
+	if errors.Is(err, ErrRoleNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Permissions returns the permissions granted by a role. Unknown roles grant
+// nothing, so deleting a role strips its holders of all permissions.
+func (s *Service) Permissions(ctx context.Context, name string) ([]string, error) {
+	r, err := s.Get(ctx, name)
+	if err != nil {
+		if errors.Is(err, ErrRoleNotFound) {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+	return r.Permissions, nil
+}
+
+// Create defines a new custom role. The actor must hold every permission the
+// role grants.
+func (s *Service) Create(ctx context.Context, actorID primitive.ObjectID, req CreateRequest) (*Role, error) {
+	if _, ok := builtIn(req.Name); ok {
+		return nil, ErrRoleExists
+	}
+	if err := CheckPermissions(req.Permissions); err != nil {
+		return nil, err
+	}
+	if _, err := s.authorize(ctx, actorID, req.Permissions); err != nil {
+		return nil, err
+	}
+
+	r := &Role{
+		Name:        req.Name,
+		Description: req.Description,
+		Permissions: NormalizePermissions(req.Permissions),
+	}
+	if err := s.repo.Create(ctx, r); err != nil {
+		return nil, err
+	}
+
+	slog.Info("role created", "name", r.Name, "permissions", r.Permissions)
+	return r, nil
+}
+
+// Update modifies a custom role's description or permissions. The actor must
+// hold every permission the role grants before and after the change, and
+// cannot edit their own role.
+func (s *Service) Update(ctx context.Context, actorID primitive.ObjectID, name string, req UpdateRequest) (*Role, error) {
+	if _, ok := builtIn(name); ok {
+		return nil, ErrBuiltInRole
+	}
+
+	current, err := s.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	own, err := s.authorize(ctx, actorID, current.Permissions)
+	if err != nil {
+		return nil, err
+	}
+	if own == name {
+		return nil, ErrOwnRole
+	}
+
+	update := bson.M{}
+	if req.Description != nil {
+		update["description"] = *req.Description
+	}
+	if req.Permissions != nil {
+		if err := CheckPermissions(*req.Permissions); err != nil {
+			return nil, err
+		}
+		if _, err := s.authorize(ctx, actorID, *req.Permissions); err != nil {
+			return nil, err
+		}
+		update["permissions"] = NormalizePermissions(*req.Permissions)
+	}
+
+	if len(update) == 0 {
+		return nil, ErrEmptyUpdate
+	}
+
+	r, err := s.repo.Update(ctx, name, update)
+	if err != nil {
+		return nil, err
+	}
+	if r == nil {
+		return nil, ErrRoleNotFound
+	}
+
+	slog.Info("role updated", "name", r.Name, "permissions", r.Permissions)
+	return r, nil
+}
+
+// Delete removes a custom role. The actor must hold every permission the
+// role grants.
+func (s *Service) Delete(ctx context.Context, actorID primitive.ObjectID, name string) error {
+	if _, ok := builtIn(name); ok {
+		return ErrBuiltInRole
+	}
+
+	current, err := s.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+	if _, err = s.authorize(ctx, actorID, current.Permissions); err != nil {
+		return err
+	}
+
+	deleted, err := s.repo.Delete(ctx, name)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrRoleNotFound
+	}
+
+	slog.Info("role deleted", "name", name)
+	return nil
+}
+
+// authorize returns ErrInsufficientAuthority unless the actor's current role
+// grants every one of perms. It returns the name of that role.
+func (s *Service) authorize(ctx context.Context, actorID primitive.ObjectID, perms []string) (string, error) {
+	name, err := s.members.RoleOf(ctx, actorID)
+	if err != nil {
+		return "", fmt.Errorf("role service authorize: %w", err)
+	}
+	held, err := s.Permissions(ctx, name)
+	if err != nil {
+		return "", fmt.Errorf("role service authorize: %w", err)
+	}
+	for _, p := range perms {
+		if !HasPermission(held, p) {
+			return "", fmt.Errorf("%w: %s", ErrInsufficientAuthority, p)
+		}
+	}
+	return name, nil
+}
+
+// CheckPermissions rejects permissions the API does not know about.
+func CheckPermissions(perms []string) error {
+	for _, p := range perms {
+		if !isValidPermission(p) {
+			return fmt.Errorf("%w: %s", ErrUnknownPermission, p)
+		}
+	}
+	return nil
+}
+
+// NormalizePermissions removes duplicate permissions, preserving order.
+func NormalizePermissions(perms []string) []string {
+	seen := make(map[string]bool, len(perms))
+	out := make([]string, 0, len(perms))
+	for _, p := range perms {
+		if !seen[p] {
+			seen[p] = true
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// ErrRoleNotFound indicates the role does not exist.
+var ErrRoleNotFound = fmt.Errorf("role not found")
+
+// ErrRoleExists indicates a role with the same name already exists.
+var ErrRoleExists = fmt.Errorf("role already exists")
+
+// ErrBuiltInRole indicates an attempt to modify a built-in role.
+var ErrBuiltInRole = fmt.Errorf("built-in roles cannot be modified")
+
+// ErrUnknownPermission indicates a role references an undefined permission.
+var ErrUnknownPermission = fmt.Errorf("unknown permission")
+
+// ErrInsufficientAuthority indicates an administrator tried to grant, or
+// change a role granting, a permission they do not hold themselves.
+var ErrInsufficientAuthority = fmt.Errorf("insufficient authority over this role")
+
+// ErrOwnRole indicates an administrator tried to edit the role they hold.
+var ErrOwnRole = fmt.Errorf("administrators cannot edit their own role")
+
+// ErrEmptyUpdate indicates an update request without any fields set.
+var ErrEmptyUpdate = fmt.Errorf("no fields to update")
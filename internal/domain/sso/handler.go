@@ -0,0 +1,87 @@
+package sso
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/one-backend-go/internal/domain/user"
+	"github.com/one-backend-go/internal/pkg/resp"
+	"github.com/one-backend-go/internal/pkg/validate"
+)
+
+// Handler holds HTTP handlers for OpenID Connect login endpoints.
+type Handler struct {
+	svc      *Service
+	validate *validate.Validator
+}
+
+// NewHandler creates a new SSO Handler.
+func NewHandler(svc *Service, v *validate.Validator) *Handler {
+	return &Handler{svc: svc, validate: v}
+}
+
+// Providers handles GET /api/v1/auth/oidc/providers.
+func (h *Handler) Providers(c *gin.Context) {
+	resp.Success(c, http.StatusOK, gin.H{"items": h.svc.Providers()})
+}
+
+// Start handles POST /api/v1/auth/oidc/:provider/start.
+func (h *Handler) Start(c *gin.Context) {
+	out, err := h.svc.Start(c.Request.Context(), c.Param("provider"))
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+
+	resp.Success(c, http.StatusOK, out)
+}
+
+// Callback handles POST /api/v1/auth/oidc/:provider/callback.
+func (h *Handler) Callback(c *gin.Context) {
+	var req CallbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.Fail(c, http.StatusBadRequest, "BAD_REQUEST", "invalid JSON body", nil)
+		return
+	}
+
+	if errs := h.validate.Struct(req); errs != nil {
+		resp.ValidationError(c, errs)
+		return
+	}
+
+	tokens, challenge, err := h.svc.Callback(c.Request.Context(), c.Param("provider"), req)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+
+	if challenge != nil {
+		resp.Success(c, http.StatusOK, challenge)
+		return
+	}
+	resp.Success(c, http.StatusOK, tokens)
+}
+
+// fail maps SSO service errors to HTTP responses.
+func (h *Handler) fail(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrUnknownProvider):
+		resp.NotFound(c, "identity provider not found")
+	case errors.Is(err, ErrProviderUnavailable):
+		resp.Fail(c, http.StatusBadGateway, "PROVIDER_UNAVAILABLE", "identity provider is unavailable", nil)
+	case errors.Is(err, ErrInvalidState):
+		resp.Fail(c, http.StatusBadRequest, "INVALID_STATE", "invalid or expired login state", nil)
+	case errors.Is(err, ErrLoginFailed):
+		resp.Fail(c, http.StatusUnauthorized, "OIDC_LOGIN_FAILED", "the identity provider did not confirm your identity", nil)
+	case errors.Is(err, user.ErrIdentityConflict):
+		resp.Conflict(c, user.ErrIdentityConflict.Error())
+	case errors.Is(err, user.ErrEmailNotVerified):
+		resp.Fail(c, http.StatusForbidden, "EMAIL_NOT_VERIFIED", "a verified email address is required", nil)
+	case errors.Is(err, user.ErrAccountDisabled):
+		resp.Fail(c, http.StatusForbidden, "ACCOUNT_DISABLED", "this account has been disabled", nil)
+	default:
+		resp.InternalError(c)
+	}
+}
@@ -0,0 +1,36 @@
+// Package sso implements login through external OpenID Connect providers
+// using the authorization code flow with PKCE.
+package sso
+
+import "time"
+
+// LoginState is a pending login, created when the user is sent to the
+// provider and consumed when they return. The state value itself is only
+// stored hashed; the nonce and PKCE verifier never leave the server.
+type LoginState struct {
+	StateHash    string    `bson:"state_hash"`
+	Provider     string    `bson:"provider"`
+	Nonce        string    `bson:"nonce"`
+	CodeVerifier string    `bson:"code_verifier"`
+	ExpiresAt    time.Time `bson:"expires_at"`
+	CreatedAt    time.Time `bson:"created_at"`
+}
+
+// CallbackRequest is the body for POST /api/v1/auth/oidc/:provider/callback,
+// carrying the parameters the provider appended to the redirect URL.
+type CallbackRequest struct {
+	Code  string `json:"code"  validate:"required"`
+	State string `json:"state" validate:"required"`
+}
+
+// StartResponse tells the client where to send the user to log in.
+type StartResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+	State            string `json:"state"`
+	ExpiresIn        int    `json:"expires_in"` // seconds until the state expires
+}
+
+// ProviderResponse describes a configured provider.
+type ProviderResponse struct {
+	Name string `json:"name"`
+}
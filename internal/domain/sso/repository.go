@@ -0,0 +1,56 @@
+package sso
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Repository provides persistence operations for pending logins.
+type Repository struct {
+	col *mongo.Collection
+}
+
+// NewRepository returns a new login state Repository.
+func NewRepository(db *mongo.Database) *Repository {
+	return &Repository{col: db.Collection("oidc_states")}
+}
+
+// Create stores a pending login.
+func (r *Repository) Create(ctx context.Context, st *LoginState) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	st.CreatedAt = time.Now().UTC()
+	if _, err := r.col.InsertOne(ctx, st); err != nil {
+		return fmt.Errorf("sso repo create: %w", err)
+	}
+	return nil
+}
+
+// Consume deletes and returns the unexpired pending login for a provider and
+// state digest, or nil if there is none. Deleting on read makes each state
+// single-use.
+func (r *Repository) Consume(ctx context.Context, provider, stateHash string, now time.Time) (*LoginState, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"state_hash": stateHash,
+		"provider":   provider,
+		"expires_at": bson.M{"$gt": now},
+	}
+	var st LoginState
+	err := r.col.FindOneAndDelete(ctx, filter).Decode(&st)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("sso repo consume: %w", err)
+	}
+	return &st, nil
+}
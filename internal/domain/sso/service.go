@@ -0,0 +1,168 @@
+package sso
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/one-backend-go/internal/config"
+	"github.com/one-backend-go/internal/domain/auth"
+	"github.com/one-backend-go/internal/domain/user"
+	"github.com/one-backend-go/internal/pkg/oidc"
+)
+
+// Service runs OpenID Connect logins against the configured providers.
+type Service struct {
+	providers map[string]*oidc.Provider
+	repo      *Repository
+	users     *user.Service
+	auth      *auth.Service
+	stateTTL  time.Duration
+}
+
+// NewService creates a new SSO Service with a client for every provider in
+// the configuration.
+func NewService(cfg *config.Config, repo *Repository, users *user.Service, authSvc *auth.Service) *Service {
+	providers := make(map[string]*oidc.Provider, len(cfg.OIDCProviders))
+	for _, p := range cfg.OIDCProviders {
+		providers[p.Name] = oidc.NewProvider(oidc.Config{
+			Name:         p.Name,
+			Issuer:       p.Issuer,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			RedirectURL:  p.RedirectURL,
+			Scopes:       p.Scopes,
+		}, nil)
+	}
+	return &Service{
+		providers: providers,
+		repo:      repo,
+		users:     users,
+		auth:      authSvc,
+		stateTTL:  cfg.OIDCStateTTL,
+	}
+}
+
+// Providers lists the configured providers by name.
+func (s *Service) Providers() []ProviderResponse {
+	out := make([]ProviderResponse, 0, len(s.providers))
+	for name := range s.providers {
+		out = append(out, ProviderResponse{Name: name})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Start begins a login: it stores a fresh state, nonce and PKCE verifier and
+// returns the provider URL to send the user to.
+func (s *Service) Start(ctx context.Context, providerName string) (*StartResponse, error) {
+	p, ok := s.providers[providerName]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	var secrets [3]string
+	for i := range secrets {
+		v, err := oidc.RandomString()
+		if err != nil {
+			return nil, err
+		}
+		secrets[i] = v
+	}
+	state, nonce, verifier := secrets[0], secrets[1], secrets[2]
+
+	url, err := p.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrProviderUnavailable, err)
+	}
+
+	err = s.repo.Create(ctx, &LoginState{
+		StateHash:    hashState(state),
+		Provider:     providerName,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().UTC().Add(s.stateTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &StartResponse{
+		AuthorizationURL: url,
+		State:            state,
+		ExpiresIn:        int(s.stateTTL.Seconds()),
+	}, nil
+}
+
+// Callback completes a login with the code and state the provider returned.
+// The ID token is verified, its identity resolved to a local user (see
+// user.Service.ResolveIdentity), and the user logged in like a password
+// login would.
+func (s *Service) Callback(ctx context.Context, providerName string, req CallbackRequest) (*auth.TokenResponse, *auth.MFAChallengeResponse, error) {
+	p, ok := s.providers[providerName]
+	if !ok {
+		return nil, nil, ErrUnknownProvider
+	}
+
+	st, err := s.repo.Consume(ctx, providerName, hashState(req.State), time.Now().UTC())
+	if err != nil {
+		return nil, nil, err
+	}
+	if st == nil {
+		slog.Warn("security event: oidc callback with unknown or expired state", "provider", providerName)
+		return nil, nil, ErrInvalidState
+	}
+
+	rawIDToken, err := p.Exchange(ctx, req.Code, st.CodeVerifier)
+	if err != nil {
+		slog.Warn("oidc code exchange failed", "provider", providerName, "error", err)
+		return nil, nil, ErrLoginFailed
+	}
+	claims, err := p.Verify(ctx, rawIDToken, st.Nonce)
+	if err != nil {
+		if errors.Is(err, oidc.ErrInvalidIDToken) {
+			slog.Warn("security event: oidc id token rejected", "provider", providerName, "error", err)
+		} else {
+			slog.Error("oidc id token verification failed", "provider", providerName, "error", err)
+		}
+		return nil, nil, ErrLoginFailed
+	}
+
+	u, err := s.users.ResolveIdentity(ctx, user.ExternalIdentity{
+		Provider:      providerName,
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Name:          claims.Name,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	slog.Info("oidc login", "user_id", u.ID.Hex(), "provider", providerName)
+	return s.auth.LoginExternal(ctx, u)
+}
+
+// hashState returns the hex-encoded SHA-256 digest under which a state is
+// stored.
+func hashState(state string) string {
+	sum := sha256.Sum256([]byte(state))
+	return hex.EncodeToString(sum[:])
+}
+
+// ErrUnknownProvider indicates no provider is configured under the name.
+var ErrUnknownProvider = fmt.Errorf("unknown identity provider")
+
+// ErrProviderUnavailable indicates the provider's discovery document could not be loaded.
+var ErrProviderUnavailable = fmt.Errorf("identity provider unavailable")
+
+// ErrInvalidState indicates the login state is unknown, used, or expired.
+var ErrInvalidState = fmt.Errorf("invalid or expired login state")
+
+// ErrLoginFailed indicates the provider did not confirm the user's identity.
+var ErrLoginFailed = fmt.Errorf("identity provider login failed")
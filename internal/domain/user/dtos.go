@@ -17,24 +17,79 @@ type LoginRequest struct {
 	Password string `json:"password" validate:"required"`
 }
 
+// UpdateProfileRequest is the body for PATCH /api/v1/users/me.
+type UpdateProfileRequest struct {
+	Name *string `json:"name" validate:"omitempty,name"`
+}
+
+// SetRoleRequest is the body for PUT /api/v1/admin/users/:id/role.
+type SetRoleRequest struct {
+	Role string `json:"role" validate:"required,slug"`
+}
+
+// VerifyEmailRequest is the body for POST /api/v1/auth/verify-email.
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// ResendVerificationRequest is the body for POST /api/v1/auth/verify-email/resend.
+type ResendVerificationRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// MFACodeRequest is the body for the MFA confirm and disable endpoints. Code
+// is a 6-digit TOTP code or, where accepted, a recovery code.
+type MFACodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
 // ── Response DTOs ──────────────────────────────────────────────────────────────
 
 // UserResponse is the safe representation of a user (no password).
 type UserResponse struct {
-	ID        string    `json:"id"`
-	Name      string    `json:"name"`
-	Email     string    `json:"email"`
-	Role      string    `json:"role"`
-	CreatedAt time.Time `json:"created_at"`
+	ID            string     `json:"id"`
+	Name          string     `json:"name"`
+	Email         string     `json:"email"`
+	Role          string     `json:"role"`
+	EmailVerified bool       `json:"email_verified"`
+	MFAEnabled    bool       `json:"mfa_enabled"`
+	Disabled      bool       `json:"disabled"`
+	Identities    []Identity `json:"identities,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// ListResponse is the paginated user list envelope.
+type ListResponse struct {
+	Items      []UserResponse `json:"items"`
+	Page       int64          `json:"page"`
+	PageSize   int64          `json:"page_size"`
+	Total      int64          `json:"total"`
+	TotalPages int64          `json:"total_pages"`
+}
+
+// MFAEnrollmentResponse is returned when TOTP enrollment starts.
+type MFAEnrollmentResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+// RecoveryCodesResponse lists freshly generated recovery codes. They are
+// shown once and only stored hashed.
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
 }
 
 // ToResponse converts a User model to its public response form.
 func (u *User) ToResponse() UserResponse {
 	return UserResponse{
-		ID:        u.ID.Hex(),
-		Name:      u.Name,
-		Email:     u.Email,
-		Role:      u.Role,
-		CreatedAt: u.CreatedAt,
+		ID:            u.ID.Hex(),
+		Name:          u.Name,
+		Email:         u.Email,
+		Role:          u.Role,
+		EmailVerified: u.EmailVerified,
+		MFAEnabled:    u.MFA.Enabled,
+		Disabled:      u.Disabled,
+		Identities:    u.Identities,
+		CreatedAt:     u.CreatedAt,
 	}
 }
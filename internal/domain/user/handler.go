@@ -3,9 +3,14 @@ package user
 import (
 	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 
+	"github.com/one-backend-go/internal/pkg/pagination"
+	"github.com/one-backend-go/internal/pkg/reqctx"
 	"github.com/one-backend-go/internal/pkg/resp"
 	"github.com/one-backend-go/internal/pkg/validate"
 )
@@ -46,3 +51,328 @@ func (h *Handler) Register(c *gin.Context) {
 
 	resp.Success(c, http.StatusCreated, u.ToResponse())
 }
+
+// Me handles GET /api/v1/users/me (authenticated).
+func (h *Handler) Me(c *gin.Context) {
+	uid, ok := reqctx.UserID(c)
+	if !ok {
+		resp.Unauthorized(c, "authentication required")
+		return
+	}
+
+	u, err := h.svc.GetByID(c.Request.Context(), uid)
+	if err != nil {
+		resp.InternalError(c)
+		return
+	}
+	if u == nil {
+		resp.Unauthorized(c, "user not found")
+		return
+	}
+
+	resp.Success(c, http.StatusOK, u.ToResponse())
+}
+
+// UpdateMe handles PATCH /api/v1/users/me (authenticated).
+func (h *Handler) UpdateMe(c *gin.Context) {
+	uid, ok := reqctx.UserID(c)
+	if !ok {
+		resp.Unauthorized(c, "authentication required")
+		return
+	}
+
+	var req UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.Fail(c, http.StatusBadRequest, "BAD_REQUEST", "invalid JSON body", nil)
+		return
+	}
+
+	if errs := h.validate.Struct(req); errs != nil {
+		resp.ValidationError(c, errs)
+		return
+	}
+
+	u, err := h.svc.UpdateProfile(c.Request.Context(), uid, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrEmptyUpdate):
+			resp.Fail(c, http.StatusBadRequest, "BAD_REQUEST", "no fields to update", nil)
+		case errors.Is(err, ErrUserNotFound):
+			resp.Unauthorized(c, "user not found")
+		default:
+			resp.InternalError(c)
+		}
+		return
+	}
+
+	resp.Success(c, http.StatusOK, u.ToResponse())
+}
+
+// VerifyEmail handles POST /api/v1/auth/verify-email.
+func (h *Handler) VerifyEmail(c *gin.Context) {
+	var req VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.Fail(c, http.StatusBadRequest, "BAD_REQUEST", "invalid JSON body", nil)
+		return
+	}
+
+	if errs := h.validate.Struct(req); errs != nil {
+		resp.ValidationError(c, errs)
+		return
+	}
+
+	u, err := h.svc.VerifyEmail(c.Request.Context(), req.Token)
+	if err != nil {
+		if errors.Is(err, ErrInvalidVerificationToken) {
+			resp.Fail(c, http.StatusBadRequest, "INVALID_TOKEN", "invalid or expired verification token", nil)
+			return
+		}
+		resp.InternalError(c)
+		return
+	}
+
+	resp.Success(c, http.StatusOK, u.ToResponse())
+}
+
+// ResendVerification handles POST /api/v1/auth/verify-email/resend. The
+// response is the same whether or not the email is registered.
+func (h *Handler) ResendVerification(c *gin.Context) {
+	var req ResendVerificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.Fail(c, http.StatusBadRequest, "BAD_REQUEST", "invalid JSON body", nil)
+		return
+	}
+
+	if errs := h.validate.Struct(req); errs != nil {
+		resp.ValidationError(c, errs)
+		return
+	}
+
+	if err := h.svc.ResendVerification(c.Request.Context(), req.Email); err != nil {
+		resp.InternalError(c)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "if the account exists and is unverified, a verification email has been sent"})
+}
+
+// List handles GET /api/v1/admin/users.
+func (h *Handler) List(c *gin.Context) {
+	p := pagination.DefaultParams()
+
+	if v := c.Query("page"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			p.Page = n
+		}
+	}
+	if v := c.Query("page_size"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			p.PageSize = n
+		}
+	}
+	if v := c.Query("sort"); v != "" {
+		parts := strings.SplitN(v, ",", 2)
+		p.Sort = parts[0]
+		if len(parts) == 2 && (parts[1] == "asc" || parts[1] == "desc") {
+			p.Order = parts[1]
+		}
+	}
+
+	filter := ListFilter{
+		Role:  c.Query("role"),
+		Email: c.Query("email"),
+	}
+
+	result, err := h.svc.List(c.Request.Context(), filter, p)
+	if err != nil {
+		resp.InternalError(c)
+		return
+	}
+
+	resp.Success(c, http.StatusOK, result)
+}
+
+// Get handles GET /api/v1/admin/users/:id.
+func (h *Handler) Get(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		resp.NotFound(c, "user not found")
+		return
+	}
+
+	u, err := h.svc.GetByID(c.Request.Context(), id)
+	if err != nil {
+		resp.InternalError(c)
+		return
+	}
+	if u == nil {
+		resp.NotFound(c, "user not found")
+		return
+	}
+
+	resp.Success(c, http.StatusOK, u.ToResponse())
+}
+
+// SetRole handles PUT /api/v1/admin/users/:id/role.
+func (h *Handler) SetRole(c *gin.Context) {
+	actorID, _ := reqctx.UserID(c)
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		resp.NotFound(c, "user not found")
+		return
+	}
+
+	var req SetRoleRequest
+	if err = c.ShouldBindJSON(&req); err != nil {
+		resp.Fail(c, http.StatusBadRequest, "BAD_REQUEST", "invalid JSON body", nil)
+		return
+	}
+
+	if errs := h.validate.Struct(req); errs != nil {
+		resp.ValidationError(c, errs)
+		return
+	}
+
+	u, err := h.svc.SetRole(c.Request.Context(), actorID, id, req.Role)
+	if err != nil {
+		failAdmin(c, err)
+		return
+	}
+
+	resp.Success(c, http.StatusOK, u.ToResponse())
+}
+
+// Disable handles POST /api/v1/admin/users/:id/disable.
+func (h *Handler) Disable(c *gin.Context) {
+	h.setDisabled(c, true)
+}
+
+// Enable handles POST /api/v1/admin/users/:id/enable.
+func (h *Handler) Enable(c *gin.Context) {
+	h.setDisabled(c, false)
+}
+
+func (h *Handler) setDisabled(c *gin.Context, disabled bool) {
+	actorID, _ := reqctx.UserID(c)
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		resp.NotFound(c, "user not found")
+		return
+	}
+
+	u, err := h.svc.SetDisabled(c.Request.Context(), actorID, id, disabled)
+	if err != nil {
+		failAdmin(c, err)
+		return
+	}
+
+	resp.Success(c, http.StatusOK, u.ToResponse())
+}
+
+// failAdmin maps user administration errors to HTTP responses.
+func failAdmin(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrUserNotFound):
+		resp.NotFound(c, "user not found")
+	case errors.Is(err, ErrUnknownRole):
+		resp.ValidationError(c, map[string]string{"role": "unknown role"})
+	case errors.Is(err, ErrSelfModification):
+		resp.Forbidden(c, "you cannot change your own role or status")
+	case errors.Is(err, ErrInsufficientAuthority):
+		resp.Forbidden(c, "you cannot manage users or grant roles with permissions you do not hold")
+	default:
+		resp.InternalError(c)
+	}
+}
+
+// EnrollMFA handles POST /api/v1/auth/mfa/enroll (authenticated).
+func (h *Handler) EnrollMFA(c *gin.Context) {
+	uid, ok := reqctx.UserID(c)
+	if !ok {
+		resp.Unauthorized(c, "authentication required")
+		return
+	}
+
+	enrollment, err := h.svc.StartMFAEnrollment(c.Request.Context(), uid)
+	if err != nil {
+		failMFA(c, err)
+		return
+	}
+
+	resp.Success(c, http.StatusOK, enrollment)
+}
+
+// ConfirmMFA handles POST /api/v1/auth/mfa/confirm (authenticated).
+func (h *Handler) ConfirmMFA(c *gin.Context) {
+	uid, ok := reqctx.UserID(c)
+	if !ok {
+		resp.Unauthorized(c, "authentication required")
+		return
+	}
+
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.Fail(c, http.StatusBadRequest, "BAD_REQUEST", "invalid JSON body", nil)
+		return
+	}
+
+	if errs := h.validate.Struct(req); errs != nil {
+		resp.ValidationError(c, errs)
+		return
+	}
+
+	codes, err := h.svc.ConfirmMFA(c.Request.Context(), uid, req.Code)
+	if err != nil {
+		failMFA(c, err)
+		return
+	}
+
+	resp.Success(c, http.StatusOK, codes)
+}
+
+// DisableMFA handles POST /api/v1/auth/mfa/disable (authenticated).
+func (h *Handler) DisableMFA(c *gin.Context) {
+	uid, ok := reqctx.UserID(c)
+	if !ok {
+		resp.Unauthorized(c, "authentication required")
+		return
+	}
+
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.Fail(c, http.StatusBadRequest, "BAD_REQUEST", "invalid JSON body", nil)
+		return
+	}
+
+	if errs := h.validate.Struct(req); errs != nil {
+		resp.ValidationError(c, errs)
+		return
+	}
+
+	if err := h.svc.DisableMFA(c.Request.Context(), uid, req.Code); err != nil {
+		failMFA(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "two-factor authentication disabled"})
+}
+
+// failMFA maps two-factor service errors to HTTP responses.
+func failMFA(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrUserNotFound):
+		resp.Unauthorized(c, "user not found")
+	case errors.Is(err, ErrInvalidMFACode):
+		resp.Fail(c, http.StatusBadRequest, "INVALID_MFA_CODE", "invalid two-factor code", nil)
+	case errors.Is(err, ErrMFAAlreadyEnabled):
+		resp.Conflict(c, "two-factor authentication is already enabled")
+	case errors.Is(err, ErrMFANotEnrolled):
+		resp.Conflict(c, "start two-factor enrollment first")
+	case errors.Is(err, ErrMFANotEnabled):
+		resp.Conflict(c, "two-factor authentication is not enabled")
+	case errors.Is(err, ErrMFARequired):
+		resp.Forbidden(c, "two-factor authentication is required for your role")
+	default:
+		resp.InternalError(c)
+	}
+}
@@ -0,0 +1,209 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/one-backend-go/internal/pkg/totp"
+)
+
+const (
+	recoveryCodeCount = 10
+	recoveryCodeBytes = 10 // 80 bits, shown as four groups of four characters
+
+	// totpSkew is how many periods of clock drift are tolerated either way.
+	totpSkew = 1
+)
+
+// mfaSettings configures TOTP enrollment and enforcement.
+type mfaSettings struct {
+	issuer        string
+	requiredRoles []string
+}
+
+// MFARequired reports whether users with the role must use two-factor
+// authentication.
+func (s *Service) MFARequired(role string) bool {
+	return slices.Contains(s.mfa.requiredRoles, role)
+}
+
+// StartMFAEnrollment generates a TOTP secret for the user. It only becomes
+// active once ConfirmMFA sees a valid code for it; restarting enrollment
+// replaces an unconfirmed secret.
+func (s *Service) StartMFAEnrollment(ctx context.Context, id primitive.ObjectID) (*MFAEnrollmentResponse, error) {
+	u, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, ErrUserNotFound
+	}
+	if u.MFA.Enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, fmt.Errorf("user service mfa enroll: %w", err)
+	}
+	if _, err = s.repo.Update(ctx, id, bson.M{"mfa.pending_secret": secret}); err != nil {
+		return nil, err
+	}
+
+	return &MFAEnrollmentResponse{
+		Secret:     secret,
+		OTPAuthURI: totp.URI(s.mfa.issuer, u.Email, secret),
+	}, nil
+}
+
+// ConfirmMFA enables two-factor authentication once the user proves their
+// authenticator produces valid codes, and returns fresh recovery codes.
+func (s *Service) ConfirmMFA(ctx context.Context, id primitive.ObjectID, code string) (*RecoveryCodesResponse, error) {
+	u, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, ErrUserNotFound
+	}
+	if u.MFA.Enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if u.MFA.PendingSecret == "" {
+		return nil, ErrMFANotEnrolled
+	}
+
+	step, ok := totp.Verify(u.MFA.PendingSecret, strings.TrimSpace(code), time.Now(), totpSkew)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, digests, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	mfa := MFA{
+		Enabled:       true,
+		Secret:        u.MFA.PendingSecret,
+		RecoveryCodes: digests,
+		LastUsedStep:  step,
+	}
+	if _, err = s.repo.Update(ctx, id, bson.M{"mfa": mfa}); err != nil {
+		return nil, err
+	}
+
+	slog.Info("mfa enabled", "id", u.ID.Hex())
+	return &RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// DisableMFA turns two-factor authentication off after checking a code.
+// Users whose role requires it cannot disable it.
+func (s *Service) DisableMFA(ctx context.Context, id primitive.ObjectID, code string) error {
+	u, err := s.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if u == nil {
+		return ErrUserNotFound
+	}
+	if !u.MFA.Enabled {
+		return ErrMFANotEnabled
+	}
+	if s.MFARequired(u.Role) {
+		return ErrMFARequired
+	}
+
+	if err = s.VerifyMFA(ctx, u, code); err != nil {
+		return err
+	}
+	if _, err = s.repo.Update(ctx, id, bson.M{"mfa": MFA{}}); err != nil {
+		return err
+	}
+
+	slog.Info("mfa disabled", "id", u.ID.Hex())
+	return nil
+}
+
+// VerifyMFA checks a TOTP code or a recovery code for the user. Each TOTP
+// code and each recovery code is accepted at most once.
+func (s *Service) VerifyMFA(ctx context.Context, u *User, code string) error {
+	if !u.MFA.Enabled {
+		return ErrMFANotEnabled
+	}
+	code = strings.TrimSpace(code)
+
+	if step, ok := totp.Verify(u.MFA.Secret, code, time.Now(), totpSkew); ok {
+		accepted, err := s.repo.ConsumeMFAStep(ctx, u.ID, step)
+		if err != nil {
+			return err
+		}
+		if !accepted {
+			return ErrInvalidMFACode
+		}
+		return nil
+	}
+
+	used, err := s.repo.ConsumeRecoveryCode(ctx, u.ID, hashRecoveryCode(code))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidMFACode
+	}
+
+	slog.Warn("recovery code used", "id", u.ID.Hex(), "remaining", len(u.MFA.RecoveryCodes)-1)
+	return nil
+}
+
+// newRecoveryCodes returns plaintext recovery codes and their digests.
+func newRecoveryCodes() ([]string, []string, error) {
+	enc := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, recoveryCodeCount)
+	digests := make([]string, recoveryCodeCount)
+
+	for i := range codes {
+		b := make([]byte, recoveryCodeBytes)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, fmt.Errorf("user service recovery codes: %w", err)
+		}
+		raw := strings.ToLower(enc.EncodeToString(b))
+		codes[i] = raw[0:4] + "-" + raw[4:8] + "-" + raw[8:12] + "-" + raw[12:16]
+		digests[i] = hashRecoveryCode(codes[i])
+	}
+	return codes, digests, nil
+}
+
+// hashRecoveryCode normalizes a recovery code and returns its SHA-256 digest,
+// so codes are accepted regardless of case and separators.
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// ErrMFAAlreadyEnabled indicates two-factor authentication is already on.
+var ErrMFAAlreadyEnabled = fmt.Errorf("two-factor authentication already enabled")
+
+// ErrMFANotEnrolled indicates confirmation was attempted without starting enrollment.
+var ErrMFANotEnrolled = fmt.Errorf("two-factor enrollment not started")
+
+// ErrMFANotEnabled indicates two-factor authentication is off for the user.
+var ErrMFANotEnabled = fmt.Errorf("two-factor authentication not enabled")
+
+// ErrMFARequired indicates the user's role requires two-factor authentication.
+var ErrMFARequired = fmt.Errorf("two-factor authentication required for this role")
+
+// ErrInvalidMFACode indicates a wrong, reused, or expired two-factor code.
+var ErrInvalidMFACode = fmt.Errorf("invalid two-factor code")
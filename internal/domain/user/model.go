@@ -5,21 +5,59 @@ import (
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/one-backend-go/internal/domain/role"
 )
 
 // User represents a registered user in the system.
 type User struct {
-	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Name         string             `bson:"name"          json:"name"`
-	Email        string             `bson:"email"         json:"email"`
-	PasswordHash string             `bson:"password_hash" json:"-"` // never serialized to JSON
-	Role         string             `bson:"role"          json:"role"`
-	CreatedAt    time.Time          `bson:"created_at"    json:"created_at"`
-	UpdatedAt    time.Time          `bson:"updated_at"    json:"updated_at"`
+	ID            primitive.ObjectID `bson:"_id,omitempty"        json:"id"`
+	Name          string             `bson:"name"                 json:"name"`
+	Email         string             `bson:"email"                json:"email"`
+	PasswordHash  string             `bson:"password_hash"        json:"-"` // never serialized to JSON
+	Role          string             `bson:"role"                 json:"role"`
+	Disabled      bool               `bson:"disabled"             json:"disabled"`
+	EmailVerified bool               `bson:"email_verified"       json:"email_verified"`
+	MFA           MFA                `bson:"mfa"                  json:"-"`
+	Identities    []Identity         `bson:"identities,omitempty" json:"identities,omitempty"`
+	ErasedAt      *time.Time         `bson:"erased_at,omitempty"  json:"-"` // set when anonymized on request
+	CreatedAt     time.Time          `bson:"created_at"           json:"created_at"`
+	UpdatedAt     time.Time          `bson:"updated_at"           json:"updated_at"`
+}
+
+// MFA holds a user's TOTP two-factor settings.
+type MFA struct {
+	Enabled       bool     `bson:"enabled"`
+	Secret        string   `bson:"secret,omitempty"`         // base32 TOTP secret
+	PendingSecret string   `bson:"pending_secret,omitempty"` // enrolled but not yet confirmed
+	RecoveryCodes []string `bson:"recovery_codes,omitempty"` // SHA-256 digests of unused codes
+	LastUsedStep  int64    `bson:"last_used_step,omitempty"` // rejects replayed codes
+}
+
+// Identity links a user to an account at an external OpenID Connect
+// provider. Subject is the provider's stable user ID; the email is kept for
+// display only.
+type Identity struct {
+	Provider string    `bson:"provider"  json:"provider"`
+	Subject  string    `bson:"subject"   json:"-"`
+	Email    string    `bson:"email"     json:"email"`
+	LinkedAt time.Time `bson:"linked_at" json:"linked_at"`
+}
+
+// ExternalIdentity is an identity asserted by an external provider at login.
+type ExternalIdentity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
 }
 
 // RoleUser is the default role for newly registered users.
-const RoleUser = "user"
+const RoleUser = role.User
 
 // RoleAdmin is the administrative role.
-const RoleAdmin = "admin"
+const RoleAdmin = role.Admin
+
+// ErasedName replaces the name of a user whose data was erased.
+const ErasedName = "Deleted User"
@@ -4,11 +4,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"regexp"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/one-backend-go/internal/pkg/pagination"
 )
 
 // Repository provides persistence operations for users.
@@ -73,5 +78,206 @@ func (r *Repository) FindByID(ctx context.Context, id primitive.ObjectID) (*User
 	return &u, nil
 }
 
+// RoleOf returns the name of the user's role, or an empty name for an
+// unknown user.
+func (r *Repository) RoleOf(ctx context.Context, id primitive.ObjectID) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var u struct {
+		Role string `bson:"role"`
+	}
+	opts := options.FindOne().SetProjection(bson.M{"role": 1})
+	err := r.col.FindOne(ctx, bson.M{"_id": id}, opts).Decode(&u)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return "", nil
+		}
+		return "", fmt.Errorf("user repo roleOf: %w", err)
+	}
+	return u.Role, nil
+}
+
+// FindByIdentity retrieves the user linked to an external identity.
+func (r *Repository) FindByIdentity(ctx context.Context, provider, subject string) (*User, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"identities": bson.M{"$elemMatch": bson.M{"provider": provider, "subject": subject}}}
+	var u User
+	err := r.col.FindOne(ctx, filter).Decode(&u)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("user repo findByIdentity: %w", err)
+	}
+	return &u, nil
+}
+
+// ListFilter holds optional filters for listing users.
+type ListFilter struct {
+	Role  string // exact match
+	Email string // case-insensitive substring
+}
+
+// List returns a paginated, filtered, and sorted list of users.
+func (r *Repository) List(ctx context.Context, filter ListFilter, p pagination.Params) ([]User, int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	f := bson.M{}
+	if filter.Role != "" {
+		f["role"] = filter.Role
+	}
+	if filter.Email != "" {
+		f["email"] = primitive.Regex{Pattern: regexp.QuoteMeta(strings.ToLower(filter.Email))}
+	}
+
+	total, err := r.col.CountDocuments(ctx, f)
+	if err != nil {
+		return nil, 0, fmt.Errorf("user repo count: %w", err)
+	}
+
+	sortOrder := -1
+	if p.Order == "asc" {
+		sortOrder = 1
+	}
+
+	sortField := "created_at"
+	switch p.Sort {
+	case "name", "email", "created_at":
+		sortField = p.Sort
+	}
+
+	opts := options.Find().
+		SetSkip(p.Skip()).
+		SetLimit(p.PageSize).
+		SetSort(bson.D{{Key: sortField, Value: sortOrder}})
+
+	cursor, err := r.col.Find(ctx, f, opts)
+	if err != nil {
+		return nil, 0, fmt.Errorf("user repo find: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var users []User
+	if err = cursor.All(ctx, &users); err != nil {
+		return nil, 0, fmt.Errorf("user repo decode: %w", err)
+	}
+
+	return users, total, nil
+}
+
+// Update applies a $set of the given fields and returns the updated user, or
+// nil if the user does not exist.
+func (r *Repository) Update(ctx context.Context, id primitive.ObjectID, fields bson.M) (*User, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	fields["updated_at"] = time.Now().UTC()
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var u User
+	err := r.col.FindOneAndUpdate(ctx, bson.M{"_id": id}, bson.M{"$set": fields}, opts).Decode(&u)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrEmailExists
+		}
+		return nil, fmt.Errorf("user repo update: %w", err)
+	}
+	return &u, nil
+}
+
+// ReplacePasswordHash swaps a user's password hash, unless it changed since
+// oldHash was read. Unlike Update it leaves updated_at alone.
+func (r *Repository) ReplacePasswordHash(ctx context.Context, id primitive.ObjectID, oldHash, newHash string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := r.col.UpdateOne(ctx,
+		bson.M{"_id": id, "password_hash": oldHash},
+		bson.M{"$set": bson.M{"password_hash": newHash}},
+	)
+	if err != nil {
+		return fmt.Errorf("user repo replacePasswordHash: %w", err)
+	}
+	return nil
+}
+
+// ConsumeMFAStep records step as the last accepted TOTP step, provided it is
+// newer than the previous one. It reports whether the step was accepted.
+func (r *Repository) ConsumeMFAStep(ctx context.Context, id primitive.ObjectID, step int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res, err := r.col.UpdateOne(ctx,
+		bson.M{
+			"_id": id,
+			"$or": bson.A{
+				bson.M{"mfa.last_used_step": bson.M{"$exists": false}},
+				bson.M{"mfa.last_used_step": bson.M{"$lt": step}},
+			},
+		},
+		bson.M{"$set": bson.M{"mfa.last_used_step": step}},
+	)
+	if err != nil {
+		return false, fmt.Errorf("user repo consumeMFAStep: %w", err)
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+// ConsumeRecoveryCode removes a recovery code digest from the user. It
+// reports whether the code was present.
+func (r *Repository) ConsumeRecoveryCode(ctx context.Context, id primitive.ObjectID, digest string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res, err := r.col.UpdateOne(ctx,
+		bson.M{"_id": id, "mfa.recovery_codes": digest},
+		bson.M{"$pull": bson.M{"mfa.recovery_codes": digest}},
+	)
+	if err != nil {
+		return false, fmt.Errorf("user repo consumeRecoveryCode: %w", err)
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+// AddIdentity links an external identity to a user and returns the updated
+// user. It returns nil if the user does not exist or already has an identity
+// at the same provider, and ErrIdentityLinked if the identity belongs to
+// another user.
+func (r *Repository) AddIdentity(ctx context.Context, id primitive.ObjectID, identity Identity) (*User, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var u User
+	err := r.col.FindOneAndUpdate(ctx,
+		bson.M{"_id": id, "identities.provider": bson.M{"$ne": identity.Provider}},
+		bson.M{
+			"$push": bson.M{"identities": identity},
+			"$set":  bson.M{"updated_at": time.Now().UTC()},
+		},
+		opts,
+	).Decode(&u)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrIdentityLinked
+		}
+		return nil, fmt.Errorf("user repo addIdentity: %w", err)
+	}
+	return &u, nil
+}
+
 // ErrEmailExists indicates a duplicate email during registration.
 var ErrEmailExists = fmt.Errorf("email already exists")
+
+// ErrIdentityLinked indicates an external identity is linked to another user.
+var ErrIdentityLinked = fmt.Errorf("external identity already linked to another user")
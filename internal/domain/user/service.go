@@ -2,51 +2,256 @@ package user
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"strings"
+	"time"
 
-	"golang.org/x/crypto/bcrypt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/one-backend-go/internal/config"
+	"github.com/one-backend-go/internal/domain/actiontoken"
+	"github.com/one-backend-go/internal/domain/role"
+	"github.com/one-backend-go/internal/pkg/notify"
+	"github.com/one-backend-go/internal/pkg/pagination"
+	"github.com/one-backend-go/internal/pkg/password"
 )
 
-const bcryptCost = 12
+// SessionRevoker signs a user out of every session. It is implemented by the
+// auth repository, which owns refresh tokens.
+type SessionRevoker interface {
+	RevokeAllForUser(ctx context.Context, userID primitive.ObjectID) error
+}
 
 // Service contains business logic for user operations.
 type Service struct {
-	repo *Repository
+	repo      *Repository
+	roles     *role.Service
+	sessions  SessionRevoker
+	tokens    *actiontoken.Service
+	notifier  notify.Notifier
+	hasher    *password.Hasher
+	verifyTTL time.Duration
+	mfa       mfaSettings
 }
 
 // NewService creates a new user Service.
-func NewService(repo *Repository) *Service {
-	return &Service{repo: repo}
+func NewService(
+	cfg *config.Config,
+	repo *Repository,
+	roles *role.Service,
+	sessions SessionRevoker,
+	tokens *actiontoken.Service,
+	notifier notify.Notifier,
+	hasher *password.Hasher,
+) *Service {
+	return &Service{
+		repo:      repo,
+		roles:     roles,
+		sessions:  sessions,
+		tokens:    tokens,
+		notifier:  notifier,
+		hasher:    hasher,
+		verifyTTL: cfg.EmailVerificationTTL,
+		mfa:       mfaSettings{issuer: cfg.MFAIssuer, requiredRoles: cfg.MFARequiredRoles},
+	}
 }
 
 // Register creates a new user after hashing the password.
 func (s *Service) Register(ctx context.Context, req RegisterRequest) (*User, error) {
-	email := strings.ToLower(strings.TrimSpace(req.Email))
+	u, err := s.create(ctx, req, RoleUser, false)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("user registered", "id", u.ID.Hex(), "email", u.Email)
+
+	// The account exists either way; the user can ask for a new email.
+	if err = s.sendVerification(ctx, u); err != nil {
+		slog.Error("failed to send verification email", "id", u.ID.Hex(), "error", err)
+	}
+	return u, nil
+}
+
+// CreateWithRole creates a user with the given role on an operator's behalf,
+// e.g. to bootstrap the first administrator. The email address is trusted and
+// marked verified; no notification is sent.
+func (s *Service) CreateWithRole(ctx context.Context, req RegisterRequest, roleName string) (*User, error) {
+	ok, err := s.roles.Exists(ctx, roleName)
+	if err != nil {
+		return nil, fmt.Errorf("user service create: %w", err)
+	}
+	if !ok {
+		return nil, ErrUnknownRole
+	}
 
-	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcryptCost)
+	u, err := s.create(ctx, req, roleName, true)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("user created", "id", u.ID.Hex(), "email", u.Email, "role", roleName)
+	return u, nil
+}
+
+// create hashes the password and inserts a new user.
+func (s *Service) create(ctx context.Context, req RegisterRequest, roleName string, verified bool) (*User, error) {
+	hash, err := s.hasher.Hash(req.Password)
 	if err != nil {
 		return nil, fmt.Errorf("user service hash: %w", err)
 	}
 
 	u := &User{
-		Name:         strings.TrimSpace(req.Name),
-		Email:        email,
-		PasswordHash: string(hash),
-		Role:         RoleUser,
+		Name:          strings.TrimSpace(req.Name),
+		Email:         strings.ToLower(strings.TrimSpace(req.Email)),
+		PasswordHash:  hash,
+		Role:          roleName,
+		EmailVerified: verified,
 	}
 
 	if err = s.repo.Create(ctx, u); err != nil {
 		return nil, err
 	}
+	return u, nil
+}
 
-	slog.Info("user registered", "id", u.ID.Hex(), "email", u.Email)
+// ResolveIdentity returns the user an external identity logs in as. A known
+// identity resolves to its linked user. Otherwise a provider-verified email
+// is required: it is linked to the local account with that address if the
+// account's own address was verified too, and a new account without a
+// password is created if there is none. An unverified local account is never
+// linked, since whoever registered it may not own the address.
+func (s *Service) ResolveIdentity(ctx context.Context, ext ExternalIdentity) (*User, error) {
+	u, err := s.repo.FindByIdentity(ctx, ext.Provider, ext.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("user service resolve identity: %w", err)
+	}
+	if u != nil {
+		return u, nil
+	}
+
+	email := strings.ToLower(strings.TrimSpace(ext.Email))
+	if email == "" || !ext.EmailVerified {
+		return nil, ErrEmailNotVerified
+	}
+	identity := Identity{
+		Provider: ext.Provider,
+		Subject:  ext.Subject,
+		Email:    email,
+		LinkedAt: time.Now().UTC(),
+	}
+
+	existing, err := s.repo.FindByEmail(ctx, email)
+	if err != nil {
+		return nil, fmt.Errorf("user service resolve identity: %w", err)
+	}
+	if existing != nil {
+		if !existing.EmailVerified {
+			slog.Warn("security event: external identity matches unverified account",
+				"id", existing.ID.Hex(), "provider", ext.Provider)
+			return nil, ErrIdentityConflict
+		}
+		linked, err := s.repo.AddIdentity(ctx, existing.ID, identity)
+		if err != nil {
+			if errors.Is(err, ErrIdentityLinked) {
+				return nil, ErrIdentityConflict
+			}
+			return nil, err
+		}
+		if linked == nil {
+			// The account is already linked to another subject at this provider.
+			return nil, ErrIdentityConflict
+		}
+		slog.Info("external identity linked", "id", linked.ID.Hex(), "provider", ext.Provider)
+		return linked, nil
+	}
+
+	name := strings.TrimSpace(ext.Name)
+	if name == "" {
+		name = strings.SplitN(email, "@", 2)[0]
+	}
+	u = &User{
+		Name:          name,
+		Email:         email,
+		Role:          RoleUser,
+		EmailVerified: true,
+		Identities:    []Identity{identity},
+	}
+	if err = s.repo.Create(ctx, u); err != nil {
+		if errors.Is(err, ErrEmailExists) {
+			// A concurrent login or registration won the race.
+			return nil, ErrIdentityConflict
+		}
+		return nil, err
+	}
+
+	slog.Info("user registered", "id", u.ID.Hex(), "email", u.Email, "provider", ext.Provider)
+	return u, nil
+}
+
+// VerifyEmail redeems a verification token and marks the user's email as
+// verified.
+func (s *Service) VerifyEmail(ctx context.Context, token string) (*User, error) {
+	id, err := s.tokens.Consume(ctx, actiontoken.PurposeEmailVerification, token)
+	if err != nil {
+		if errors.Is(err, actiontoken.ErrInvalidToken) {
+			return nil, ErrInvalidVerificationToken
+		}
+		return nil, fmt.Errorf("user service verify email: %w", err)
+	}
+
+	u, err := s.repo.Update(ctx, id, bson.M{"email_verified": true})
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	slog.Info("email verified", "id", u.ID.Hex())
 	return u, nil
 }
 
+// ResendVerification sends a fresh verification token, invalidating earlier
+// ones. Unknown and already verified addresses are ignored silently.
+func (s *Service) ResendVerification(ctx context.Context, email string) error {
+	u, err := s.GetByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	if u == nil || u.EmailVerified || u.Disabled {
+		return nil
+	}
+	return s.sendVerification(ctx, u)
+}
+
+// sendVerification issues a verification token and mails it to the user.
+func (s *Service) sendVerification(ctx context.Context, u *User) error {
+	token, err := s.tokens.Issue(ctx, u.ID, actiontoken.PurposeEmailVerification, s.verifyTTL)
+	if err != nil {
+		return fmt.Errorf("user service verification token: %w", err)
+	}
+
+	err = s.notifier.Send(ctx, notify.Message{
+		To:      u.Email,
+		Kind:    actiontoken.PurposeEmailVerification,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Use this token to verify your email address: %s\nIt expires in %s.",
+			token, s.verifyTTL),
+		Data: map[string]string{"token": token},
+	})
+	if err != nil {
+		return fmt.Errorf("user service verification notify: %w", err)
+	}
+	return nil
+}
+
 // Authenticate verifies email/password and returns the user on success.
-func (s *Service) Authenticate(ctx context.Context, email, password string) (*User, error) {
+// Disabled accounts are refused once the password has been checked. A hash
+// made with outdated parameters is replaced using the plaintext at hand.
+func (s *Service) Authenticate(ctx context.Context, email, plain string) (*User, error) {
 	email = strings.ToLower(strings.TrimSpace(email))
 
 	u, err := s.repo.FindByEmail(ctx, email)
@@ -57,23 +262,342 @@ func (s *Service) Authenticate(ctx context.Context, email, password string) (*Us
 		return nil, ErrInvalidCredentials
 	}
 
-	if err = bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)); err != nil {
+	ok, rehash := s.hasher.Verify(u.PasswordHash, plain)
+	if !ok {
 		return nil, ErrInvalidCredentials
 	}
+	if u.Disabled {
+		return nil, ErrAccountDisabled
+	}
 
+	if rehash {
+		s.rehash(ctx, u, plain)
+	}
 	return u, nil
 }
 
-// HashPassword hashes a plaintext password with bcrypt. Exported for testing.
+// rehash upgrades a user's stored hash to the current parameters. Failures
+// are only logged: the login itself has succeeded.
+func (s *Service) rehash(ctx context.Context, u *User, plain string) {
+	hash, err := s.hasher.Hash(plain)
+	if err != nil {
+		slog.Error("failed to rehash password", "id", u.ID.Hex(), "error", err)
+		return
+	}
+	if err = s.repo.ReplacePasswordHash(ctx, u.ID, u.PasswordHash, hash); err != nil {
+		slog.Error("failed to rehash password", "id", u.ID.Hex(), "error", err)
+		return
+	}
+	u.PasswordHash = hash
+	slog.Info("password rehashed", "id", u.ID.Hex())
+}
+
+// GetByID returns the user with the given ID, or nil if none exists.
+func (s *Service) GetByID(ctx context.Context, id primitive.ObjectID) (*User, error) {
+	u, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("user service get: %w", err)
+	}
+	return u, nil
+}
+
+// List returns a paginated list of users for administrators.
+func (s *Service) List(ctx context.Context, filter ListFilter, p pagination.Params) (*ListResponse, error) {
+	p.Clamp()
+
+	users, total, err := s.repo.List(ctx, filter, p)
+	if err != nil {
+		return nil, fmt.Errorf("user service list: %w", err)
+	}
+
+	items := make([]UserResponse, 0, len(users))
+	for i := range users {
+		items = append(items, users[i].ToResponse())
+	}
+
+	return &ListResponse{
+		Items:      items,
+		Page:       p.Page,
+		PageSize:   p.PageSize,
+		Total:      total,
+		TotalPages: pagination.TotalPages(total, p.PageSize),
+	}, nil
+}
+
+// SetRole assigns an existing role to a user. Administrators cannot change
+// their own role, so the last admin cannot demote themselves by accident, and
+// can only move a user between roles whose permissions they hold themselves.
+func (s *Service) SetRole(ctx context.Context, actorID, id primitive.ObjectID, roleName string) (*User, error) {
+	if actorID == id {
+		return nil, ErrSelfModification
+	}
+
+	ok, err := s.roles.Exists(ctx, roleName)
+	if err != nil {
+		return nil, fmt.Errorf("user service set role: %w", err)
+	}
+	if !ok {
+		return nil, ErrUnknownRole
+	}
+
+	target, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("user service set role: %w", err)
+	}
+	if target == nil {
+		return nil, ErrUserNotFound
+	}
+	if err = s.authorize(ctx, actorID, target.Role, roleName); err != nil {
+		return nil, err
+	}
+
+	u, err := s.repo.Update(ctx, id, bson.M{"role": roleName})
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, ErrUserNotFound
+	}
+
+	slog.Info("user role changed", "id", u.ID.Hex(), "role", roleName, "by", actorID.Hex())
+	return u, nil
+}
+
+// AssignRole assigns an existing role to a user on an operator's behalf, e.g.
+// from the admin CLI. It acts as no particular user and skips the checks
+// SetRole applies to administrators.
+func (s *Service) AssignRole(ctx context.Context, id primitive.ObjectID, roleName string) (*User, error) {
+	ok, err := s.roles.Exists(ctx, roleName)
+	if err != nil {
+		return nil, fmt.Errorf("user service assign role: %w", err)
+	}
+	if !ok {
+		return nil, ErrUnknownRole
+	}
+
+	u, err := s.repo.Update(ctx, id, bson.M{"role": roleName})
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, ErrUserNotFound
+	}
+
+	slog.Info("user role changed", "id", u.ID.Hex(), "role", roleName, "by", "operator")
+	return u, nil
+}
+
+// SetDisabled disables or re-enables a user. Disabling also signs the user
+// out of every session.
+func (s *Service) SetDisabled(ctx context.Context, actorID, id primitive.ObjectID, disabled bool) (*User, error) {
+	if actorID == id {
+		return nil, ErrSelfModification
+	}
+	if err := s.CheckAuthority(ctx, actorID, id); err != nil {
+		return nil, err
+	}
+
+	u, err := s.repo.Update(ctx, id, bson.M{"disabled": disabled})
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, ErrUserNotFound
+	}
+
+	if disabled {
+		if err = s.sessions.RevokeAllForUser(ctx, u.ID); err != nil {
+			return nil, fmt.Errorf("user service disable revoke: %w", err)
+		}
+	}
+
+	slog.Info("user disabled state changed", "id", u.ID.Hex(), "disabled", disabled, "by", actorID.Hex())
+	return u, nil
+}
+
+// CheckAuthority returns ErrUserNotFound if the target user does not exist and
+// ErrInsufficientAuthority if their role grants a permission the actor lacks.
+// Administrative actions on another account must pass it, so that holding
+// users:manage is not enough to disable or erase an administrator.
+func (s *Service) CheckAuthority(ctx context.Context, actorID, targetID primitive.ObjectID) error {
+	target, err := s.repo.FindByID(ctx, targetID)
+	if err != nil {
+		return fmt.Errorf("user service check authority: %w", err)
+	}
+	if target == nil {
+		return ErrUserNotFound
+	}
+	return s.authorize(ctx, actorID, target.Role)
+}
+
+// Permissions returns the permissions granted by the user's current role, as
+// stored in the database rather than in any token.
+func (s *Service) Permissions(ctx context.Context, id primitive.ObjectID) ([]string, error) {
+	u, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("user service permissions: %w", err)
+	}
+	if u == nil {
+		return nil, ErrUserNotFound
+	}
+
+	perms, err := s.roles.Permissions(ctx, u.Role)
+	if err != nil {
+		return nil, fmt.Errorf("user service permissions: %w", err)
+	}
+	return perms, nil
+}
+
+// authorize returns ErrInsufficientAuthority unless the actor's current role
+// grants every permission of each named role.
+func (s *Service) authorize(ctx context.Context, actorID primitive.ObjectID, roleNames ...string) error {
+	held, err := s.Permissions(ctx, actorID)
+	if errors.Is(err, ErrUserNotFound) {
+		return ErrInsufficientAuthority
+	}
+	if err != nil {
+		return fmt.Errorf("user service authorize: %w", err)
+	}
+	for _, name := range roleNames {
+		perms, err := s.roles.Permissions(ctx, name)
+		if err != nil {
+			return fmt.Errorf("user service authorize: %w", err)
+		}
+		for _, p := range perms {
+			if !role.HasPermission(held, p) {
+				return ErrInsufficientAuthority
+			}
+		}
+	}
+	return nil
+}
+
+// UpdateProfile applies a user's changes to their own profile.
+func (s *Service) UpdateProfile(ctx context.Context, id primitive.ObjectID, req UpdateProfileRequest) (*User, error) {
+	update := bson.M{}
+	if req.Name != nil {
+		update["name"] = strings.TrimSpace(*req.Name)
+	}
+	if len(update) == 0 {
+		return nil, ErrEmptyUpdate
+	}
+
+	u, err := s.repo.Update(ctx, id, update)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, ErrUserNotFound
+	}
+	return u, nil
+}
+
+// GetByEmail returns the user with the given email address, or nil if none
+// exists.
+func (s *Service) GetByEmail(ctx context.Context, email string) (*User, error) {
+	u, err := s.repo.FindByEmail(ctx, strings.ToLower(strings.TrimSpace(email)))
+	if err != nil {
+		return nil, fmt.Errorf("user service get by email: %w", err)
+	}
+	return u, nil
+}
+
+// SetPassword replaces the user's password. Callers are responsible for
+// revoking existing sessions.
+func (s *Service) SetPassword(ctx context.Context, id primitive.ObjectID, plain string) (*User, error) {
+	hash, err := s.hasher.Hash(plain)
+	if err != nil {
+		return nil, fmt.Errorf("user service hash: %w", err)
+	}
+
+	u, err := s.repo.Update(ctx, id, bson.M{"password_hash": hash})
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, ErrUserNotFound
+	}
+
+	slog.Info("password changed", "id", u.ID.Hex())
+	return u, nil
+}
+
+// ExportUserData returns a user's profile for a data export, or nil if the
+// user does not exist.
+func (s *Service) ExportUserData(ctx context.Context, id primitive.ObjectID) (interface{}, error) {
+	u, err := s.GetByID(ctx, id)
+	if err != nil || u == nil {
+		return nil, err
+	}
+	return u.ToResponse(), nil
+}
+
+// EraseUserData anonymizes a user in place rather than deleting it, so
+// records elsewhere that point at the ID stay consistent. The account is
+// disabled and can no longer log in; its email address becomes free for a
+// new registration.
+func (s *Service) EraseUserData(ctx context.Context, id primitive.ObjectID) error {
+	u, err := s.repo.Update(ctx, id, bson.M{
+		"name":           ErasedName,
+		"email":          "erased-" + id.Hex() + "@users.invalid",
+		"password_hash":  "",
+		"role":           RoleUser,
+		"disabled":       true,
+		"email_verified": false,
+		"mfa":            MFA{},
+		"identities":     []Identity{},
+		"erased_at":      time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+	if u != nil {
+		slog.Info("user erased", "id", id.Hex())
+	}
+	return nil
+}
+
+// HashPassword hashes a plaintext password with the default parameters.
+// Exported for testing.
 func HashPassword(plain string) (string, error) {
-	h, err := bcrypt.GenerateFromPassword([]byte(plain), bcryptCost)
-	return string(h), err
+	return password.Default.Hash(plain)
 }
 
-// CheckPassword compares a hash with a plaintext password. Exported for testing.
+// CheckPassword compares a hash made with any supported algorithm with a
+// plaintext password.
 func CheckPassword(hash, plain string) bool {
-	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(plain)) == nil
+	ok, _ := password.Default.Verify(hash, plain)
+	return ok
 }
 
+// ErrUserNotFound indicates the user does not exist.
+var ErrUserNotFound = fmt.Errorf("user not found")
+
+// ErrEmptyUpdate indicates an update request without any fields set.
+var ErrEmptyUpdate = fmt.Errorf("no fields to update")
+
+// ErrAccountDisabled indicates the account was disabled by an administrator.
+var ErrAccountDisabled = fmt.Errorf("account disabled")
+
+// ErrUnknownRole indicates the role to assign does not exist.
+var ErrUnknownRole = fmt.Errorf("unknown role")
+
+// ErrSelfModification indicates an administrator tried to change their own role or status.
+var ErrSelfModification = fmt.Errorf("cannot change your own role or status")
+
+// ErrInsufficientAuthority indicates an administrator tried to manage a user,
+// or grant a role, with permissions they do not hold themselves.
+var ErrInsufficientAuthority = fmt.Errorf("insufficient authority over this user or role")
+
 // ErrInvalidCredentials indicates wrong email or password.
 var ErrInvalidCredentials = fmt.Errorf("invalid email or password")
+
+// ErrInvalidVerificationToken indicates the email verification token is unknown, used, or expired.
+var ErrInvalidVerificationToken = fmt.Errorf("invalid or expired verification token")
+
+// ErrEmailNotVerified indicates the action requires a verified email address.
+var ErrEmailNotVerified = fmt.Errorf("email address not verified")
+
+// ErrIdentityConflict indicates an external identity cannot be linked to the
+// local account with the same email address.
+var ErrIdentityConflict = fmt.Errorf("an account with this email already exists; sign in with a password to use it")
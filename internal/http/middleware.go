@@ -4,15 +4,20 @@ package http
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"log/slog"
+	"net/http"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson/primitive"
 
+	"github.com/one-backend-go/internal/config"
+	"github.com/one-backend-go/internal/domain/apikey"
 	"github.com/one-backend-go/internal/domain/auth"
+	"github.com/one-backend-go/internal/domain/role"
 	"github.com/one-backend-go/internal/domain/user"
+	"github.com/one-backend-go/internal/pkg/reqctx"
 	"github.com/one-backend-go/internal/pkg/resp"
 )
 
@@ -20,11 +25,21 @@ import (
 
 const (
 	// ContextKeyUserID is the gin context key storing the authenticated user's ID.
-	ContextKeyUserID = "user_id"
+	ContextKeyUserID = reqctx.UserIDKey
 	// ContextKeyEmail is the gin context key storing the authenticated user's email.
-	ContextKeyEmail = "user_email"
+	ContextKeyEmail = reqctx.EmailKey
 	// ContextKeyRole is the gin context key storing the authenticated user's role.
-	ContextKeyRole = "user_role"
+	ContextKeyRole = reqctx.RoleKey
+	// ContextKeyPermissions is the gin context key storing the caller's permissions.
+	ContextKeyPermissions = reqctx.PermissionsKey
+	// ContextKeyEmailVerified is the gin context key storing whether the caller's email is verified.
+	ContextKeyEmailVerified = reqctx.EmailVerifiedKey
+	// ContextKeyMFAPending is the gin context key storing whether the session still owes a second factor.
+	ContextKeyMFAPending = reqctx.MFAPendingKey
+	// ContextKeySessionID is the gin context key storing the caller's session ID.
+	ContextKeySessionID = reqctx.SessionIDKey
+	// ContextKeyAPIKeyID is the gin context key storing the caller's API key ID.
+	ContextKeyAPIKeyID = reqctx.APIKeyIDKey
 )
 
 // ── Request-ID middleware ──────────────────────────────────────────────────────
@@ -80,8 +95,14 @@ func Recovery() gin.HandlerFunc {
 // ── Auth middleware ────────────────────────────────────────────────────────────
 
 // AuthRequired returns middleware that validates a Bearer JWT token.
+// Requests already authenticated by APIKeyAuth pass through.
 func AuthRequired(jwtMgr *auth.JWTManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if c.GetString(ContextKeyAPIKeyID) != "" {
+			c.Next()
+			return
+		}
+
 		header := c.GetHeader("Authorization")
 		if header == "" {
 			resp.Unauthorized(c, "missing authorization header")
@@ -105,42 +126,181 @@ func AuthRequired(jwtMgr *auth.JWTManager) gin.HandlerFunc {
 
 		c.Set(ContextKeyUserID, claims.Subject)
 		c.Set(ContextKeyEmail, claims.Email)
+		c.Set(ContextKeyRole, claims.Role)
+		c.Set(ContextKeyPermissions, claims.Permissions)
+		c.Set(ContextKeyEmailVerified, claims.EmailVerified)
+		c.Set(ContextKeyMFAPending, claims.MFAPending)
+		c.Set(ContextKeySessionID, claims.SessionID)
+		c.Next()
+	}
+}
+
+// APIKeyAuth authenticates requests presenting an API key, either as
+// "Authorization: ApiKey <key>" or in the X-API-Key header, and grants the
+// key's scopes as permissions. Requests without a key are left for
+// AuthRequired, which must be placed AFTER it. Every keyed request is logged
+// with the key that made it.
+func APIKeyAuth(keys *apikey.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key, ok := apiKeyFromRequest(c)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		k, err := keys.Authenticate(c.Request.Context(), key, c.ClientIP())
+		if err != nil {
+			if errors.Is(err, apikey.ErrInvalidKey) {
+				slog.Warn("security event: invalid api key",
+					"ip", c.ClientIP(),
+					"path", c.Request.URL.Path,
+					"request_id", c.GetString("request_id"),
+				)
+				resp.Unauthorized(c, "invalid or expired api key")
+			} else {
+				resp.InternalError(c)
+			}
+			c.Abort()
+			return
+		}
+
+		// Keys act for no user: they have no email to verify and no second
+		// factor to owe.
+		c.Set(ContextKeyAPIKeyID, k.ID.Hex())
+		c.Set(ContextKeyPermissions, k.Scopes)
+		c.Set(ContextKeyEmailVerified, true)
+		c.Next()
+
+		slog.Info("api key request",
+			"key_id", k.ID.Hex(),
+			"key_name", k.Name,
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"status", c.Writer.Status(),
+			"ip", c.ClientIP(),
+			"request_id", c.GetString("request_id"),
+		)
+	}
+}
+
+// apiKeyFromRequest returns the API key presented with the request, if any.
+func apiKeyFromRequest(c *gin.Context) (string, bool) {
+	if key := c.GetHeader("X-API-Key"); key != "" {
+		return key, true
+	}
+	parts := strings.SplitN(c.GetHeader("Authorization"), " ", 2)
+	if len(parts) == 2 && strings.EqualFold(parts[0], "ApiKey") && parts[1] != "" {
+		return parts[1], true
+	}
+	return "", false
+}
+
+// VerifiedEmailRequired refuses callers whose email is not verified, unless
+// EMAIL_VERIFICATION is "off". The router guards catalog management and admin
+// routes with it.
+// Must be placed AFTER AuthRequired.
+func VerifiedEmailRequired(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if cfg.EmailVerification == config.EmailVerificationOff {
+			c.Next()
+			return
+		}
+
+		if !reqctx.EmailVerified(c) {
+			resp.Fail(c, http.StatusForbidden, "EMAIL_NOT_VERIFIED", "verify your email address to continue", nil)
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
 }
 
-// AdminRequired ensures the authenticated user has the admin role.
-// Must be placed AFTER AuthRequired in the middleware chain.
-func AdminRequired(userRepo *user.Repository) gin.HandlerFunc {
+// RequirePermission ensures the caller was granted perm, trusting the
+// permissions embedded in the access token or granted to the API key. Must be
+// placed AFTER AuthRequired.
+func RequirePermission(perm string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		userIDStr, exists := c.Get(ContextKeyUserID)
-		if !exists {
+		if c.GetString(ContextKeyUserID) == "" && c.GetString(ContextKeyAPIKeyID) == "" {
 			resp.Unauthorized(c, "authentication required")
 			c.Abort()
 			return
 		}
 
-		uid, err := primitive.ObjectIDFromHex(userIDStr.(string))
-		if err != nil {
-			resp.Unauthorized(c, "invalid user id in token")
+		if reqctx.MFAPending(c) {
+			failMFAPending(c)
+			return
+		}
+
+		if !role.HasPermission(reqctx.Permissions(c), perm) {
+			resp.Forbidden(c, "missing permission: "+perm)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequirePermissionStrict is RequirePermission for sensitive routes: it
+// re-resolves the user's role and permissions from the database instead of
+// trusting the token, so a demotion takes effect before the access token
+// expires. Must be placed AFTER AuthRequired.
+func RequirePermissionStrict(userRepo *user.Repository, roleSvc *role.Service, perm string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// APIKeyAuth read the key's scopes from the database on this request.
+		if _, ok := reqctx.APIKeyID(c); ok {
+			if !role.HasPermission(reqctx.Permissions(c), perm) {
+				resp.Forbidden(c, "missing permission: "+perm)
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+
+		uid, ok := reqctx.UserID(c)
+		if !ok {
+			resp.Unauthorized(c, "authentication required")
 			c.Abort()
 			return
 		}
 
+		// The database is not consulted for this: whether the session
+		// passed a second factor is only recorded in the token.
+		if reqctx.MFAPending(c) {
+			failMFAPending(c)
+			return
+		}
+
 		u, err := userRepo.FindByID(c.Request.Context(), uid)
-		if err != nil || u == nil {
+		if err != nil || u == nil || u.Disabled {
 			resp.Unauthorized(c, "user not found")
 			c.Abort()
 			return
 		}
 
-		if u.Role != user.RoleAdmin {
-			resp.Forbidden(c, "admin access required")
+		perms, err := roleSvc.Permissions(c.Request.Context(), u.Role)
+		if err != nil {
+			resp.InternalError(c)
+			c.Abort()
+			return
+		}
+
+		if !role.HasPermission(perms, perm) {
+			resp.Forbidden(c, "missing permission: "+perm)
 			c.Abort()
 			return
 		}
 
 		c.Set(ContextKeyRole, u.Role)
+		c.Set(ContextKeyPermissions, perms)
 		c.Next()
 	}
 }
+
+// failMFAPending refuses a session that has not completed the two-factor
+// authentication its role requires.
+func failMFAPending(c *gin.Context) {
+	resp.Fail(c, http.StatusForbidden, "MFA_REQUIRED", "two-factor authentication is required for your role; enroll and log in again", nil)
+	c.Abort()
+}
@@ -5,8 +5,15 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"github.com/one-backend-go/internal/config"
+	"github.com/one-backend-go/internal/domain/apikey"
 	"github.com/one-backend-go/internal/domain/auth"
+	"github.com/one-backend-go/internal/domain/category"
+	"github.com/one-backend-go/internal/domain/inventory"
+	"github.com/one-backend-go/internal/domain/modifier"
+	"github.com/one-backend-go/internal/domain/privacy"
 	"github.com/one-backend-go/internal/domain/product"
+	"github.com/one-backend-go/internal/domain/role"
+	"github.com/one-backend-go/internal/domain/sso"
 	"github.com/one-backend-go/internal/domain/user"
 )
 
@@ -15,9 +22,18 @@ func NewRouter(
 	cfg *config.Config,
 	jwtMgr *auth.JWTManager,
 	userRepo *user.Repository,
+	roleSvc *role.Service,
+	apiKeySvc *apikey.Service,
 	userHandler *user.Handler,
 	authHandler *auth.Handler,
 	productHandler *product.Handler,
+	roleHandler *role.Handler,
+	privacyHandler *privacy.Handler,
+	apiKeyHandler *apikey.Handler,
+	ssoHandler *sso.Handler,
+	categoryHandler *category.Handler,
+	modifierHandler *modifier.Handler,
+	inventoryHandler *inventory.Handler,
 ) *gin.Engine {
 	gin.SetMode(gin.ReleaseMode)
 
@@ -31,8 +47,8 @@ func NewRouter(
 	// ── CORS ───────────────────────────────────────────────────────────
 	corsConfig := cors.Config{
 		AllowOrigins:     cfg.CORSAllowedOrigins,
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-Request-ID"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-API-Key", "X-Request-ID"},
 		ExposeHeaders:    []string{"X-Request-ID"},
 		AllowCredentials: true,
 	}
@@ -43,6 +59,13 @@ func NewRouter(
 		c.JSON(200, gin.H{"status": "ok"})
 	})
 
+	// ── Public signing keys ────────────────────────────────────────────
+	r.GET("/.well-known/jwks.json", authHandler.JWKS)
+
+	// Unverified users may sign in and manage their own account, but not
+	// act on the catalog or other users when EMAIL_VERIFICATION=actions.
+	verified := VerifiedEmailRequired(cfg)
+
 	// ── API v1 ─────────────────────────────────────────────────────────
 	v1 := r.Group("/api/v1")
 	{
@@ -51,7 +74,37 @@ func NewRouter(
 		{
 			authGroup.POST("/register", userHandler.Register)
 			authGroup.POST("/login", authHandler.Login)
+			authGroup.POST("/login/mfa", authHandler.LoginMFA)
 			authGroup.POST("/refresh", authHandler.Refresh)
+			authGroup.POST("/logout", authHandler.Logout)
+			authGroup.POST("/logout-all", AuthRequired(jwtMgr), authHandler.LogoutAll)
+			authGroup.POST("/password/forgot", authHandler.ForgotPassword)
+			authGroup.POST("/password/reset", authHandler.ResetPassword)
+			authGroup.POST("/verify-email", userHandler.VerifyEmail)
+			authGroup.POST("/verify-email/resend", userHandler.ResendVerification)
+			authGroup.GET("/oidc/providers", ssoHandler.Providers)
+			authGroup.POST("/oidc/:provider/start", ssoHandler.Start)
+			authGroup.POST("/oidc/:provider/callback", ssoHandler.Callback)
+		}
+
+		// Two-factor enrollment (authenticated)
+		mfaGroup := v1.Group("/auth/mfa")
+		mfaGroup.Use(AuthRequired(jwtMgr))
+		{
+			mfaGroup.POST("/enroll", userHandler.EnrollMFA)
+			mfaGroup.POST("/confirm", userHandler.ConfirmMFA)
+			mfaGroup.POST("/disable", userHandler.DisableMFA)
+		}
+
+		// Self-service account routes
+		meGroup := v1.Group("/users/me")
+		meGroup.Use(AuthRequired(jwtMgr))
+		{
+			meGroup.GET("", userHandler.Me)
+			meGroup.PATCH("", userHandler.UpdateMe)
+			meGroup.DELETE("", privacyHandler.EraseMe)
+			meGroup.POST("/password", authHandler.ChangePassword)
+			meGroup.GET("/export", privacyHandler.ExportMe)
 		}
 
 		// Product routes
@@ -59,14 +112,104 @@ func NewRouter(
 		{
 			// Public
 			productsGroup.GET("", productHandler.List)
+			productsGroup.GET("/:id", productHandler.Get)
+			productsGroup.GET("/by-slug/:slug", productHandler.GetBySlug)
+			productsGroup.POST("/:id/price", productHandler.Price)
+
+			// Catalog management, also open to API keys
+			manage := productsGroup.Group("")
+			manage.Use(APIKeyAuth(apiKeySvc), AuthRequired(jwtMgr), verified)
+			{
+				manage.POST("", RequirePermission(role.PermProductsWrite), productHandler.Create)
+				manage.PUT("/:id", RequirePermission(role.PermProductsWrite), productHandler.Update)
+				manage.DELETE("/:id", RequirePermissionStrict(userRepo, roleSvc, role.PermProductsWrite), productHandler.Delete)
+			}
+		}
+
+		// Category routes
+		categoriesGroup := v1.Group("/categories")
+		{
+			// Public
+			categoriesGroup.GET("", categoryHandler.Tree)
+			categoriesGroup.GET("/:id", categoryHandler.Get)
+
+			// Categories are part of the catalog and share its permission
+			manage := categoriesGroup.Group("")
+			manage.Use(APIKeyAuth(apiKeySvc), AuthRequired(jwtMgr), verified)
+			{
+				manage.POST("", RequirePermission(role.PermProductsWrite), categoryHandler.Create)
+				manage.PUT("/:id", RequirePermission(role.PermProductsWrite), categoryHandler.Update)
+				manage.DELETE("/:id", RequirePermissionStrict(userRepo, roleSvc, role.PermProductsWrite), categoryHandler.Delete)
+			}
+		}
+
+		// Modifier group routes
+		modifiersGroup := v1.Group("/modifier-groups")
+		{
+			// Public
+			modifiersGroup.GET("", modifierHandler.List)
+			modifiersGroup.GET("/:id", modifierHandler.Get)
+
+			// Modifier groups are part of the catalog and share its permission
+			manage := modifiersGroup.Group("")
+			manage.Use(APIKeyAuth(apiKeySvc), AuthRequired(jwtMgr), verified)
+			{
+				manage.POST("", RequirePermission(role.PermProductsWrite), modifierHandler.Create)
+				manage.PUT("/:id", RequirePermission(role.PermProductsWrite), modifierHandler.Update)
+				manage.DELETE("/:id", RequirePermissionStrict(userRepo, roleSvc, role.PermProductsWrite), modifierHandler.Delete)
+			}
+		}
+
+		// Stock management, also open to API keys
+		stockGroup := v1.Group("/admin/products/:id/stock")
+		stockGroup.Use(APIKeyAuth(apiKeySvc), AuthRequired(jwtMgr), verified, RequirePermission(role.PermInventoryManage))
+		{
+			stockGroup.GET("", inventoryHandler.Levels)
+			stockGroup.POST("/adjustments", inventoryHandler.Adjust)
+			stockGroup.PUT("/threshold", inventoryHandler.SetThreshold)
+			stockGroup.GET("/ledger", inventoryHandler.Ledger)
+		}
+
+		// Admin routes
+		adminGroup := v1.Group("/admin")
+		adminGroup.Use(AuthRequired(jwtMgr), verified)
+		{
+			rolesGroup := adminGroup.Group("/roles")
+			{
+				rolesGroup.GET("", RequirePermission(role.PermRolesManage), roleHandler.List)
+				rolesGroup.GET("/:name", RequirePermission(role.PermRolesManage), roleHandler.Get)
+
+				strict := RequirePermissionStrict(userRepo, roleSvc, role.PermRolesManage)
+				rolesGroup.POST("", strict, roleHandler.Create)
+				rolesGroup.PUT("/:name", strict, roleHandler.Update)
+				rolesGroup.DELETE("/:name", strict, roleHandler.Delete)
+			}
+
+			usersGroup := adminGroup.Group("/users")
+			usersGroup.Use(RequirePermission(role.PermUsersManage))
+			{
+				usersGroup.GET("", userHandler.List)
+				usersGroup.GET("/:id", userHandler.Get)
+
+				strict := RequirePermissionStrict(userRepo, roleSvc, role.PermUsersManage)
+				usersGroup.POST("/:id/unlock", strict, authHandler.UnlockUser)
+				usersGroup.POST("/:id/logout", strict, authHandler.ForceLogout)
+				usersGroup.PUT("/:id/role", strict, userHandler.SetRole)
+				usersGroup.POST("/:id/disable", strict, userHandler.Disable)
+				usersGroup.POST("/:id/enable", strict, userHandler.Enable)
+				usersGroup.GET("/:id/export", strict, privacyHandler.Export)
+				usersGroup.DELETE("/:id", strict, privacyHandler.Erase)
+			}
 
-			// Admin-only
-			admin := productsGroup.Group("")
-			admin.Use(AuthRequired(jwtMgr), AdminRequired(userRepo))
+			adminGroup.GET("/categories", RequirePermission(role.PermProductsWrite), categoryHandler.AdminTree)
+
+			keysGroup := adminGroup.Group("/api-keys")
 			{
-				admin.POST("", productHandler.Create)
-				admin.PUT("/:id", productHandler.Update)
-				admin.DELETE("/:id", productHandler.Delete)
+				keysGroup.GET("", RequirePermission(role.PermAPIKeysManage), apiKeyHandler.List)
+
+				strict := RequirePermissionStrict(userRepo, roleSvc, role.PermAPIKeysManage)
+				keysGroup.POST("", strict, apiKeyHandler.Create)
+				keysGroup.DELETE("/:id", strict, apiKeyHandler.Revoke)
 			}
 		}
 	}
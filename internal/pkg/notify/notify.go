@@ -0,0 +1,218 @@
+// Package notify delivers user-facing messages such as password reset and
+// email verification links. Implementations are pluggable; the webhook
+// notifier delivers them, while the log and file notifiers are meant for
+// development and offline testing.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message is a notification addressed to a single user.
+type Message struct {
+	To      string            `json:"to"`
+	Kind    string            `json:"kind"` // e.g. "password_reset"
+	Subject string            `json:"subject"`
+	Body    string            `json:"body"`
+	Data    map[string]string `json:"data,omitempty"` // machine-readable values, e.g. "token"
+	SentAt  time.Time         `json:"sent_at"`
+}
+
+// Notifier sends messages to users.
+type Notifier interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Options configures the notifier New creates.
+type Options struct {
+	FilePath     string // where the file notifier writes
+	WebhookURL   string // where the webhook notifier posts
+	WebhookToken string // bearer token for the webhook, if set
+}
+
+// New returns the notifier selected by kind: "log", "file" or "webhook".
+func New(kind string, opts Options) (Notifier, error) {
+	switch kind {
+	case "", "log":
+		return NewLogNotifier(), nil
+	case "file":
+		return NewFileNotifier(opts.FilePath), nil
+	case "webhook":
+		if opts.WebhookURL == "" {
+			return nil, fmt.Errorf("notify: webhook notifier needs a URL")
+		}
+		return NewWebhookNotifier(opts.WebhookURL, opts.WebhookToken), nil
+	default:
+		return nil, fmt.Errorf("notify: unknown notifier %q", kind)
+	}
+}
+
+// ── Log notifier ───────────────────────────────────────────────────────────────
+
+// LogNotifier writes messages to the structured log at debug level. The
+// values in Data, such as reset tokens, are redacted from the body so that
+// logs never hold a usable secret; use the file notifier to read tokens in
+// development.
+type LogNotifier struct{}
+
+// NewLogNotifier creates a LogNotifier.
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+// Send logs the message without its secrets.
+func (n *LogNotifier) Send(_ context.Context, msg Message) error {
+	slog.Debug("notification",
+		"to", msg.To,
+		"kind", msg.Kind,
+		"subject", msg.Subject,
+		"body", redactedBody(msg),
+	)
+	return nil
+}
+
+// redactedBody returns the message body with every Data value replaced.
+func redactedBody(msg Message) string {
+	body := msg.Body
+	for _, v := range msg.Data {
+		if v != "" {
+			body = strings.ReplaceAll(body, v, "[redacted]")
+		}
+	}
+	return body
+}
+
+// ── File notifier ──────────────────────────────────────────────────────────────
+
+// FileNotifier appends each message as a JSON line to a file.
+type FileNotifier struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileNotifier creates a FileNotifier writing to path.
+func NewFileNotifier(path string) *FileNotifier {
+	return &FileNotifier{path: path}
+}
+
+// Send appends the message to the file.
+func (n *FileNotifier) Send(_ context.Context, msg Message) error {
+	if msg.SentAt.IsZero() {
+		msg.SentAt = time.Now().UTC()
+	}
+	line, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("notify file marshal: %w", err)
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	f, err := os.OpenFile(n.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("notify file open: %w", err)
+	}
+	defer f.Close()
+
+	if _, err = f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("notify file write: %w", err)
+	}
+	return nil
+}
+
+// ── Webhook notifier ───────────────────────────────────────────────────────────
+
+// WebhookNotifier posts each message as JSON to a URL, typically a mail or
+// messaging relay that delivers it to the user. Any status other than 2xx is
+// an error.
+type WebhookNotifier struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// NewWebhookNotifier creates a WebhookNotifier posting to url. A non-empty
+// token is sent as a bearer token.
+func NewWebhookNotifier(url, token string) *WebhookNotifier {
+	return &WebhookNotifier{url: url, token: token, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Send posts the message to the webhook.
+func (n *WebhookNotifier) Send(ctx context.Context, msg Message) error {
+	if msg.SentAt.IsZero() {
+		msg.SentAt = time.Now().UTC()
+	}
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("notify webhook marshal: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("notify webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.token != "" {
+		req.Header.Set("Authorization", "Bearer "+n.token)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("notify webhook post: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("notify webhook post: status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// ── Memory notifier ────────────────────────────────────────────────────────────
+
+// MemoryNotifier keeps messages in memory so tests can inspect them.
+type MemoryNotifier struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+// NewMemoryNotifier creates an empty MemoryNotifier.
+func NewMemoryNotifier() *MemoryNotifier {
+	return &MemoryNotifier{}
+}
+
+// Send records the message.
+func (n *MemoryNotifier) Send(_ context.Context, msg Message) error {
+	if msg.SentAt.IsZero() {
+		msg.SentAt = time.Now().UTC()
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.messages = append(n.messages, msg)
+	return nil
+}
+
+// Last returns the most recent message of the given kind sent to the address.
+func (n *MemoryNotifier) Last(to, kind string) (Message, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for i := len(n.messages) - 1; i >= 0; i-- {
+		if m := n.messages[i]; m.To == to && m.Kind == kind {
+			return m, true
+		}
+	}
+	return Message{}, false
+}
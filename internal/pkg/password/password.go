@@ -0,0 +1,180 @@
+// Package password hashes and verifies user passwords with bcrypt or
+// Argon2id. Hashes are stored in PHC string format, so each one records the
+// algorithm and parameters it was made with:
+//
+//	$argon2id$v=19$m=19456,t=2,p=1$<salt>$<hash>
+//	$bcrypt$r=12$<salt>$<hash>
+//
+// Plain bcrypt hashes ($2a$...) from before this format was adopted still
+// verify, and are reported as needing a rehash.
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/one-backend-go/internal/config"
+)
+
+// Supported algorithms.
+const (
+	Bcrypt   = "bcrypt"
+	Argon2id = "argon2id"
+)
+
+const (
+	argon2SaltLen = 16
+	argon2KeyLen  = 32
+	bcryptSaltLen = 22 // characters of bcrypt's own base64
+)
+
+// Params selects the algorithm used for new hashes and its cost.
+type Params struct {
+	Algorithm     string
+	BcryptCost    int
+	Argon2Memory  uint32 // KiB
+	Argon2Time    uint32 // iterations
+	Argon2Threads uint8
+}
+
+// DefaultParams returns Argon2id with the parameters recommended by OWASP.
+func DefaultParams() Params {
+	return Params{
+		Algorithm:     Argon2id,
+		BcryptCost:    12,
+		Argon2Memory:  19 * 1024,
+		Argon2Time:    2,
+		Argon2Threads: 1,
+	}
+}
+
+// Hasher hashes new passwords with its Params and verifies hashes made with
+// any supported algorithm and parameters.
+type Hasher struct {
+	p Params
+}
+
+// New returns a Hasher, failing if the parameters are unusable.
+func New(p Params) (*Hasher, error) {
+	switch p.Algorithm {
+	case Bcrypt:
+		if p.BcryptCost < bcrypt.MinCost || p.BcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("password: bcrypt cost %d out of range %d-%d", p.BcryptCost, bcrypt.MinCost, bcrypt.MaxCost)
+		}
+	case Argon2id:
+		if p.Argon2Memory < 8*uint32(p.Argon2Threads) || p.Argon2Time < 1 || p.Argon2Threads < 1 {
+			return nil, fmt.Errorf("password: invalid argon2id parameters m=%d,t=%d,p=%d", p.Argon2Memory, p.Argon2Time, p.Argon2Threads)
+		}
+	default:
+		return nil, fmt.Errorf("password: unknown algorithm %q (want bcrypt or argon2id)", p.Algorithm)
+	}
+	return &Hasher{p: p}, nil
+}
+
+// NewFromConfig builds the Hasher described by the PASSWORD_* settings.
+func NewFromConfig(cfg *config.Config) (*Hasher, error) {
+	if cfg.PasswordArgon2Memory < 0 || cfg.PasswordArgon2Memory > math.MaxUint32 ||
+		cfg.PasswordArgon2Time < 0 || cfg.PasswordArgon2Time > math.MaxUint32 ||
+		cfg.PasswordArgon2Threads < 0 || cfg.PasswordArgon2Threads > math.MaxUint8 {
+		return nil, fmt.Errorf("password: argon2id parameters out of range")
+	}
+	return New(Params{
+		Algorithm:     cfg.PasswordHashAlgorithm,
+		BcryptCost:    cfg.PasswordBcryptCost,
+		Argon2Memory:  uint32(cfg.PasswordArgon2Memory),
+		Argon2Time:    uint32(cfg.PasswordArgon2Time),
+		Argon2Threads: uint8(cfg.PasswordArgon2Threads),
+	})
+}
+
+// Default is a Hasher with DefaultParams.
+var Default = &Hasher{p: DefaultParams()}
+
+// Hash returns the PHC-encoded hash of plain.
+func (h *Hasher) Hash(plain string) (string, error) {
+	if h.p.Algorithm == Bcrypt {
+		mcf, err := bcrypt.GenerateFromPassword([]byte(plain), h.p.BcryptCost)
+		if err != nil {
+			return "", fmt.Errorf("password: bcrypt: %w", err)
+		}
+		// $2a$12$<22 salt><31 hash>
+		rest := string(mcf[7:])
+		return fmt.Sprintf("$bcrypt$r=%d$%s$%s", h.p.BcryptCost, rest[:bcryptSaltLen], rest[bcryptSaltLen:]), nil
+	}
+
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("password: salt: %w", err)
+	}
+	key := argon2.IDKey([]byte(plain), salt, h.p.Argon2Time, h.p.Argon2Memory, h.p.Argon2Threads, argon2KeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.p.Argon2Memory, h.p.Argon2Time, h.p.Argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify reports whether plain matches the encoded hash, and if so whether
+// the hash should be replaced because it was made with a different algorithm
+// or parameters than the Hasher's. Malformed hashes never match.
+func (h *Hasher) Verify(encoded, plain string) (ok, rehash bool) {
+	switch {
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		var v int
+		var m, t uint32
+		var p uint8
+		fields := strings.Split(encoded, "$")
+		if len(fields) != 6 {
+			return false, false
+		}
+		if _, err := fmt.Sscanf(fields[2], "v=%d", &v); err != nil || v != argon2.Version {
+			return false, false
+		}
+		if _, err := fmt.Sscanf(fields[3], "m=%d,t=%d,p=%d", &m, &t, &p); err != nil || t < 1 || p < 1 {
+			return false, false
+		}
+		salt, err := base64.RawStdEncoding.DecodeString(fields[4])
+		if err != nil {
+			return false, false
+		}
+		want, err := base64.RawStdEncoding.DecodeString(fields[5])
+		if err != nil || len(want) == 0 {
+			return false, false
+		}
+		got := argon2.IDKey([]byte(plain), salt, t, m, p, uint32(len(want)))
+		if subtle.ConstantTimeCompare(got, want) != 1 {
+			return false, false
+		}
+		stale := h.p.Algorithm != Argon2id || m != h.p.Argon2Memory || t != h.p.Argon2Time ||
+			p != h.p.Argon2Threads || len(want) != argon2KeyLen
+		return true, stale
+
+	case strings.HasPrefix(encoded, "$bcrypt$"):
+		var cost int
+		fields := strings.Split(encoded, "$")
+		if len(fields) != 5 {
+			return false, false
+		}
+		if _, err := fmt.Sscanf(fields[2], "r=%d", &cost); err != nil {
+			return false, false
+		}
+		mcf := fmt.Sprintf("$2a$%02d$%s%s", cost, fields[3], fields[4])
+		if bcrypt.CompareHashAndPassword([]byte(mcf), []byte(plain)) != nil {
+			return false, false
+		}
+		return true, h.p.Algorithm != Bcrypt || cost != h.p.BcryptCost
+
+	case strings.HasPrefix(encoded, "$2"):
+		// Plain bcrypt from before hashes were PHC-encoded.
+		if bcrypt.CompareHashAndPassword([]byte(encoded), []byte(plain)) != nil {
+			return false, false
+		}
+		return true, true
+	}
+	return false, false
+}
@@ -0,0 +1,277 @@
+package password
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/one-backend-go/internal/config"
+)
+
+// Character classes a Policy can require.
+const (
+	ClassLower  = "lower"
+	ClassUpper  = "upper"
+	ClassLetter = "letter"
+	ClassDigit  = "digit"
+	ClassSymbol = "symbol"
+)
+
+// Blocklist sources for PASSWORD_BLOCKLIST besides a file path.
+const (
+	BlocklistBuiltin = "builtin"
+	BlocklistNone    = "none"
+)
+
+// commonPasswords is a gzipped, newline-separated list of the most widely
+// used passwords from public breach corpora, lowercased.
+//
+//go:embed common-passwords.txt.gz
+var commonPasswords []byte
+
+var (
+	builtinOnce sync.Once
+	builtinList map[string]struct{}
+	builtinErr  error
+)
+
+// Policy decides which passwords users may choose.
+type Policy struct {
+	MinLength       int      // in characters
+	MaxLength       int      // in bytes, as bcrypt ignores anything past 72
+	RequiredClasses []string // any of the Class* constants
+	MaxRepeat       int      // longest run of one character; 0 allows any
+
+	blocklist map[string]struct{}
+}
+
+// DefaultPolicy requires 8 to 72 characters with a letter and a digit, no
+// character repeated more than 3 times in a row, and nothing on the built-in
+// list of common passwords.
+func DefaultPolicy() *Policy {
+	list, err := builtinBlocklist()
+	if err != nil {
+		// The list is compiled into the binary, so this is a build problem.
+		panic(err)
+	}
+	return &Policy{
+		MinLength:       8,
+		MaxLength:       72,
+		RequiredClasses: []string{ClassLetter, ClassDigit},
+		MaxRepeat:       3,
+		blocklist:       list,
+	}
+}
+
+// NewPolicyFromConfig builds the Policy described by the PASSWORD_* settings.
+func NewPolicyFromConfig(cfg *config.Config) (*Policy, error) {
+	p := &Policy{
+		MinLength:       cfg.PasswordMinLength,
+		MaxLength:       cfg.PasswordMaxLength,
+		RequiredClasses: cfg.PasswordRequiredClasses,
+		MaxRepeat:       cfg.PasswordMaxRepeat,
+	}
+	if p.MinLength < 1 || p.MaxLength < p.MinLength {
+		return nil, fmt.Errorf("password: invalid length limits %d-%d", p.MinLength, p.MaxLength)
+	}
+	if p.MaxRepeat < 0 {
+		return nil, fmt.Errorf("password: invalid max repeat %d", p.MaxRepeat)
+	}
+	for _, class := range p.RequiredClasses {
+		if classLabel(class) == "" {
+			return nil, fmt.Errorf("password: unknown character class %q (want lower, upper, letter, digit or symbol)", class)
+		}
+	}
+
+	var err error
+	switch cfg.PasswordBlocklist {
+	case BlocklistNone:
+	case BlocklistBuiltin, "":
+		p.blocklist, err = builtinBlocklist()
+	default:
+		p.blocklist, err = loadBlocklist(cfg.PasswordBlocklist)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// loadBlocklist reads a list of forbidden passwords, one per line, from a
+// plain or gzipped (.gz) file. Blank lines and lines starting with # are
+// skipped; entries are matched case-insensitively.
+func loadBlocklist(path string) (map[string]struct{}, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("password: open blocklist: %w", err)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, fmt.Errorf("password: read blocklist %s: %w", path, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+	list, err := parseBlocklist(r)
+	if err != nil {
+		return nil, fmt.Errorf("password: read blocklist %s: %w", path, err)
+	}
+	return list, nil
+}
+
+// Check returns every rule the candidate breaks, as messages suitable for
+// showing to the user. An empty result means the password is acceptable.
+func (p *Policy) Check(candidate string) []string {
+	var problems []string
+
+	if n := utf8.RuneCountInString(candidate); n < p.MinLength {
+		problems = append(problems, fmt.Sprintf("must be at least %d characters", p.MinLength))
+	}
+	if p.MaxLength > 0 && len(candidate) > p.MaxLength {
+		problems = append(problems, fmt.Sprintf("must be at most %d bytes", p.MaxLength))
+	}
+
+	for _, class := range p.RequiredClasses {
+		if !hasClass(candidate, class) {
+			problems = append(problems, "must contain "+classLabel(class))
+		}
+	}
+
+	if p.MaxRepeat > 0 && longestRun(candidate) > p.MaxRepeat {
+		problems = append(problems, fmt.Sprintf("must not repeat a character more than %d times in a row", p.MaxRepeat))
+	}
+
+	if p.isCommon(candidate) {
+		problems = append(problems, "is too common and easy to guess")
+	}
+	return problems
+}
+
+// isCommon reports whether the candidate, or its letters with any digits or
+// symbols around them stripped ("Monkey123!" becomes "monkey"), is on the
+// blocklist.
+func (p *Policy) isCommon(candidate string) bool {
+	if len(p.blocklist) == 0 {
+		return false
+	}
+	lower := strings.ToLower(candidate)
+	if _, ok := p.blocklist[lower]; ok {
+		return true
+	}
+	base := strings.TrimFunc(lower, func(r rune) bool { return !unicode.IsLetter(r) })
+	if utf8.RuneCountInString(base) < 4 || base == lower {
+		return false
+	}
+	_, ok := p.blocklist[base]
+	return ok
+}
+
+// hasClass reports whether s contains a character of the given class.
+func hasClass(s, class string) bool {
+	for _, r := range s {
+		switch class {
+		case ClassLower:
+			if unicode.IsLower(r) {
+				return true
+			}
+		case ClassUpper:
+			if unicode.IsUpper(r) {
+				return true
+			}
+		case ClassLetter:
+			if unicode.IsLetter(r) {
+				return true
+			}
+		case ClassDigit:
+			if unicode.IsDigit(r) {
+				return true
+			}
+		case ClassSymbol:
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// classLabel describes a character class in messages, or returns "" for an
+// unknown class.
+func classLabel(class string) string {
+	switch class {
+	case ClassLower:
+		return "a lowercase letter"
+	case ClassUpper:
+		return "an uppercase letter"
+	case ClassLetter:
+		return "a letter"
+	case ClassDigit:
+		return "a digit"
+	case ClassSymbol:
+		return "a symbol"
+	}
+	return ""
+}
+
+// longestRun returns the length of the longest run of one repeated character.
+func longestRun(s string) int {
+	longest, run := 0, 0
+	var prev rune
+	for i, r := range s {
+		if i > 0 && r == prev {
+			run++
+		} else {
+			run = 1
+		}
+		prev = r
+		if run > longest {
+			longest = run
+		}
+	}
+	return longest
+}
+
+// builtinBlocklist decompresses the embedded list once.
+func builtinBlocklist() (map[string]struct{}, error) {
+	builtinOnce.Do(func() {
+		gz, err := gzip.NewReader(bytes.NewReader(commonPasswords))
+		if err != nil {
+			builtinErr = fmt.Errorf("password: read built-in blocklist: %w", err)
+			return
+		}
+		defer gz.Close()
+		builtinList, builtinErr = parseBlocklist(gz)
+		if builtinErr != nil {
+			builtinErr = fmt.Errorf("password: read built-in blocklist: %w", builtinErr)
+		}
+	})
+	return builtinList, builtinErr
+}
+
+func parseBlocklist(r io.Reader) (map[string]struct{}, error) {
+	list := make(map[string]struct{})
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		list[strings.ToLower(line)] = struct{}{}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
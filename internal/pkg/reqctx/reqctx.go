@@ -0,0 +1,77 @@
+// Package reqctx exposes request-scoped values placed on the gin context by
+// the HTTP middleware, so domain handlers can read them without importing
+// the http package.
+package reqctx
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// UserIDKey is the gin context key storing the authenticated user's ID.
+	UserIDKey = "user_id"
+	// EmailKey is the gin context key storing the authenticated user's email.
+	EmailKey = "user_email"
+	// RoleKey is the gin context key storing the authenticated user's role.
+	RoleKey = "user_role"
+	// PermissionsKey is the gin context key storing the caller's permissions.
+	PermissionsKey = "permissions"
+	// EmailVerifiedKey is the gin context key storing whether the caller's
+	// email address is verified.
+	EmailVerifiedKey = "email_verified"
+	// MFAPendingKey is the gin context key storing whether the caller's role
+	// requires a second factor the session has not passed.
+	MFAPendingKey = "mfa_pending"
+	// SessionIDKey is the gin context key storing the caller's session
+	// (refresh token family) ID.
+	SessionIDKey = "session_id"
+	// APIKeyIDKey is the gin context key storing the ID of the API key the
+	// caller authenticated with, if any.
+	APIKeyIDKey = "api_key_id"
+)
+
+// UserID returns the authenticated user's ID, or false if none is present.
+func UserID(c *gin.Context) (primitive.ObjectID, bool) {
+	id, err := primitive.ObjectIDFromHex(c.GetString(UserIDKey))
+	if err != nil {
+		return primitive.NilObjectID, false
+	}
+	return id, true
+}
+
+// SessionID returns the caller's session ID, or false for tokens issued
+// before sessions were tracked.
+func SessionID(c *gin.Context) (primitive.ObjectID, bool) {
+	id, err := primitive.ObjectIDFromHex(c.GetString(SessionIDKey))
+	if err != nil {
+		return primitive.NilObjectID, false
+	}
+	return id, true
+}
+
+// APIKeyID returns the ID of the API key the caller authenticated with, or
+// false if the caller is a user.
+func APIKeyID(c *gin.Context) (primitive.ObjectID, bool) {
+	id, err := primitive.ObjectIDFromHex(c.GetString(APIKeyIDKey))
+	if err != nil {
+		return primitive.NilObjectID, false
+	}
+	return id, true
+}
+
+// Permissions returns the permissions granted to the caller.
+func Permissions(c *gin.Context) []string {
+	return c.GetStringSlice(PermissionsKey)
+}
+
+// EmailVerified reports whether the caller's email address is verified.
+func EmailVerified(c *gin.Context) bool {
+	return c.GetBool(EmailVerifiedKey)
+}
+
+// MFAPending reports whether the caller must complete two-factor
+// authentication before being granted permissions.
+func MFAPending(c *gin.Context) bool {
+	return c.GetBool(MFAPendingKey)
+}
@@ -0,0 +1,64 @@
+// Package slug turns names into URL-friendly identifiers.
+package slug
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxLength is the longest slug Make returns.
+const MaxLength = 64
+
+// letters spells out characters that do not decompose into an ASCII letter
+// and a combining mark.
+var letters = strings.NewReplacer(
+	"ß", "ss", "æ", "ae", "œ", "oe", "ø", "o", "ł", "l", "đ", "d", "þ", "th", "&", " and ",
+)
+
+// Make returns the slug for a name: lowercase ASCII letters and digits, with
+// runs of anything else collapsed into single hyphens, e.g. "Crème Brûlée &
+// Co." becomes "creme-brulee-and-co". Characters without an ASCII spelling
+// are dropped, so the result may be empty.
+func Make(name string) string {
+	decomposed := norm.NFKD.String(letters.Replace(strings.ToLower(name)))
+
+	var b strings.Builder
+	pendingHyphen := false
+	for _, r := range decomposed {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			// Drop accents left over from decomposition.
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			if pendingHyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			pendingHyphen = false
+			b.WriteRune(r)
+		default:
+			pendingHyphen = true
+		}
+	}
+
+	s := b.String()
+	if len(s) > MaxLength {
+		s = s[:MaxLength]
+		if i := strings.LastIndexByte(s, '-'); i > MaxLength/2 {
+			s = s[:i]
+		}
+		s = strings.TrimSuffix(s, "-")
+	}
+	return s
+}
+
+// WithSuffix returns base with a numeric suffix, e.g. "pizza-2", shortening
+// base so the result stays within MaxLength.
+func WithSuffix(base string, n int) string {
+	suffix := "-" + strconv.Itoa(n)
+	if len(base)+len(suffix) > MaxLength {
+		base = strings.TrimSuffix(base[:MaxLength-len(suffix)], "-")
+	}
+	return base + suffix
+}
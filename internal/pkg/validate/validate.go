@@ -2,19 +2,30 @@
 package validate
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
 
 	"github.com/go-playground/validator/v10"
+
+	"github.com/one-backend-go/internal/pkg/password"
 )
 
 // Validator wraps the go-playground validator with custom registrations.
 type Validator struct {
-	v *validator.Validate
+	v      *validator.Validate
+	policy *password.Policy
 }
 
-// New creates a Validator with custom validations registered.
+// New creates a Validator with custom validations registered, checking
+// passwords against password.DefaultPolicy.
 func New() *Validator {
+	return NewWithPasswordPolicy(password.DefaultPolicy())
+}
+
+// NewWithPasswordPolicy creates a Validator whose strongpass rule enforces
+// the given password policy.
+func NewWithPasswordPolicy(policy *password.Policy) *Validator {
 	v := validator.New()
 
 	// name: letters and spaces, 2-50 chars
@@ -26,18 +37,25 @@ func New() *Validator {
 		return regexp.MustCompile(`^[a-zA-Z\s]+$`).MatchString(val)
 	})
 
-	// strongpass: min 8 chars, at least 1 letter and 1 number
+	// strongpass: satisfies the password policy
 	_ = v.RegisterValidation("strongpass", func(fl validator.FieldLevel) bool {
+		return len(policy.Check(fl.Field().String())) == 0
+	})
+
+	// slug: lowercase letters and digits, separated by single '-' or '_'
+	slugRe := regexp.MustCompile(`^[a-z0-9]+(?:[-_][a-z0-9]+)*$`)
+	_ = v.RegisterValidation("slug", func(fl validator.FieldLevel) bool {
+		return slugRe.MatchString(fl.Field().String())
+	})
+
+	// sku: letters and digits separated by single '-', '_' or '.', up to 64 chars
+	skuRe := regexp.MustCompile(`^[A-Za-z0-9]+(?:[-_.][A-Za-z0-9]+)*$`)
+	_ = v.RegisterValidation("sku", func(fl validator.FieldLevel) bool {
 		val := fl.Field().String()
-		if len(val) < 8 {
-			return false
-		}
-		hasLetter := regexp.MustCompile(`[a-zA-Z]`).MatchString(val)
-		hasDigit := regexp.MustCompile(`[0-9]`).MatchString(val)
-		return hasLetter && hasDigit
+		return len(val) <= 64 && skuRe.MatchString(val)
 	})
 
-	return &Validator{v: v}
+	return &Validator{v: v, policy: policy}
 }
 
 // Struct validates a struct and returns a map of field-level error messages.
@@ -58,7 +76,11 @@ func (va *Validator) Struct(s interface{}) map[string]string {
 		case "name":
 			errs[field] = "must be 2-50 characters, letters and spaces only"
 		case "strongpass":
-			errs[field] = "min 8 chars with at least 1 letter and 1 number"
+			errs[field] = strings.Join(va.policy.Check(fmt.Sprint(fe.Value())), "; ")
+		case "slug":
+			errs[field] = "must be lowercase letters and digits separated by '-' or '_'"
+		case "sku":
+			errs[field] = "must be up to 64 letters and digits separated by '-', '_' or '.'"
 		case "min":
 			errs[field] = field + " must be at least " + fe.Param() + " characters"
 		case "max":
@@ -67,6 +89,8 @@ func (va *Validator) Struct(s interface{}) map[string]string {
 			errs[field] = field + " must be >= " + fe.Param()
 		case "lte":
 			errs[field] = field + " must be <= " + fe.Param()
+		case "oneof":
+			errs[field] = field + " must be one of: " + strings.ReplaceAll(fe.Param(), " ", ", ")
 		default:
 			errs[field] = field + " is invalid"
 		}
@@ -9,25 +9,65 @@ package e2e
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/crypto/bcrypt"
 
 	"github.com/one-backend-go/internal/config"
 	"github.com/one-backend-go/internal/db"
+	"github.com/one-backend-go/internal/db/migrate"
+	"github.com/one-backend-go/internal/db/migrations"
+	"github.com/one-backend-go/internal/domain/actiontoken"
+	"github.com/one-backend-go/internal/domain/apikey"
 	"github.com/one-backend-go/internal/domain/auth"
+	"github.com/one-backend-go/internal/domain/category"
+	"github.com/one-backend-go/internal/domain/inventory"
+	"github.com/one-backend-go/internal/domain/modifier"
+	"github.com/one-backend-go/internal/domain/privacy"
 	"github.com/one-backend-go/internal/domain/product"
+	"github.com/one-backend-go/internal/domain/role"
+	"github.com/one-backend-go/internal/domain/sso"
 	"github.com/one-backend-go/internal/domain/user"
 	apphttp "github.com/one-backend-go/internal/http"
+	"github.com/one-backend-go/internal/pkg/notify"
+	"github.com/one-backend-go/internal/pkg/password"
+	"github.com/one-backend-go/internal/pkg/totp"
 	"github.com/one-backend-go/internal/pkg/validate"
 )
 
+// outbox captures the notifications sent by the most recently set up server.
+var outbox *notify.MemoryNotifier
+
+// testDB is the database behind the most recently set up server.
+var testDB *mongo.Database
+
 // setupRouter creates a test router backed by a real MongoDB.
 // It drops the test database before each call to guarantee isolation.
 func setupRouter(t *testing.T) *httptest.Server {
 	t.Helper()
+	return setupRouterWith(t, nil)
+}
+
+// setupRouterWith is setupRouter with a hook to adjust the loaded config.
+func setupRouterWith(t *testing.T, configure func(cfg *config.Config)) *httptest.Server {
+	t.Helper()
 
 	// Use test-specific env if not set
 	if os.Getenv("JWT_SECRET") == "" {
@@ -39,12 +79,19 @@ func setupRouter(t *testing.T) *httptest.Server {
 	if os.Getenv("MONGODB_DB") == "" {
 		os.Setenv("MONGODB_DB", "foodsvc_test")
 	}
+	if os.Getenv("APP_ENV") == "" {
+		os.Setenv("APP_ENV", "development")
+	}
 
 	cfg, err := config.Load()
 	if err != nil {
 		t.Fatalf("config load: %v", err)
 	}
 	cfg.MongoDB = "foodsvc_test" // force test db
+	cfg.LoginBaseDelay = 0       // retry failed logins without waiting
+	if configure != nil {
+		configure(cfg)
+	}
 
 	ctx := context.Background()
 	mongoDB, err := db.Connect(ctx, cfg.MongoURI, cfg.MongoDB)
@@ -59,25 +106,69 @@ func setupRouter(t *testing.T) *httptest.Server {
 	if err := db.EnsureIndexes(ctx, mongoDB); err != nil {
 		t.Fatalf("ensure indexes: %v", err)
 	}
+	if err := applyMigrations(ctx, mongoDB); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	testDB = mongoDB
 
-	v := validate.New()
+	passwordPolicy, err := password.NewPolicyFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("password policy: %v", err)
+	}
+	v := validate.NewWithPasswordPolicy(passwordPolicy)
 	userRepo := user.NewRepository(mongoDB)
 	authRepo := auth.NewRepository(mongoDB)
+	roleRepo := role.NewRepository(mongoDB)
 	productRepo := product.NewRepository(mongoDB)
+	categoryRepo := category.NewRepository(mongoDB)
+	modifierRepo := modifier.NewRepository(mongoDB)
+	inventoryRepo := inventory.NewRepository(mongoDB)
+	actionTokenRepo := actiontoken.NewRepository(mongoDB)
+	apiKeyRepo := apikey.NewRepository(mongoDB)
+	ssoRepo := sso.NewRepository(mongoDB)
+	outbox = notify.NewMemoryNotifier()
 
-	jwtMgr := auth.NewJWTManager(cfg.JWTSecret, cfg.AccessTokenTTL)
-	userSvc := user.NewService(userRepo)
-	authSvc := auth.NewService(cfg, jwtMgr, authRepo, userSvc)
-	productSvc := product.NewService(productRepo)
+	jwtMgr, err := auth.NewJWTManagerFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("jwt manager: %v", err)
+	}
+	hasher, err := password.NewFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("password hasher: %v", err)
+	}
+	actionTokenSvc := actiontoken.NewService(actionTokenRepo)
+	roleSvc := role.NewService(roleRepo, userRepo)
+	userSvc := user.NewService(cfg, userRepo, roleSvc, authRepo, actionTokenSvc, outbox, hasher)
+	authSvc := auth.NewService(cfg, jwtMgr, authRepo, userSvc, roleSvc, actionTokenSvc, outbox)
+	categorySvc := category.NewService(categoryRepo, productRepo)
+	modifierSvc := modifier.NewService(modifierRepo, productRepo)
+	productSvc := product.NewService(productRepo, categorySvc, modifierSvc, inventoryRepo)
+	inventorySvc := inventory.NewService(inventoryRepo, productRepo)
+	apiKeySvc := apikey.NewService(apiKeyRepo, userSvc)
+	ssoSvc := sso.NewService(cfg, ssoRepo, userSvc, authSvc)
+	privacySvc := privacy.NewService(userSvc, authSvc)
+	privacySvc.RegisterExporter("account", userSvc)
+	privacySvc.RegisterExporter("sessions", authSvc)
+	privacySvc.RegisterExporter("action_tokens", actionTokenSvc)
+	privacySvc.RegisterEraser("sessions", authSvc)
+	privacySvc.RegisterEraser("action_tokens", actionTokenSvc)
+	privacySvc.RegisterEraser("account", userSvc) // last: anonymizes the user
 
 	userHandler := user.NewHandler(userSvc, v)
 	authHandler := auth.NewHandler(authSvc, v)
 	productHandler := product.NewHandler(productSvc, v)
+	categoryHandler := category.NewHandler(categorySvc, v)
+	modifierHandler := modifier.NewHandler(modifierSvc, v)
+	inventoryHandler := inventory.NewHandler(inventorySvc, v)
+	roleHandler := role.NewHandler(roleSvc, v)
+	apiKeyHandler := apikey.NewHandler(apiKeySvc, v)
+	ssoHandler := sso.NewHandler(ssoSvc, v)
+	privacyHandler := privacy.NewHandler(privacySvc, v)
 
-	router := apphttp.NewRouter(cfg, jwtMgr, userRepo, userHandler, authHandler, productHandler)
+	router := apphttp.NewRouter(cfg, jwtMgr, userRepo, roleSvc, apiKeySvc, userHandler, authHandler, productHandler, roleHandler, privacyHandler, apiKeyHandler, ssoHandler, categoryHandler, modifierHandler, inventoryHandler)
 
 	// Seed some products
-	seedProducts(t, productRepo)
+	seedProducts(t, categoryRepo, productRepo)
 
 	ts := httptest.NewServer(router)
 	t.Cleanup(func() {
@@ -87,12 +178,30 @@ func setupRouter(t *testing.T) *httptest.Server {
 	return ts
 }
 
-func seedProducts(t *testing.T, repo *product.Repository) {
+// applyMigrations brings the test database up to date, as the server does on start.
+func applyMigrations(ctx context.Context, mongoDB *mongo.Database) error {
+	m, err := migrate.New(mongoDB, migrations.All())
+	if err != nil {
+		return err
+	}
+	_, err = m.Up(ctx, 0)
+	return err
+}
+
+func seedProducts(t *testing.T, categories *category.Repository, repo *product.Repository) {
 	t.Helper()
+	ids := map[string]primitive.ObjectID{}
+	for i, name := range []string{"Burgers", "Pizza", "Salads"} {
+		c := &category.Category{Name: name, Slug: strings.ToLower(name), Position: i, Active: true}
+		if err := categories.Create(context.Background(), c); err != nil {
+			t.Fatalf("seed categories: %v", err)
+		}
+		ids[c.Slug] = c.ID
+	}
 	products := []product.Product{
-		{Name: "Classic Burger", Description: "Beef burger", PriceCents: 999, Category: "burgers", IsAvailable: true},
-		{Name: "Margherita Pizza", Description: "Fresh pizza", PriceCents: 1299, Category: "pizza", IsAvailable: true},
-		{Name: "Caesar Salad", Description: "Romaine salad", PriceCents: 799, Category: "salads", IsAvailable: true},
+		{Name: "Classic Burger", Description: "Beef burger", PriceCents: 999, CategoryID: ids["burgers"], IsAvailable: true},
+		{Name: "Margherita Pizza", Description: "Fresh pizza", PriceCents: 1299, CategoryID: ids["pizza"], IsAvailable: true},
+		{Name: "Caesar Salad", Description: "Romaine salad", PriceCents: 799, CategoryID: ids["salads"], IsAvailable: true},
 	}
 	if err := repo.InsertMany(context.Background(), products); err != nil {
 		t.Fatalf("seed products: %v", err)
@@ -132,17 +241,17 @@ func TestRegister(t *testing.T) {
 	}{
 		{
 			"valid registration",
-			map[string]string{"name": "Jane Doe", "email": "jane@example.com", "password": "secret123"},
+			map[string]string{"name": "Jane Doe", "email": "jane@example.com", "password": "tulip-anchor-7"},
 			http.StatusCreated,
 		},
 		{
 			"duplicate email",
-			map[string]string{"name": "Jane Again", "email": "jane@example.com", "password": "secret456"},
+			map[string]string{"name": "Jane Again", "email": "jane@example.com", "password": "tulip-anchor-8"},
 			http.StatusConflict,
 		},
 		{
 			"invalid email",
-			map[string]string{"name": "Bob", "email": "not-an-email", "password": "secret123"},
+			map[string]string{"name": "Bob", "email": "not-an-email", "password": "tulip-anchor-7"},
 			http.StatusBadRequest,
 		},
 		{
@@ -150,9 +259,14 @@ func TestRegister(t *testing.T) {
 			map[string]string{"name": "Bob Smith", "email": "bob@example.com", "password": "short"},
 			http.StatusBadRequest,
 		},
+		{
+			"common password",
+			map[string]string{"name": "Bob Smith", "email": "bob@example.com", "password": "password1"},
+			http.StatusBadRequest,
+		},
 		{
 			"name too short",
-			map[string]string{"name": "A", "email": "a@example.com", "password": "secret123"},
+			map[string]string{"name": "A", "email": "a@example.com", "password": "tulip-anchor-7"},
 			http.StatusBadRequest,
 		},
 	}
@@ -173,11 +287,68 @@ func TestRegister(t *testing.T) {
 	}
 }
 
+func TestPasswordPolicy(t *testing.T) {
+	ts := setupRouterWith(t, func(cfg *config.Config) {
+		cfg.PasswordRequiredClasses = []string{"lower", "upper", "digit"}
+	})
+
+	// fieldErrors returns the field-level messages, failing unless the
+	// request was rejected as invalid.
+	fieldErrors := func(resp *http.Response) map[string]string {
+		t.Helper()
+		defer resp.Body.Close()
+		var body struct {
+			Error struct {
+				Code    string            `json:"code"`
+				Details map[string]string `json:"details"`
+			} `json:"error"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("decode error response: %v", err)
+		}
+		if resp.StatusCode != http.StatusBadRequest || body.Error.Code != "VALIDATION_ERROR" {
+			t.Fatalf("status = %d, code = %q, want 400 VALIDATION_ERROR", resp.StatusCode, body.Error.Code)
+		}
+		return body.Error.Details
+	}
+
+	// Every broken rule is reported, not just the first.
+	reg := map[string]string{"name": "Pat Doe", "email": "pat@example.com", "password": "monkey1111"}
+	resp, err := http.Post(ts.URL+"/api/v1/auth/register", "application/json", jsonBody(t, reg))
+	if err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+	msg := fieldErrors(resp)["password"]
+	for _, want := range []string{"uppercase letter", "more than 3 times in a row", "too common"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("register error %q does not mention %q", msg, want)
+		}
+	}
+
+	reg["password"] = "Kite-Orbit-42"
+	resp, err = http.Post(ts.URL+"/api/v1/auth/register", "application/json", jsonBody(t, reg))
+	if err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("register status = %d, want 201", resp.StatusCode)
+	}
+
+	// Changing the password is held to the same policy.
+	access := login(t, ts, "pat@example.com", "Kite-Orbit-42")["access_token"].(string)
+	resp = postAuthed(t, ts.URL+"/api/v1/users/me/password", access,
+		map[string]string{"current_password": "Kite-Orbit-42", "new_password": "Password123!"})
+	if msg := fieldErrors(resp)["newpassword"]; !strings.Contains(msg, "too common") {
+		t.Errorf("change password error = %q, want it to mention the common list", msg)
+	}
+}
+
 func TestLoginAndRefresh(t *testing.T) {
 	ts := setupRouter(t)
 
 	// Register first
-	regBody := map[string]string{"name": "Test User", "email": "test@example.com", "password": "password123"}
+	regBody := map[string]string{"name": "Test User", "email": "test@example.com", "password": "kite-orbit-42"}
 	resp, err := http.Post(ts.URL+"/api/v1/auth/register", "application/json", jsonBody(t, regBody))
 	if err != nil {
 		t.Fatalf("register error: %v", err)
@@ -188,7 +359,7 @@ func TestLoginAndRefresh(t *testing.T) {
 	}
 
 	// Login
-	loginBody := map[string]string{"email": "test@example.com", "password": "password123"}
+	loginBody := map[string]string{"email": "test@example.com", "password": "kite-orbit-42"}
 	resp, err = http.Post(ts.URL+"/api/v1/auth/login", "application/json", jsonBody(t, loginBody))
 	if err != nil {
 		t.Fatalf("login error: %v", err)
@@ -240,7 +411,7 @@ func TestLoginAndRefresh(t *testing.T) {
 func TestLoginInvalidCredentials(t *testing.T) {
 	ts := setupRouter(t)
 
-	body := map[string]string{"email": "nonexistent@example.com", "password": "password123"}
+	body := map[string]string{"email": "nonexistent@example.com", "password": "kite-orbit-42"}
 	resp, err := http.Post(ts.URL+"/api/v1/auth/login", "application/json", jsonBody(t, body))
 	if err != nil {
 		t.Fatalf("login error: %v", err)
@@ -325,3 +496,2611 @@ func TestListProducts(t *testing.T) {
 		}
 	})
 }
+
+// registerAndLogin registers a user with the given email and returns the login token response.
+func registerAndLogin(t *testing.T, ts *httptest.Server, email string) map[string]interface{} {
+	t.Helper()
+
+	regBody := map[string]string{"name": "Test User", "email": email, "password": "kite-orbit-42"}
+	resp, err := http.Post(ts.URL+"/api/v1/auth/register", "application/json", jsonBody(t, regBody))
+	if err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("register status = %d, want 201", resp.StatusCode)
+	}
+
+	loginBody := map[string]string{"email": email, "password": "kite-orbit-42"}
+	resp, err = http.Post(ts.URL+"/api/v1/auth/login", "application/json", jsonBody(t, loginBody))
+	if err != nil {
+		t.Fatalf("login error: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("login status = %d, want 200", resp.StatusCode)
+	}
+
+	var tokenResp map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		t.Fatalf("decode token response: %v", err)
+	}
+	return tokenResp
+}
+
+// login logs in an existing user and returns the token response.
+func login(t *testing.T, ts *httptest.Server, email, password string) map[string]interface{} {
+	t.Helper()
+
+	body := map[string]string{"email": email, "password": password}
+	resp, err := http.Post(ts.URL+"/api/v1/auth/login", "application/json", jsonBody(t, body))
+	if err != nil {
+		t.Fatalf("login error: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("login status = %d, want 200", resp.StatusCode)
+	}
+
+	var tokenResp map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		t.Fatalf("decode token response: %v", err)
+	}
+	return tokenResp
+}
+
+// postAuthed sends a JSON POST with a Bearer token.
+func postAuthed(t *testing.T, url, accessToken string, body interface{}) *http.Response {
+	t.Helper()
+	return doAuthed(t, http.MethodPost, url, accessToken, body)
+}
+
+// doAuthed sends a JSON request with a Bearer token.
+func doAuthed(t *testing.T, method, url, accessToken string, body interface{}) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, jsonBody(t, body))
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s error: %v", method, url, err)
+	}
+	return resp
+}
+
+// accessClaims decodes the (unverified) claims segment of a JWT.
+func accessClaims(t *testing.T, token string) map[string]interface{} {
+	t.Helper()
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("malformed JWT: %q", token)
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatalf("decode JWT payload: %v", err)
+	}
+	var claims map[string]interface{}
+	if err := json.Unmarshal(raw, &claims); err != nil {
+		t.Fatalf("unmarshal JWT payload: %v", err)
+	}
+	return claims
+}
+
+func TestLogout(t *testing.T) {
+	ts := setupRouter(t)
+	tokens := registerAndLogin(t, ts, "logout@example.com")
+	refreshBody := map[string]string{"refresh_token": tokens["refresh_token"].(string)}
+
+	resp, err := http.Post(ts.URL+"/api/v1/auth/logout", "application/json", jsonBody(t, refreshBody))
+	if err != nil {
+		t.Fatalf("logout error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("logout status = %d, want 200", resp.StatusCode)
+	}
+
+	// The revoked refresh token can no longer be used.
+	resp, err = http.Post(ts.URL+"/api/v1/auth/refresh", "application/json", jsonBody(t, refreshBody))
+	if err != nil {
+		t.Fatalf("refresh error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("refresh after logout: status = %d, want 401", resp.StatusCode)
+	}
+
+	// Logging out twice with the same token is rejected.
+	resp, err = http.Post(ts.URL+"/api/v1/auth/logout", "application/json", jsonBody(t, refreshBody))
+	if err != nil {
+		t.Fatalf("logout error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("second logout: status = %d, want 401", resp.StatusCode)
+	}
+}
+
+func TestLogoutAll(t *testing.T) {
+	ts := setupRouter(t)
+	first := registerAndLogin(t, ts, "logoutall@example.com")
+	second := login(t, ts, "logoutall@example.com", "kite-orbit-42")
+
+	t.Run("requires authentication", func(t *testing.T) {
+		resp, err := http.Post(ts.URL+"/api/v1/auth/logout-all", "application/json", jsonBody(t, map[string]string{}))
+		if err != nil {
+			t.Fatalf("logout-all error: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("status = %d, want 401", resp.StatusCode)
+		}
+	})
+
+	resp := postAuthed(t, ts.URL+"/api/v1/auth/logout-all", first["access_token"].(string), map[string]string{})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("logout-all status = %d, want 200", resp.StatusCode)
+	}
+
+	// Every session's refresh token is revoked.
+	for i, tokens := range []map[string]interface{}{first, second} {
+		refreshBody := map[string]string{"refresh_token": tokens["refresh_token"].(string)}
+		resp, err := http.Post(ts.URL+"/api/v1/auth/refresh", "application/json", jsonBody(t, refreshBody))
+		if err != nil {
+			t.Fatalf("refresh error: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("session %d refresh after logout-all: status = %d, want 401", i, resp.StatusCode)
+		}
+	}
+}
+
+func TestRefreshTokenReuseRevokesFamily(t *testing.T) {
+	ts := setupRouter(t)
+	original := registerAndLogin(t, ts, "reuse@example.com")
+	other := login(t, ts, "reuse@example.com", "kite-orbit-42")
+
+	refresh := func(token string) (int, map[string]interface{}) {
+		t.Helper()
+		body := map[string]string{"refresh_token": token}
+		resp, err := http.Post(ts.URL+"/api/v1/auth/refresh", "application/json", jsonBody(t, body))
+		if err != nil {
+			t.Fatalf("refresh error: %v", err)
+		}
+		defer resp.Body.Close()
+		var out map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&out)
+		return resp.StatusCode, out
+	}
+
+	status, rotated := refresh(original["refresh_token"].(string))
+	if status != http.StatusOK {
+		t.Fatalf("first refresh status = %d, want 200", status)
+	}
+
+	// Replaying the rotated-out token is rejected...
+	if status, _ := refresh(original["refresh_token"].(string)); status != http.StatusUnauthorized {
+		t.Fatalf("replayed refresh status = %d, want 401", status)
+	}
+
+	// ...and takes the legitimate successor down with it.
+	if status, _ := refresh(rotated["refresh_token"].(string)); status != http.StatusUnauthorized {
+		t.Errorf("successor refresh status = %d, want 401", status)
+	}
+
+	// Sessions from other logins are unaffected.
+	if status, _ := refresh(other["refresh_token"].(string)); status != http.StatusOK {
+		t.Errorf("unrelated session refresh status = %d, want 200", status)
+	}
+}
+
+func TestProductWriteRequiresPermission(t *testing.T) {
+	ts := setupRouter(t)
+	tokens := registerAndLogin(t, ts, "customer@example.com")
+
+	body := map[string]interface{}{"name": "Sneaky Pizza", "price_cents": 100, "category_id": categoryID(t, "pizza")}
+	resp := postAuthed(t, ts.URL+"/api/v1/products", tokens["access_token"].(string), body)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("status = %d, want 403", resp.StatusCode)
+	}
+}
+
+func TestRefreshRebuildsProfileClaims(t *testing.T) {
+	ts := setupRouter(t)
+	tokens := registerAndLogin(t, ts, "claims@example.com")
+
+	body := map[string]string{"refresh_token": tokens["refresh_token"].(string)}
+	resp, err := http.Post(ts.URL+"/api/v1/auth/refresh", "application/json", jsonBody(t, body))
+	if err != nil {
+		t.Fatalf("refresh error: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("refresh status = %d, want 200", resp.StatusCode)
+	}
+
+	var refreshed map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&refreshed)
+	claims := accessClaims(t, refreshed["access_token"].(string))
+	if claims["email"] != "claims@example.com" {
+		t.Errorf("email claim = %v, want claims@example.com", claims["email"])
+	}
+	if claims["name"] != "Test User" {
+		t.Errorf("name claim = %v, want Test User", claims["name"])
+	}
+	if claims["role"] != "user" {
+		t.Errorf("role claim = %v, want user", claims["role"])
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	ts := setupRouter(t)
+	registerAndLogin(t, ts, "locked@example.com")
+
+	attempt := func(password string) *http.Response {
+		t.Helper()
+		body := map[string]string{"email": "locked@example.com", "password": password}
+		resp, err := http.Post(ts.URL+"/api/v1/auth/login", "application/json", jsonBody(t, body))
+		if err != nil {
+			t.Fatalf("login error: %v", err)
+		}
+		return resp
+	}
+
+	for i := 0; i < 5; i++ {
+		resp := attempt("wrongpass1")
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("failed attempt %d: status = %d, want 401", i+1, resp.StatusCode)
+		}
+	}
+
+	// Even the correct password is refused while locked.
+	resp := attempt("kite-orbit-42")
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusLocked {
+		t.Fatalf("status = %d, want 423", resp.StatusCode)
+	}
+	if resp.Header.Get("Retry-After") == "" {
+		t.Error("missing Retry-After header")
+	}
+
+	var body map[string]map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&body)
+	if code := body["error"]["code"]; code != "ACCOUNT_LOCKED" {
+		t.Errorf("error code = %v, want ACCOUNT_LOCKED", code)
+	}
+}
+
+func TestPasswordReset(t *testing.T) {
+	ts := setupRouter(t)
+	tokens := registerAndLogin(t, ts, "reset@example.com")
+
+	post := func(path string, body interface{}) *http.Response {
+		t.Helper()
+		resp, err := http.Post(ts.URL+path, "application/json", jsonBody(t, body))
+		if err != nil {
+			t.Fatalf("POST %s error: %v", path, err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	// Unknown emails get the same response and no message.
+	if resp := post("/api/v1/auth/password/forgot", map[string]string{"email": "nobody@example.com"}); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("forgot unknown: status = %d, want 202", resp.StatusCode)
+	}
+	if _, ok := outbox.Last("nobody@example.com", actiontoken.PurposePasswordReset); ok {
+		t.Fatal("reset message sent for unknown email")
+	}
+
+	if resp := post("/api/v1/auth/password/forgot", map[string]string{"email": "reset@example.com"}); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("forgot: status = %d, want 202", resp.StatusCode)
+	}
+	msg, ok := outbox.Last("reset@example.com", actiontoken.PurposePasswordReset)
+	if !ok {
+		t.Fatal("no reset message sent")
+	}
+	resetToken := msg.Data["token"]
+
+	// Weak passwords are rejected without consuming the token.
+	weak := map[string]string{"token": resetToken, "password": "short"}
+	if resp := post("/api/v1/auth/password/reset", weak); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("weak password: status = %d, want 400", resp.StatusCode)
+	}
+
+	reset := map[string]string{"token": resetToken, "password": "newpass456"}
+	if resp := post("/api/v1/auth/password/reset", reset); resp.StatusCode != http.StatusOK {
+		t.Fatalf("reset: status = %d, want 200", resp.StatusCode)
+	}
+
+	// The token is single-use.
+	if resp := post("/api/v1/auth/password/reset", reset); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("second reset: status = %d, want 400", resp.StatusCode)
+	}
+
+	// Existing sessions are revoked.
+	refreshBody := map[string]string{"refresh_token": tokens["refresh_token"].(string)}
+	if resp := post("/api/v1/auth/refresh", refreshBody); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("refresh after reset: status = %d, want 401", resp.StatusCode)
+	}
+
+	// Only the new password works.
+	if resp := post("/api/v1/auth/login", map[string]string{"email": "reset@example.com", "password": "kite-orbit-42"}); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("login with old password: status = %d, want 401", resp.StatusCode)
+	}
+	login(t, ts, "reset@example.com", "newpass456")
+}
+
+func TestEmailVerificationBlocksLogin(t *testing.T) {
+	ts := setupRouterWith(t, func(cfg *config.Config) {
+		cfg.EmailVerification = config.EmailVerificationLogin
+	})
+
+	regBody := map[string]string{"name": "Test User", "email": "verify@example.com", "password": "kite-orbit-42"}
+	resp, err := http.Post(ts.URL+"/api/v1/auth/register", "application/json", jsonBody(t, regBody))
+	if err != nil {
+		t.Fatalf("register error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("register status = %d, want 201", resp.StatusCode)
+	}
+
+	loginBody := map[string]string{"email": "verify@example.com", "password": "kite-orbit-42"}
+	resp, err = http.Post(ts.URL+"/api/v1/auth/login", "application/json", jsonBody(t, loginBody))
+	if err != nil {
+		t.Fatalf("login error: %v", err)
+	}
+	var errBody map[string]map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&errBody)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden || errBody["error"]["code"] != "EMAIL_NOT_VERIFIED" {
+		t.Fatalf("unverified login: status = %d, code = %v; want 403 EMAIL_NOT_VERIFIED", resp.StatusCode, errBody["error"]["code"])
+	}
+
+	msg, ok := outbox.Last("verify@example.com", actiontoken.PurposeEmailVerification)
+	if !ok {
+		t.Fatal("no verification message sent")
+	}
+	verifyBody := map[string]string{"token": msg.Data["token"]}
+
+	resp, err = http.Post(ts.URL+"/api/v1/auth/verify-email", "application/json", jsonBody(t, verifyBody))
+	if err != nil {
+		t.Fatalf("verify error: %v", err)
+	}
+	var verified map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&verified)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("verify status = %d, want 200", resp.StatusCode)
+	}
+	if verified["email_verified"] != true {
+		t.Errorf("email_verified = %v, want true", verified["email_verified"])
+	}
+
+	// Verification tokens are single-use.
+	resp, err = http.Post(ts.URL+"/api/v1/auth/verify-email", "application/json", jsonBody(t, verifyBody))
+	if err != nil {
+		t.Fatalf("verify error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("second verify: status = %d, want 400", resp.StatusCode)
+	}
+
+	tokens := login(t, ts, "verify@example.com", "kite-orbit-42")
+	if claims := accessClaims(t, tokens["access_token"].(string)); claims["email_verified"] != true {
+		t.Errorf("email_verified claim = %v, want true", claims["email_verified"])
+	}
+}
+
+func TestEmailVerificationGuardsActions(t *testing.T) {
+	ts := setupRouterWith(t, func(cfg *config.Config) {
+		cfg.EmailVerification = config.EmailVerificationActions
+	})
+	registerAndLogin(t, ts, "unverified@example.com")
+	setRole(t, "unverified@example.com", role.Admin)
+	access := login(t, ts, "unverified@example.com", "kite-orbit-42")["access_token"].(string)
+	product := map[string]interface{}{"name": "Guarded Burger", "price_cents": 950, "category_id": categoryID(t, "burgers")}
+
+	// Signing in and reading the own account still work.
+	resp := doAuthed(t, http.MethodGet, ts.URL+"/api/v1/users/me", access, nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("me: status = %d, want 200", resp.StatusCode)
+	}
+
+	for _, tc := range []struct{ method, url string }{
+		{http.MethodPost, ts.URL + "/api/v1/products"},
+		{http.MethodGet, ts.URL + "/api/v1/admin/users"},
+	} {
+		resp := doAuthed(t, tc.method, tc.url, access, product)
+		var errBody map[string]map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&errBody)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden || errBody["error"]["code"] != "EMAIL_NOT_VERIFIED" {
+			t.Errorf("%s %s: status = %d, code = %v; want 403 EMAIL_NOT_VERIFIED", tc.method, tc.url, resp.StatusCode, errBody["error"]["code"])
+		}
+	}
+
+	msg, ok := outbox.Last("unverified@example.com", actiontoken.PurposeEmailVerification)
+	if !ok {
+		t.Fatal("no verification message sent")
+	}
+	resp, err := http.Post(ts.URL+"/api/v1/auth/verify-email", "application/json", jsonBody(t, map[string]string{"token": msg.Data["token"]}))
+	if err != nil {
+		t.Fatalf("verify error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("verify status = %d, want 200", resp.StatusCode)
+	}
+
+	// The verified flag travels in the access token, so sign in again.
+	access = login(t, ts, "unverified@example.com", "kite-orbit-42")["access_token"].(string)
+	resp = postAuthed(t, ts.URL+"/api/v1/products", access, product)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("create after verifying: status = %d, want 201", resp.StatusCode)
+	}
+}
+
+func TestTOTPLogin(t *testing.T) {
+	ts := setupRouterWith(t, func(cfg *config.Config) {
+		cfg.MFARequiredRoles = []string{role.User}
+	})
+	tokens := registerAndLogin(t, ts, "mfa@example.com")
+	access := tokens["access_token"].(string)
+
+	// The role requires MFA, so the password-only session is flagged.
+	if claims := accessClaims(t, access); claims["mfa_pending"] != true {
+		t.Fatalf("mfa_pending claim = %v, want true", claims["mfa_pending"])
+	}
+
+	decode := func(resp *http.Response) map[string]interface{} {
+		t.Helper()
+		defer resp.Body.Close()
+		var body map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&body)
+		return body
+	}
+	post := func(path string, body interface{}) *http.Response {
+		t.Helper()
+		resp, err := http.Post(ts.URL+path, "application/json", jsonBody(t, body))
+		if err != nil {
+			t.Fatalf("POST %s error: %v", path, err)
+		}
+		return resp
+	}
+	codeAt := func(secret string, offset int64) string {
+		t.Helper()
+		code, err := totp.Code(secret, totp.Step(time.Now())+offset)
+		if err != nil {
+			t.Fatalf("totp code: %v", err)
+		}
+		return code
+	}
+
+	resp := postAuthed(t, ts.URL+"/api/v1/auth/mfa/enroll", access, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("enroll status = %d, want 200", resp.StatusCode)
+	}
+	secret := decode(resp)["secret"].(string)
+
+	resp = postAuthed(t, ts.URL+"/api/v1/auth/mfa/confirm", access, map[string]string{"code": codeAt(secret, 0)})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("confirm status = %d, want 200", resp.StatusCode)
+	}
+	recovery := decode(resp)["recovery_codes"].([]interface{})
+	if len(recovery) != 10 {
+		t.Fatalf("got %d recovery codes, want 10", len(recovery))
+	}
+
+	// Password login now yields a challenge instead of tokens.
+	creds := map[string]string{"email": "mfa@example.com", "password": "kite-orbit-42"}
+	challenge := decode(post("/api/v1/auth/login", creds))
+	if challenge["mfa_required"] != true || challenge["access_token"] != nil {
+		t.Fatalf("login response = %v, want MFA challenge", challenge)
+	}
+	mfaToken := challenge["mfa_token"].(string)
+
+	// The challenge token is not an access token.
+	resp = postAuthed(t, ts.URL+"/api/v1/auth/logout-all", mfaToken, nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("challenge as bearer: status = %d, want 401", resp.StatusCode)
+	}
+
+	resp = post("/api/v1/auth/login/mfa", map[string]string{"mfa_token": mfaToken, "code": "000000"})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("wrong code: status = %d, want 401", resp.StatusCode)
+	}
+
+	// The confirmation code's step is spent; use the next one.
+	resp = post("/api/v1/auth/login/mfa", map[string]string{"mfa_token": mfaToken, "code": codeAt(secret, 1)})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("login/mfa status = %d, want 200", resp.StatusCode)
+	}
+	full := decode(resp)
+	if claims := accessClaims(t, full["access_token"].(string)); claims["mfa_pending"] != nil {
+		t.Errorf("mfa_pending claim = %v, want absent", claims["mfa_pending"])
+	}
+
+	// Recovery codes work once.
+	useRecovery := func() int {
+		t.Helper()
+		mfaToken := decode(post("/api/v1/auth/login", creds))["mfa_token"].(string)
+		resp := post("/api/v1/auth/login/mfa", map[string]string{"mfa_token": mfaToken, "code": recovery[0].(string)})
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if status := useRecovery(); status != http.StatusOK {
+		t.Fatalf("recovery code: status = %d, want 200", status)
+	}
+	if status := useRecovery(); status != http.StatusUnauthorized {
+		t.Errorf("reused recovery code: status = %d, want 401", status)
+	}
+
+	// The role requires MFA, so it cannot be turned off.
+	resp = postAuthed(t, ts.URL+"/api/v1/auth/mfa/disable", full["access_token"].(string), map[string]string{"code": codeAt(secret, 0)})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("disable: status = %d, want 403", resp.StatusCode)
+	}
+}
+
+func TestProfileMe(t *testing.T) {
+	ts := setupRouter(t)
+	current := registerAndLogin(t, ts, "me@example.com")
+	other := login(t, ts, "me@example.com", "kite-orbit-42")
+	access := current["access_token"].(string)
+	meURL := ts.URL + "/api/v1/users/me"
+
+	resp := doAuthed(t, http.MethodGet, meURL, access, nil)
+	var me map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&me)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || me["email"] != "me@example.com" {
+		t.Fatalf("GET /me: status = %d, body = %v", resp.StatusCode, me)
+	}
+
+	patches := []struct {
+		name string
+		body interface{}
+		want int
+	}{
+		{"valid name", map[string]string{"name": "Renamed User"}, http.StatusOK},
+		{"invalid name", map[string]string{"name": "R2"}, http.StatusBadRequest},
+		{"no fields", map[string]string{}, http.StatusBadRequest},
+	}
+	for _, p := range patches {
+		resp := doAuthed(t, http.MethodPatch, meURL, access, p.body)
+		resp.Body.Close()
+		if resp.StatusCode != p.want {
+			t.Errorf("PATCH /me %s: status = %d, want %d", p.name, resp.StatusCode, p.want)
+		}
+	}
+
+	resp = doAuthed(t, http.MethodGet, meURL, access, nil)
+	json.NewDecoder(resp.Body).Decode(&me)
+	resp.Body.Close()
+	if me["name"] != "Renamed User" {
+		t.Errorf("name = %v, want Renamed User", me["name"])
+	}
+
+	pwURL := meURL + "/password"
+	resp = postAuthed(t, pwURL, access, map[string]string{"current_password": "wrongpass1", "new_password": "newpass456"})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("wrong current password: status = %d, want 400", resp.StatusCode)
+	}
+
+	resp = postAuthed(t, pwURL, access, map[string]string{"current_password": "kite-orbit-42", "new_password": "newpass456"})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("change password: status = %d, want 200", resp.StatusCode)
+	}
+
+	refresh := func(tokens map[string]interface{}) int {
+		t.Helper()
+		body := map[string]string{"refresh_token": tokens["refresh_token"].(string)}
+		resp, err := http.Post(ts.URL+"/api/v1/auth/refresh", "application/json", jsonBody(t, body))
+		if err != nil {
+			t.Fatalf("refresh error: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// The other session is signed out; the caller's survives.
+	if status := refresh(other); status != http.StatusUnauthorized {
+		t.Errorf("other session refresh: status = %d, want 401", status)
+	}
+	if status := refresh(current); status != http.StatusOK {
+		t.Errorf("current session refresh: status = %d, want 200", status)
+	}
+	login(t, ts, "me@example.com", "newpass456")
+}
+
+// setRole assigns a role directly in the database, bypassing the API.
+func setRole(t *testing.T, email, roleName string) {
+	t.Helper()
+	_, err := testDB.Collection("users").UpdateOne(context.Background(),
+		bson.M{"email": email}, bson.M{"$set": bson.M{"role": roleName}})
+	if err != nil {
+		t.Fatalf("set role: %v", err)
+	}
+}
+
+func TestAdminUserManagement(t *testing.T) {
+	ts := setupRouter(t)
+	registerAndLogin(t, ts, "admin@example.com")
+	setRole(t, "admin@example.com", role.Admin)
+	admin := login(t, ts, "admin@example.com", "kite-orbit-42")["access_token"].(string)
+	alice := registerAndLogin(t, ts, "alice@example.com")
+	bob := registerAndLogin(t, ts, "bob@example.com")
+	usersURL := ts.URL + "/api/v1/admin/users"
+
+	list := func(query string) map[string]interface{} {
+		t.Helper()
+		resp := doAuthed(t, http.MethodGet, usersURL+query, admin, nil)
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("list %s: status = %d, want 200", query, resp.StatusCode)
+		}
+		var body map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&body)
+		return body
+	}
+
+	if total := list("")["total"]; total != float64(3) {
+		t.Errorf("total = %v, want 3", total)
+	}
+	if total := list("?role=admin")["total"]; total != float64(1) {
+		t.Errorf("admins = %v, want 1", total)
+	}
+	byEmail := list("?email=ALICE")
+	items := byEmail["items"].([]interface{})
+	if len(items) != 1 || items[0].(map[string]interface{})["email"] != "alice@example.com" {
+		t.Fatalf("email filter = %v, want alice only", items)
+	}
+	aliceID := items[0].(map[string]interface{})["id"].(string)
+	bobID := list("?email=bob")["items"].([]interface{})[0].(map[string]interface{})["id"].(string)
+	adminID := list("?role=admin")["items"].([]interface{})[0].(map[string]interface{})["id"].(string)
+
+	roleChanges := []struct {
+		name string
+		id   string
+		role string
+		want int
+	}{
+		{"unknown role", bobID, "ghost", http.StatusBadRequest},
+		{"own role", adminID, role.User, http.StatusForbidden},
+		{"promote", bobID, role.Admin, http.StatusOK},
+	}
+	for _, rc := range roleChanges {
+		resp := doAuthed(t, http.MethodPut, usersURL+"/"+rc.id+"/role", admin, map[string]string{"role": rc.role})
+		resp.Body.Close()
+		if resp.StatusCode != rc.want {
+			t.Errorf("%s: status = %d, want %d", rc.name, resp.StatusCode, rc.want)
+		}
+	}
+
+	refresh := func(tokens map[string]interface{}) int {
+		t.Helper()
+		body := map[string]string{"refresh_token": tokens["refresh_token"].(string)}
+		resp, err := http.Post(ts.URL+"/api/v1/auth/refresh", "application/json", jsonBody(t, body))
+		if err != nil {
+			t.Fatalf("refresh error: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// Disabling signs the user out and blocks login until re-enabled.
+	resp := postAuthed(t, usersURL+"/"+aliceID+"/disable", admin, nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("disable: status = %d, want 200", resp.StatusCode)
+	}
+	if status := refresh(alice); status != http.StatusUnauthorized {
+		t.Errorf("refresh while disabled: status = %d, want 401", status)
+	}
+	creds := map[string]string{"email": "alice@example.com", "password": "kite-orbit-42"}
+	resp, err := http.Post(ts.URL+"/api/v1/auth/login", "application/json", jsonBody(t, creds))
+	if err != nil {
+		t.Fatalf("login error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("login while disabled: status = %d, want 403", resp.StatusCode)
+	}
+
+	resp = postAuthed(t, usersURL+"/"+aliceID+"/enable", admin, nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("enable: status = %d, want 200", resp.StatusCode)
+	}
+	login(t, ts, "alice@example.com", "kite-orbit-42")
+
+	// Force logout revokes every session of the user.
+	resp = postAuthed(t, usersURL+"/"+bobID+"/logout", admin, nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("force logout: status = %d, want 200", resp.StatusCode)
+	}
+	if status := refresh(bob); status != http.StatusUnauthorized {
+		t.Errorf("refresh after force logout: status = %d, want 401", status)
+	}
+
+	// Regular users cannot reach the admin API.
+	resp = doAuthed(t, http.MethodGet, usersURL, alice["access_token"].(string), nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("non-admin list: status = %d, want 403", resp.StatusCode)
+	}
+}
+
+func TestUserManagerCannotEscalate(t *testing.T) {
+	ts := setupRouter(t)
+	registerAndLogin(t, ts, "admin@example.com")
+	setRole(t, "admin@example.com", role.Admin)
+	admin := login(t, ts, "admin@example.com", "kite-orbit-42")["access_token"].(string)
+	usersURL := ts.URL + "/api/v1/admin/users"
+
+	resp := postAuthed(t, ts.URL+"/api/v1/admin/roles", admin, map[string]interface{}{
+		"name": "user-manager", "permissions": []string{role.PermUsersManage},
+	})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create role: status = %d, want 201", resp.StatusCode)
+	}
+
+	registerAndLogin(t, ts, "manager@example.com")
+	setRole(t, "manager@example.com", "user-manager")
+	manager := login(t, ts, "manager@example.com", "kite-orbit-42")["access_token"].(string)
+	registerAndLogin(t, ts, "accomplice@example.com")
+	accompliceID := userID(t, "accomplice@example.com")
+	adminID := userID(t, "admin@example.com")
+
+	// Granting a role with permissions the manager lacks is refused.
+	resp = doAuthed(t, http.MethodPut, usersURL+"/"+accompliceID+"/role", manager, map[string]string{"role": role.Admin})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("grant admin: status = %d, want 403", resp.StatusCode)
+	}
+
+	// So is acting on an account more privileged than the manager's.
+	for _, tc := range []struct{ method, url string }{
+		{http.MethodPut, usersURL + "/" + adminID + "/role"},
+		{http.MethodPost, usersURL + "/" + adminID + "/disable"},
+		{http.MethodPost, usersURL + "/" + adminID + "/unlock"},
+		{http.MethodPost, usersURL + "/" + adminID + "/logout"},
+		{http.MethodGet, usersURL + "/" + adminID + "/export"},
+		{http.MethodDelete, usersURL + "/" + adminID},
+	} {
+		resp := doAuthed(t, tc.method, tc.url, manager, map[string]string{"role": role.User})
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("%s %s: status = %d, want 403", tc.method, tc.url, resp.StatusCode)
+		}
+	}
+	login(t, ts, "admin@example.com", "kite-orbit-42")
+
+	// Roles within the manager's own permissions can still be assigned.
+	resp = doAuthed(t, http.MethodPut, usersURL+"/"+accompliceID+"/role", manager, map[string]string{"role": "user-manager"})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("grant own role: status = %d, want 200", resp.StatusCode)
+	}
+	resp = postAuthed(t, usersURL+"/"+accompliceID+"/disable", manager, nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("disable peer: status = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestRoleManagerCannotEscalate(t *testing.T) {
+	ts := setupRouter(t)
+	registerAndLogin(t, ts, "admin@example.com")
+	setRole(t, "admin@example.com", role.Admin)
+	admin := login(t, ts, "admin@example.com", "kite-orbit-42")["access_token"].(string)
+	rolesURL := ts.URL + "/api/v1/admin/roles"
+
+	send := func(method, url, token string, body interface{}) int {
+		t.Helper()
+		resp := doAuthed(t, method, url, token, body)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	for _, r := range []map[string]interface{}{
+		{"name": "role-manager", "permissions": []string{role.PermRolesManage, role.PermProductsWrite}},
+		{"name": "store-manager", "permissions": []string{role.PermProductsWrite, role.PermUsersManage}},
+	} {
+		if status := send(http.MethodPost, rolesURL, admin, r); status != http.StatusCreated {
+			t.Fatalf("create %s: status = %d, want 201", r["name"], status)
+		}
+	}
+	registerAndLogin(t, ts, "roles@example.com")
+	setRole(t, "roles@example.com", "role-manager")
+	manager := login(t, ts, "roles@example.com", "kite-orbit-42")["access_token"].(string)
+
+	// Roles granting permissions the manager lacks can be neither created
+	// nor changed, and the manager's own role is off limits.
+	for name, tc := range map[string]struct {
+		method, url string
+		body        interface{}
+	}{
+		"create wildcard":     {http.MethodPost, rolesURL, map[string]interface{}{"name": "root", "permissions": []string{role.PermAll}}},
+		"create users:manage": {http.MethodPost, rolesURL, map[string]interface{}{"name": "hr", "permissions": []string{role.PermUsersManage}}},
+		"widen own role":      {http.MethodPut, rolesURL + "/role-manager", map[string]interface{}{"permissions": []string{role.PermAll}}},
+		"describe own role":   {http.MethodPut, rolesURL + "/role-manager", map[string]interface{}{"description": "mine"}},
+		"edit stronger role":  {http.MethodPut, rolesURL + "/store-manager", map[string]interface{}{"description": "weaker"}},
+		"delete stronger":     {http.MethodDelete, rolesURL + "/store-manager", nil},
+	} {
+		if status := send(tc.method, tc.url, manager, tc.body); status != http.StatusForbidden {
+			t.Errorf("%s: status = %d, want 403", name, status)
+		}
+	}
+
+	// Roles within the manager's own permissions can still be managed.
+	kitchen := map[string]interface{}{"name": "kitchen", "permissions": []string{role.PermProductsWrite}}
+	if status := send(http.MethodPost, rolesURL, manager, kitchen); status != http.StatusCreated {
+		t.Errorf("create kitchen: status = %d, want 201", status)
+	}
+	if status := send(http.MethodPut, rolesURL+"/kitchen", manager, map[string]interface{}{"description": "Cooks"}); status != http.StatusOK {
+		t.Errorf("update kitchen: status = %d, want 200", status)
+	}
+	if status := send(http.MethodDelete, rolesURL+"/kitchen", manager, nil); status != http.StatusOK {
+		t.Errorf("delete kitchen: status = %d, want 200", status)
+	}
+}
+
+func TestMigrations(t *testing.T) {
+	ts := setupRouter(t)
+	ctx := context.Background()
+	registerAndLogin(t, ts, "legacy@example.com")
+
+	// Recreate data as it looked before the migrations existed: a plaintext
+	// refresh token, a user without the email_verified field, and products
+	// with a single price and no slug that name their category in free text.
+	var u user.User
+	if err := testDB.Collection("users").FindOne(ctx, bson.M{"email": "legacy@example.com"}).Decode(&u); err != nil {
+		t.Fatalf("find user: %v", err)
+	}
+	legacyToken := "legacy-plaintext-refresh-token"
+	_, err := testDB.Collection("refresh_tokens").InsertOne(ctx, bson.M{
+		"user_id":    u.ID,
+		"family_id":  primitive.NewObjectID(),
+		"token":      legacyToken,
+		"expires_at": time.Now().Add(time.Hour),
+		"revoked":    false,
+		"created_at": time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("insert legacy token: %v", err)
+	}
+	if _, err = testDB.Collection("users").UpdateOne(ctx, bson.M{"_id": u.ID}, bson.M{"$unset": bson.M{"email_verified": ""}}); err != nil {
+		t.Fatalf("unset email_verified: %v", err)
+	}
+	if _, err = testDB.Collection("products").UpdateMany(ctx, bson.M{}, bson.M{"$unset": bson.M{"slug": "", "category_id": "", "variants": ""}}); err != nil {
+		t.Fatalf("unset slugs: %v", err)
+	}
+	if _, err = testDB.Collection("products").UpdateOne(ctx, bson.M{"name": "Caesar Salad"}, bson.M{"$set": bson.M{"is_available": false}}); err != nil {
+		t.Fatalf("take product off sale: %v", err)
+	}
+	for name, cat := range map[string]string{"Classic Burger": "burgers", "Margherita Pizza": "Pizza ", "Caesar Salad": "pizza"} {
+		if _, err = testDB.Collection("products").UpdateOne(ctx, bson.M{"name": name}, bson.M{"$set": bson.M{"category": cat}}); err != nil {
+			t.Fatalf("set legacy category: %v", err)
+		}
+	}
+	if _, err = testDB.Collection("categories").DeleteMany(ctx, bson.M{}); err != nil {
+		t.Fatalf("delete categories: %v", err)
+	}
+	if _, err = testDB.Collection("schema_migrations").DeleteMany(ctx, bson.M{}); err != nil {
+		t.Fatalf("reset schema_migrations: %v", err)
+	}
+
+	m, err := migrate.New(testDB, migrations.All())
+	if err != nil {
+		t.Fatalf("new migrator: %v", err)
+	}
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		t.Fatalf("pending: %v", err)
+	}
+	if len(pending) != len(migrations.All()) {
+		t.Fatalf("pending = %d, want %d", len(pending), len(migrations.All()))
+	}
+
+	// A lock held by another process blocks migrating until its lease expires.
+	locks := testDB.Collection("schema_migrations_lock")
+	_, err = locks.InsertOne(ctx, bson.M{"_id": "schema_migrations", "owner": "other", "expires_at": time.Now().Add(time.Minute)})
+	if err != nil {
+		t.Fatalf("insert lock: %v", err)
+	}
+	if _, err = m.Up(ctx, 0); !errors.Is(err, migrate.ErrLocked) {
+		t.Fatalf("up while locked: err = %v, want ErrLocked", err)
+	}
+	_, err = locks.UpdateOne(ctx, bson.M{"_id": "schema_migrations"}, bson.M{"$set": bson.M{"expires_at": time.Now().Add(-time.Second)}})
+	if err != nil {
+		t.Fatalf("expire lock: %v", err)
+	}
+
+	applied, err := m.Up(ctx, 0)
+	if err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	if len(applied) != len(migrations.All()) {
+		t.Errorf("applied = %d, want %d", len(applied), len(migrations.All()))
+	}
+	if n, _ := locks.CountDocuments(ctx, bson.M{}); n != 0 {
+		t.Errorf("lock documents after up = %d, want 0", n)
+	}
+
+	var rt bson.M
+	if err = testDB.Collection("refresh_tokens").FindOne(ctx, bson.M{"token_hash": auth.HashRefreshToken(legacyToken)}).Decode(&rt); err != nil {
+		t.Fatalf("find hashed legacy token: %v", err)
+	}
+	if _, ok := rt["token"]; ok {
+		t.Error("plaintext token still stored after migration")
+	}
+	resp, err := http.Post(ts.URL+"/api/v1/auth/refresh", "application/json", jsonBody(t, map[string]string{"refresh_token": legacyToken}))
+	if err != nil {
+		t.Fatalf("refresh error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("refresh with migrated token: status = %d, want 200", resp.StatusCode)
+	}
+
+	if err = testDB.Collection("users").FindOne(ctx, bson.M{"_id": u.ID}).Decode(&u); err != nil {
+		t.Fatalf("reload user: %v", err)
+	}
+	if !u.EmailVerified {
+		t.Error("legacy user not backfilled as verified")
+	}
+	if n, _ := testDB.Collection("products").CountDocuments(ctx, bson.M{"slug": bson.M{"$type": "string"}}); n != 3 {
+		t.Errorf("products with slugs after backfill = %d, want 3", n)
+	}
+	if n, _ := testDB.Collection("products").CountDocuments(ctx, bson.M{"slug": "classic-burger"}); n != 1 {
+		t.Error("product slug not backfilled from its name")
+	}
+	var pizza category.Category
+	if err = testDB.Collection("categories").FindOne(ctx, bson.M{"slug": "pizza"}).Decode(&pizza); err != nil {
+		t.Fatalf("find migrated category: %v", err)
+	}
+	if pizza.Name != "Pizza" || !pizza.Active || pizza.ParentID != nil {
+		t.Errorf("migrated category = %+v", pizza)
+	}
+	if n, _ := testDB.Collection("categories").CountDocuments(ctx, bson.M{}); n != 2 {
+		t.Errorf("categories after migration = %d, want 2 (case variants merged)", n)
+	}
+	if n, _ := testDB.Collection("products").CountDocuments(ctx, bson.M{"category_id": pizza.ID}); n != 2 {
+		t.Errorf("products in migrated pizza category = %d, want 2", n)
+	}
+	if n, _ := testDB.Collection("products").CountDocuments(ctx, bson.M{"category": bson.M{"$exists": true}}); n != 0 {
+		t.Errorf("products still carrying a category string = %d, want 0", n)
+	}
+	var burger product.Product
+	if err = testDB.Collection("products").FindOne(ctx, bson.M{"slug": "classic-burger"}).Decode(&burger); err != nil {
+		t.Fatalf("find migrated product: %v", err)
+	}
+	if len(burger.Variants) != 1 || burger.Variants[0].SKU != "CLASSIC-BURGER" || burger.Variants[0].PriceCents != 999 {
+		t.Errorf("migrated variants = %+v, want one CLASSIC-BURGER variant at 999", burger.Variants)
+	}
+	if len(burger.Variants) == 1 && !burger.Variants[0].IsAvailable {
+		t.Error("migrated variant of an available product is unavailable")
+	}
+	var salad product.Product
+	if err = testDB.Collection("products").FindOne(ctx, bson.M{"name": "Caesar Salad"}).Decode(&salad); err != nil {
+		t.Fatalf("find migrated product: %v", err)
+	}
+	if len(salad.Variants) != 1 || salad.Variants[0].IsAvailable {
+		t.Errorf("migrated variants = %+v, want one unavailable variant like the product", salad.Variants)
+	}
+
+	// Applying again is a no-op, and one-way backfills refuse to roll back.
+	if applied, err = m.Up(ctx, 0); err != nil || len(applied) != 0 {
+		t.Errorf("second up: applied = %d, err = %v; want 0, nil", len(applied), err)
+	}
+	if _, err = m.Down(ctx, 1); !errors.Is(err, migrate.ErrIrreversible) {
+		t.Errorf("down irreversible: err = %v, want ErrIrreversible", err)
+	}
+
+	// Reversible migrations round-trip.
+	flagProducts := migrate.Migration{
+		Version: 1000,
+		Name:    "flag_products",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("products").UpdateMany(ctx, bson.M{}, bson.M{"$set": bson.M{"flagged": true}})
+			return err
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("products").UpdateMany(ctx, bson.M{}, bson.M{"$unset": bson.M{"flagged": ""}})
+			return err
+		},
+	}
+	m, err = migrate.New(testDB, append(migrations.All(), flagProducts))
+	if err != nil {
+		t.Fatalf("new migrator: %v", err)
+	}
+	if applied, err = m.Up(ctx, 0); err != nil || len(applied) != 1 {
+		t.Fatalf("up flag: applied = %d, err = %v; want 1, nil", len(applied), err)
+	}
+	if n, _ := testDB.Collection("products").CountDocuments(ctx, bson.M{"flagged": true}); n == 0 {
+		t.Error("flag migration did not run")
+	}
+	if _, err = m.Down(ctx, 1); err != nil {
+		t.Fatalf("down flag: %v", err)
+	}
+	if n, _ := testDB.Collection("products").CountDocuments(ctx, bson.M{"flagged": bson.M{"$exists": true}}); n != 0 {
+		t.Errorf("flagged products after down = %d, want 0", n)
+	}
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	if last := statuses[len(statuses)-1]; last.Version != 1000 || last.Applied() {
+		t.Errorf("status after down = %+v, want version 1000 pending", last)
+	}
+}
+
+func TestMigrationsConcurrentRunners(t *testing.T) {
+	setupRouter(t)
+	ctx := context.Background()
+	if _, err := testDB.Collection("schema_migrations").DeleteMany(ctx, bson.M{}); err != nil {
+		t.Fatalf("reset schema_migrations: %v", err)
+	}
+
+	// The first migration blocks until released, so the second runner starts
+	// while the first holds the lock. runs is only read once the first
+	// runner has finished.
+	runs := map[int64]int{}
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var once sync.Once
+	count := func(version int64) migrate.Func {
+		return func(context.Context, *mongo.Database) error {
+			runs[version]++
+			if version == 1 {
+				once.Do(func() { close(started) })
+				<-release
+			}
+			return nil
+		}
+	}
+	set := []migrate.Migration{
+		{Version: 1, Name: "slow", Up: count(1)},
+		{Version: 2, Name: "fast", Up: count(2)},
+	}
+	first, err := migrate.New(testDB, set)
+	if err != nil {
+		t.Fatalf("new migrator: %v", err)
+	}
+	second, err := migrate.New(testDB, set)
+	if err != nil {
+		t.Fatalf("new migrator: %v", err)
+	}
+
+	type result struct {
+		applied []migrate.Migration
+		err     error
+	}
+	done := make(chan result)
+	go func() {
+		applied, err := first.Up(ctx, 0)
+		done <- result{applied, err}
+	}()
+	<-started
+
+	if applied, err := second.Up(ctx, 0); !errors.Is(err, migrate.ErrLocked) || len(applied) != 0 {
+		t.Errorf("second runner while locked: applied = %d, err = %v; want 0, ErrLocked", len(applied), err)
+	}
+	close(release)
+	if r := <-done; r.err != nil || len(r.applied) != 2 {
+		t.Fatalf("first runner: applied = %d, err = %v; want 2, nil", len(r.applied), r.err)
+	}
+
+	// Once the lock is released the second runner finds nothing left to do.
+	if applied, err := second.Up(ctx, 0); err != nil || len(applied) != 0 {
+		t.Errorf("second runner after release: applied = %d, err = %v; want 0, nil", len(applied), err)
+	}
+	for _, version := range []int64{1, 2} {
+		if runs[version] != 1 {
+			t.Errorf("migration %d ran %d times, want 1", version, runs[version])
+		}
+	}
+	if n, _ := testDB.Collection("schema_migrations").CountDocuments(ctx, bson.M{}); n != 2 {
+		t.Errorf("recorded migrations = %d, want 2", n)
+	}
+}
+
+// userID returns the ID of the user with the given email.
+func userID(t *testing.T, email string) string {
+	t.Helper()
+	var u user.User
+	if err := testDB.Collection("users").FindOne(context.Background(), bson.M{"email": email}).Decode(&u); err != nil {
+		t.Fatalf("find user %s: %v", email, err)
+	}
+	return u.ID.Hex()
+}
+
+func categoryID(t *testing.T, slug string) string {
+	t.Helper()
+	var c category.Category
+	if err := testDB.Collection("categories").FindOne(context.Background(), bson.M{"slug": slug}).Decode(&c); err != nil {
+		t.Fatalf("find category %s: %v", slug, err)
+	}
+	return c.ID.Hex()
+}
+
+func TestDataExportAndErasure(t *testing.T) {
+	ts := setupRouter(t)
+	alice := registerAndLogin(t, ts, "alice@example.com")
+	aliceToken := alice["access_token"].(string)
+	meURL := ts.URL + "/api/v1/users/me"
+
+	// Self-service export covers the account, sessions and issued tokens.
+	resp := doAuthed(t, http.MethodGet, meURL+"/export", aliceToken, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("export: status = %d, want 200", resp.StatusCode)
+	}
+	if cd := resp.Header.Get("Content-Disposition"); !strings.HasPrefix(cd, "attachment") {
+		t.Errorf("Content-Disposition = %q, want attachment", cd)
+	}
+	var archive struct {
+		UserID string                     `json:"user_id"`
+		Data   map[string]json.RawMessage `json:"data"`
+	}
+	json.NewDecoder(resp.Body).Decode(&archive)
+	resp.Body.Close()
+	if archive.UserID != userID(t, "alice@example.com") {
+		t.Errorf("user_id = %q, want alice's", archive.UserID)
+	}
+	for _, section := range []string{"account", "sessions", "action_tokens"} {
+		if _, ok := archive.Data[section]; !ok {
+			t.Errorf("export is missing %q", section)
+		}
+	}
+	if !strings.Contains(string(archive.Data["account"]), "alice@example.com") {
+		t.Errorf("account section = %s, want alice's email", archive.Data["account"])
+	}
+	if strings.Contains(string(archive.Data["sessions"]), "token_hash") {
+		t.Error("export leaks refresh token digests")
+	}
+
+	// Erasure requires the current password.
+	resp = doAuthed(t, http.MethodDelete, meURL, aliceToken, map[string]string{"password": "wrong-password1"})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("erase with wrong password: status = %d, want 400", resp.StatusCode)
+	}
+	resp = doAuthed(t, http.MethodDelete, meURL, aliceToken, map[string]string{"password": "kite-orbit-42"})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("erase: status = %d, want 200", resp.StatusCode)
+	}
+
+	loginBody := map[string]string{"email": "alice@example.com", "password": "kite-orbit-42"}
+	resp, _ = http.Post(ts.URL+"/api/v1/auth/login", "application/json", jsonBody(t, loginBody))
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("login after erasure: status = %d, want 401", resp.StatusCode)
+	}
+	refreshBody := map[string]string{"refresh_token": alice["refresh_token"].(string)}
+	resp, _ = http.Post(ts.URL+"/api/v1/auth/refresh", "application/json", jsonBody(t, refreshBody))
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("refresh after erasure: status = %d, want 401", resp.StatusCode)
+	}
+
+	// The email address is free again.
+	registerAndLogin(t, ts, "alice@example.com")
+
+	// Administrators can act on a user's behalf, but not on themselves.
+	registerAndLogin(t, ts, "admin@example.com")
+	setRole(t, "admin@example.com", role.Admin)
+	admin := login(t, ts, "admin@example.com", "kite-orbit-42")["access_token"].(string)
+	registerAndLogin(t, ts, "bob@example.com")
+	bobID := userID(t, "bob@example.com")
+	usersURL := ts.URL + "/api/v1/admin/users/"
+
+	resp = doAuthed(t, http.MethodGet, usersURL+bobID+"/export", admin, nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("admin export: status = %d, want 200", resp.StatusCode)
+	}
+	resp = doAuthed(t, http.MethodGet, usersURL+primitive.NewObjectID().Hex()+"/export", admin, nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("admin export unknown user: status = %d, want 404", resp.StatusCode)
+	}
+	resp = doAuthed(t, http.MethodDelete, usersURL+userID(t, "admin@example.com"), admin, nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("admin erasing self: status = %d, want 403", resp.StatusCode)
+	}
+	resp = doAuthed(t, http.MethodDelete, usersURL+bobID, admin, nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("admin erase: status = %d, want 200", resp.StatusCode)
+	}
+
+	oid, _ := primitive.ObjectIDFromHex(bobID)
+	var bob user.User
+	if err := testDB.Collection("users").FindOne(context.Background(), bson.M{"_id": oid}).Decode(&bob); err != nil {
+		t.Fatalf("find erased user: %v", err)
+	}
+	if bob.Name != user.ErasedName || bob.Email == "bob@example.com" || !bob.Disabled {
+		t.Errorf("erased user = %+v, want anonymized and disabled", bob)
+	}
+	if n, _ := testDB.Collection("refresh_tokens").CountDocuments(context.Background(), bson.M{"user_id": oid}); n != 0 {
+		t.Errorf("refresh tokens after erasure = %d, want 0", n)
+	}
+}
+
+func TestPasswordRehashOnLogin(t *testing.T) {
+	ts := setupRouter(t)
+	registerAndLogin(t, ts, "legacy@example.com")
+
+	// Store the password as a plain bcrypt hash, as older versions did.
+	legacy, err := bcrypt.GenerateFromPassword([]byte("kite-orbit-42"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("bcrypt: %v", err)
+	}
+	users := testDB.Collection("users")
+	_, err = users.UpdateOne(context.Background(),
+		bson.M{"email": "legacy@example.com"}, bson.M{"$set": bson.M{"password_hash": string(legacy)}})
+	if err != nil {
+		t.Fatalf("set legacy hash: %v", err)
+	}
+
+	login(t, ts, "legacy@example.com", "kite-orbit-42")
+
+	var u user.User
+	if err = users.FindOne(context.Background(), bson.M{"email": "legacy@example.com"}).Decode(&u); err != nil {
+		t.Fatalf("find user: %v", err)
+	}
+	if !strings.HasPrefix(u.PasswordHash, "$argon2id$") {
+		t.Errorf("hash after login = %q, want argon2id", u.PasswordHash)
+	}
+	login(t, ts, "legacy@example.com", "kite-orbit-42")
+}
+
+func TestAPIKeys(t *testing.T) {
+	ts := setupRouter(t)
+	registerAndLogin(t, ts, "admin@example.com")
+	setRole(t, "admin@example.com", role.Admin)
+	admin := login(t, ts, "admin@example.com", "kite-orbit-42")["access_token"].(string)
+	keysURL := ts.URL + "/api/v1/admin/api-keys"
+
+	decode := func(resp *http.Response) map[string]interface{} {
+		t.Helper()
+		defer resp.Body.Close()
+		var body map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&body)
+		return body
+	}
+	// withKey sends a JSON request authenticated by an API key header.
+	withKey := func(method, url, header, value string, body interface{}) int {
+		t.Helper()
+		req, err := http.NewRequest(method, url, jsonBody(t, body))
+		if err != nil {
+			t.Fatalf("new request: %v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(header, value)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s error: %v", method, url, err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// Scopes must be required by a route that accepts keys, never
+	// wildcards, and expiry must be in the future.
+	for _, scope := range []string{"pos:everything", role.PermAll, "products:*", role.PermOrdersRefund} {
+		resp := postAuthed(t, keysURL, admin, map[string]interface{}{"name": "Till 1", "scopes": []string{scope}})
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("scope %q: status = %d, want 400", scope, resp.StatusCode)
+		}
+		resp.Body.Close()
+	}
+	past := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	resp := postAuthed(t, keysURL, admin, map[string]interface{}{"name": "Till 1", "scopes": []string{role.PermProductsWrite}, "expires_at": past})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("past expiry: status = %d, want 400", resp.StatusCode)
+	}
+	resp.Body.Close()
+
+	resp = postAuthed(t, keysURL, admin, map[string]interface{}{"name": "Till 1", "scopes": []string{role.PermProductsWrite}})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create key: status = %d, want 201", resp.StatusCode)
+	}
+	created := decode(resp)
+	key, _ := created["key"].(string)
+	keyID := created["id"].(string)
+	if !strings.HasPrefix(key, "fsk_") || !strings.HasPrefix(key, created["prefix"].(string)) {
+		t.Fatalf("key = %q, prefix = %v", key, created["prefix"])
+	}
+
+	// Both header forms authenticate on routes that accept keys.
+	product := map[string]interface{}{"name": "Till Pizza", "price_cents": 900, "category_id": categoryID(t, "pizza")}
+	if status := withKey(http.MethodPost, ts.URL+"/api/v1/products", "Authorization", "ApiKey "+key, product); status != http.StatusCreated {
+		t.Errorf("create product with Authorization: status = %d, want 201", status)
+	}
+	if status := withKey(http.MethodPost, ts.URL+"/api/v1/products", "X-API-Key", key, product); status != http.StatusCreated {
+		t.Errorf("create product with X-API-Key: status = %d, want 201", status)
+	}
+
+	// Keys do not open routes that only accept users.
+	if status := withKey(http.MethodGet, ts.URL+"/api/v1/users/me", "X-API-Key", key, nil); status != http.StatusUnauthorized {
+		t.Errorf("profile with key: status = %d, want 401", status)
+	}
+	if status := withKey(http.MethodGet, keysURL, "Authorization", "ApiKey "+key, nil); status != http.StatusUnauthorized {
+		t.Errorf("key management with key: status = %d, want 401", status)
+	}
+
+	// Key managers cannot mint keys with scopes they do not hold.
+	resp = postAuthed(t, ts.URL+"/api/v1/admin/roles", admin, map[string]interface{}{
+		"name": "key-manager", "permissions": []string{role.PermAPIKeysManage},
+	})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create role: status = %d, want 201", resp.StatusCode)
+	}
+	registerAndLogin(t, ts, "keys@example.com")
+	setRole(t, "keys@example.com", "key-manager")
+	keyManager := login(t, ts, "keys@example.com", "kite-orbit-42")["access_token"].(string)
+	resp = postAuthed(t, keysURL, keyManager, map[string]interface{}{"name": "Sneaky till", "scopes": []string{role.PermProductsWrite}})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("mint unheld scope: status = %d, want 403", resp.StatusCode)
+	}
+
+	// The key is never shown again, but its use is.
+	resp = doAuthed(t, http.MethodGet, keysURL, admin, nil)
+	items := decode(resp)["items"].([]interface{})
+	if len(items) != 1 {
+		t.Fatalf("listed %d keys, want 1", len(items))
+	}
+	till := items[0].(map[string]interface{})
+	if _, ok := till["key"]; ok {
+		t.Error("list exposes the key")
+	}
+	if till["id"] != keyID || till["use_count"] != float64(2) || till["last_used_at"] == nil {
+		t.Errorf("listed key = %v, want 2 recorded uses", till)
+	}
+
+	// Revoked keys stop working immediately.
+	resp = doAuthed(t, http.MethodDelete, keysURL+"/"+keyID, admin, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("revoke: status = %d, want 200", resp.StatusCode)
+	}
+	resp.Body.Close()
+	if status := withKey(http.MethodPost, ts.URL+"/api/v1/products", "X-API-Key", key, product); status != http.StatusUnauthorized {
+		t.Errorf("create product with revoked key: status = %d, want 401", status)
+	}
+	resp = doAuthed(t, http.MethodDelete, keysURL+"/"+keyID, admin, nil)
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("revoke twice: status = %d, want 409", resp.StatusCode)
+	}
+	resp.Body.Close()
+}
+
+// mockOIDC is a minimal OpenID Connect provider for login tests. Every
+// authorization request is approved as the identity in next.
+type mockOIDC struct {
+	*httptest.Server
+	t   *testing.T
+	key *rsa.PrivateKey
+
+	mu    sync.Mutex
+	next  map[string]interface{} // claims for the next authorization
+	nonce string                 // if set, replaces the nonce in issued ID tokens
+	codes map[string]mockGrant
+}
+
+// mockGrant is an issued, not yet redeemed authorization code.
+type mockGrant struct {
+	claims      map[string]interface{}
+	nonce       string
+	challenge   string
+	redirectURI string
+}
+
+const (
+	mockClientID     = "food-service"
+	mockClientSecret = "mock-secret"
+	mockRedirectURL  = "http://app.example/login/callback"
+)
+
+func newMockOIDC(t *testing.T) *mockOIDC {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	m := &mockOIDC{t: t, key: key, codes: map[string]mockGrant{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.URL,
+			"authorization_endpoint": m.URL + "/authorize",
+			"token_endpoint":         m.URL + "/token",
+			"jwks_uri":               m.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		pub := m.key.PublicKey
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "mock-1",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/authorize", m.authorize)
+	mux.HandleFunc("/token", m.token)
+
+	m.Server = httptest.NewServer(mux)
+	t.Cleanup(m.Close)
+	return m
+}
+
+// as sets the identity the provider asserts from now on.
+func (m *mockOIDC) as(sub, email string, verified bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.next = map[string]interface{}{"sub": sub, "email": email, "email_verified": verified, "name": "Mock User"}
+}
+
+func (m *mockOIDC) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("client_id") != mockClientID || q.Get("response_type") != "code" || q.Get("code_challenge_method") != "S256" {
+		http.Error(w, "bad authorization request", http.StatusBadRequest)
+		return
+	}
+
+	m.mu.Lock()
+	code := primitive.NewObjectID().Hex()
+	m.codes[code] = mockGrant{
+		claims:      m.next,
+		nonce:       q.Get("nonce"),
+		challenge:   q.Get("code_challenge"),
+		redirectURI: q.Get("redirect_uri"),
+	}
+	m.mu.Unlock()
+
+	http.Redirect(w, r, q.Get("redirect_uri")+"?code="+code+"&state="+q.Get("state"), http.StatusFound)
+}
+
+func (m *mockOIDC) token(w http.ResponseWriter, r *http.Request) {
+	id, secret, ok := r.BasicAuth()
+	if !ok || id != mockClientID || secret != mockClientSecret {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	m.mu.Lock()
+	grant, found := m.codes[r.PostFormValue("code")]
+	delete(m.codes, r.PostFormValue("code"))
+	nonce := m.nonce
+	m.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+	if !found || grant.redirectURI != r.PostFormValue("redirect_uri") ||
+		base64.RawURLEncoding.EncodeToString(sum[:]) != grant.challenge {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"iss":   m.URL,
+		"aud":   mockClientID,
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(5 * time.Minute).Unix(),
+		"nonce": grant.nonce,
+	}
+	if nonce != "" {
+		claims["nonce"] = nonce
+	}
+	for k, v := range grant.claims {
+		claims[k] = v
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "mock-1"
+	signed, err := token.SignedString(m.key)
+	if err != nil {
+		m.t.Errorf("sign id token: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"access_token": "mock-access", "token_type": "Bearer", "id_token": signed})
+}
+
+// oidcLogin runs a login through the mock provider and returns the
+// callback's status and body.
+func oidcLogin(t *testing.T, ts *httptest.Server) (int, map[string]interface{}) {
+	t.Helper()
+
+	resp, err := http.Post(ts.URL+"/api/v1/auth/oidc/mock/start", "application/json", nil)
+	if err != nil {
+		t.Fatalf("start error: %v", err)
+	}
+	var start map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&start)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("start status = %d, want 200", resp.StatusCode)
+	}
+
+	// Play the browser: visit the provider and capture where it redirects.
+	noFollow := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err = noFollow.Get(start["authorization_url"].(string))
+	if err != nil {
+		t.Fatalf("authorize error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize status = %d, want 302", resp.StatusCode)
+	}
+	location, err := resp.Location()
+	if err != nil {
+		t.Fatalf("authorize location: %v", err)
+	}
+	if got := location.Query().Get("state"); got != start["state"] {
+		t.Fatalf("redirect state = %q, want %q", got, start["state"])
+	}
+
+	return oidcCallback(t, ts, location.Query().Get("code"), location.Query().Get("state"))
+}
+
+// oidcCallback posts the parameters of a provider redirect to the callback.
+func oidcCallback(t *testing.T, ts *httptest.Server, code, state string) (int, map[string]interface{}) {
+	t.Helper()
+
+	body := map[string]string{"code": code, "state": state}
+	resp, err := http.Post(ts.URL+"/api/v1/auth/oidc/mock/callback", "application/json", jsonBody(t, body))
+	if err != nil {
+		t.Fatalf("callback error: %v", err)
+	}
+	defer resp.Body.Close()
+	var out map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&out)
+	return resp.StatusCode, out
+}
+
+func TestOIDCLogin(t *testing.T) {
+	provider := newMockOIDC(t)
+	ts := setupRouterWith(t, func(cfg *config.Config) {
+		cfg.OIDCProviders = []config.OIDCProvider{{
+			Name:         "mock",
+			Issuer:       provider.URL,
+			ClientID:     mockClientID,
+			ClientSecret: mockClientSecret,
+			RedirectURL:  mockRedirectURL,
+			Scopes:       []string{"openid", "email"},
+		}}
+	})
+
+	resp, err := http.Get(ts.URL + "/api/v1/auth/oidc/providers")
+	if err != nil {
+		t.Fatalf("providers error: %v", err)
+	}
+	var providers map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&providers)
+	resp.Body.Close()
+	if items := providers["items"].([]interface{}); len(items) != 1 || items[0].(map[string]interface{})["name"] != "mock" {
+		t.Errorf("providers = %v, want [mock]", providers)
+	}
+	resp, _ = http.Post(ts.URL+"/api/v1/auth/oidc/nope/start", "application/json", nil)
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown provider: status = %d, want 404", resp.StatusCode)
+	}
+	resp.Body.Close()
+
+	// A new identity signs up with a verified, passwordless account.
+	provider.as("mock-sub-1", "Sso.User@example.com", true)
+	status, tokens := oidcLogin(t, ts)
+	if status != http.StatusOK || tokens["access_token"] == nil || tokens["refresh_token"] == nil {
+		t.Fatalf("first login: status = %d, body = %v", status, tokens)
+	}
+	claims := accessClaims(t, tokens["access_token"].(string))
+	if claims["email"] != "sso.user@example.com" || claims["email_verified"] != true {
+		t.Errorf("claims = %v, want verified sso.user@example.com", claims)
+	}
+	firstID := claims["sub"]
+
+	resp = doAuthed(t, http.MethodGet, ts.URL+"/api/v1/users/me", tokens["access_token"].(string), nil)
+	var me map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&me)
+	resp.Body.Close()
+	identities, _ := me["identities"].([]interface{})
+	if len(identities) != 1 || identities[0].(map[string]interface{})["provider"] != "mock" {
+		t.Errorf("identities = %v, want one mock identity", me["identities"])
+	}
+	if _, ok := identities[0].(map[string]interface{})["subject"]; ok {
+		t.Error("profile exposes the provider subject")
+	}
+
+	// The same identity logs in as the same user, even after an email change.
+	provider.as("mock-sub-1", "renamed@example.com", true)
+	status, tokens = oidcLogin(t, ts)
+	if status != http.StatusOK || accessClaims(t, tokens["access_token"].(string))["sub"] != firstID {
+		t.Errorf("repeat login: status = %d, sub = %v, want %v", status, tokens, firstID)
+	}
+
+	// The account has no password to log in with.
+	body := map[string]string{"email": "sso.user@example.com", "password": "kite-orbit-42"}
+	resp, _ = http.Post(ts.URL+"/api/v1/auth/login", "application/json", jsonBody(t, body))
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("password login to a passwordless account: status = %d, want 401", resp.StatusCode)
+	}
+	resp.Body.Close()
+
+	// A verified local account is linked; an unverified one is not.
+	registerAndLogin(t, ts, "local@example.com")
+	setVerified := func(verified bool) {
+		t.Helper()
+		_, err := testDB.Collection("users").UpdateOne(context.Background(),
+			bson.M{"email": "local@example.com"}, bson.M{"$set": bson.M{"email_verified": verified}, "$unset": bson.M{"identities": ""}})
+		if err != nil {
+			t.Fatalf("set email_verified: %v", err)
+		}
+	}
+	setVerified(true)
+	provider.as("mock-sub-2", "local@example.com", true)
+	status, tokens = oidcLogin(t, ts)
+	if status != http.StatusOK || accessClaims(t, tokens["access_token"].(string))["sub"] != userID(t, "local@example.com") {
+		t.Errorf("link verified account: status = %d, body = %v", status, tokens)
+	}
+
+	setVerified(false)
+	if status, body := oidcLogin(t, ts); status != http.StatusConflict {
+		t.Errorf("link unverified account: status = %d, body = %v, want 409", status, body)
+	}
+
+	// Providers must vouch for the email address of a new identity.
+	provider.as("mock-sub-3", "unverified@example.com", false)
+	if status, _ := oidcLogin(t, ts); status != http.StatusForbidden {
+		t.Errorf("unverified provider email: status = %d, want 403", status)
+	}
+
+	// ID tokens are bound to the login that requested them.
+	provider.as("mock-sub-1", "sso.user@example.com", true)
+	provider.mu.Lock()
+	provider.nonce = "someone-elses-nonce"
+	provider.mu.Unlock()
+	if status, _ := oidcLogin(t, ts); status != http.StatusUnauthorized {
+		t.Errorf("wrong nonce: status = %d, want 401", status)
+	}
+	provider.mu.Lock()
+	provider.nonce = ""
+	provider.mu.Unlock()
+
+	// States are single-use, and unknown states are refused.
+	resp, _ = http.Post(ts.URL+"/api/v1/auth/oidc/mock/start", "application/json", nil)
+	var start map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&start)
+	resp.Body.Close()
+	noFollow := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, _ = noFollow.Get(start["authorization_url"].(string))
+	resp.Body.Close()
+	location, _ := resp.Location()
+	code, state := location.Query().Get("code"), location.Query().Get("state")
+	if status, _ := oidcCallback(t, ts, code, "forged-state"); status != http.StatusBadRequest {
+		t.Errorf("forged state: status = %d, want 400", status)
+	}
+	if status, _ := oidcCallback(t, ts, code, state); status != http.StatusOK {
+		t.Errorf("callback: status = %d, want 200", status)
+	}
+	if status, _ := oidcCallback(t, ts, code, state); status != http.StatusBadRequest {
+		t.Errorf("replayed state: status = %d, want 400", status)
+	}
+}
+
+func TestOIDCUserErasesOwnAccount(t *testing.T) {
+	provider := newMockOIDC(t)
+	ts := setupRouterWith(t, func(cfg *config.Config) {
+		cfg.OIDCProviders = []config.OIDCProvider{{
+			Name:         "mock",
+			Issuer:       provider.URL,
+			ClientID:     mockClientID,
+			ClientSecret: mockClientSecret,
+			RedirectURL:  mockRedirectURL,
+			Scopes:       []string{"openid", "email"},
+		}}
+	})
+	provider.as("mock-sub-erase", "sso.erase@example.com", true)
+	status, tokens := oidcLogin(t, ts)
+	if status != http.StatusOK {
+		t.Fatalf("login: status = %d, body = %v", status, tokens)
+	}
+	meURL := ts.URL + "/api/v1/users/me"
+
+	// Age the session past RecentSignIn: sessions keep their ID, and so
+	// their sign-in time, when refreshed.
+	ctx := context.Background()
+	uid, _ := primitive.ObjectIDFromHex(userID(t, "sso.erase@example.com"))
+	stale := primitive.NewObjectIDFromTimestamp(time.Now().Add(-auth.RecentSignIn - time.Minute))
+	if _, err := testDB.Collection("refresh_tokens").UpdateMany(ctx, bson.M{"user_id": uid}, bson.M{"$set": bson.M{"family_id": stale}}); err != nil {
+		t.Fatalf("age session: %v", err)
+	}
+	resp, err := http.Post(ts.URL+"/api/v1/auth/refresh", "application/json", jsonBody(t, map[string]string{"refresh_token": tokens["refresh_token"].(string)}))
+	if err != nil {
+		t.Fatalf("refresh error: %v", err)
+	}
+	var refreshed map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&refreshed)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("refresh: status = %d, want 200", resp.StatusCode)
+	}
+
+	// Without a password to confirm, an old session must sign in again. The
+	// refusals are not failed logins and never lock the account.
+	for i := 0; i < 6; i++ {
+		resp = doAuthed(t, http.MethodDelete, meURL, refreshed["access_token"].(string), map[string]string{"password": "guess"})
+		var errBody map[string]map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&errBody)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden || errBody["error"]["code"] != "RECENT_SIGN_IN_REQUIRED" {
+			t.Fatalf("erase with old session: status = %d, code = %v; want 403 RECENT_SIGN_IN_REQUIRED", resp.StatusCode, errBody["error"]["code"])
+		}
+	}
+	if n, _ := testDB.Collection("login_attempts").CountDocuments(ctx, bson.M{}); n != 0 {
+		t.Errorf("login attempts recorded = %d, want 0", n)
+	}
+
+	// A fresh sign-in with the provider confirms the erasure.
+	status, tokens = oidcLogin(t, ts)
+	if status != http.StatusOK {
+		t.Fatalf("second login: status = %d, body = %v", status, tokens)
+	}
+	resp = doAuthed(t, http.MethodDelete, meURL, tokens["access_token"].(string), map[string]string{})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("erase after sign-in: status = %d, want 200", resp.StatusCode)
+	}
+	var u user.User
+	if err = testDB.Collection("users").FindOne(ctx, bson.M{"_id": uid}).Decode(&u); err != nil {
+		t.Fatalf("reload user: %v", err)
+	}
+	if u.ErasedAt == nil || len(u.Identities) != 0 {
+		t.Errorf("erased user = %+v, want anonymized without identities", u)
+	}
+}
+
+func TestProductGetAndSlugs(t *testing.T) {
+	ts := setupRouter(t)
+	registerAndLogin(t, ts, "admin@example.com")
+	setRole(t, "admin@example.com", role.Admin)
+	admin := login(t, ts, "admin@example.com", "kite-orbit-42")["access_token"].(string)
+	productsURL := ts.URL + "/api/v1/products"
+
+	decode := func(resp *http.Response) map[string]interface{} {
+		t.Helper()
+		defer resp.Body.Close()
+		var body map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&body)
+		return body
+	}
+	get := func(url string) (int, map[string]interface{}) {
+		t.Helper()
+		resp, err := http.Get(url)
+		if err != nil {
+			t.Fatalf("GET %s error: %v", url, err)
+		}
+		return resp.StatusCode, decode(resp)
+	}
+
+	// A name that is already taken gets a numbered slug.
+	resp := postAuthed(t, productsURL, admin, map[string]interface{}{"name": "Margherita Pizza", "price_cents": 1100, "category_id": categoryID(t, "pizza")})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create: status = %d, want 201", resp.StatusCode)
+	}
+	created := decode(resp)
+	id := created["id"].(string)
+	if created["slug"] != "margherita-pizza-2" {
+		t.Errorf("slug = %v, want margherita-pizza-2", created["slug"])
+	}
+
+	if status, body := get(productsURL + "/" + id); status != http.StatusOK || body["name"] != "Margherita Pizza" {
+		t.Errorf("get by id: status = %d, body = %v", status, body)
+	}
+	for _, bad := range []string{"not-an-id", primitive.NewObjectID().Hex()} {
+		if status, _ := get(productsURL + "/" + bad); status != http.StatusNotFound {
+			t.Errorf("get %s: status = %d, want 404", bad, status)
+		}
+	}
+	if status, body := get(productsURL + "/by-slug/margherita-pizza-2"); status != http.StatusOK || body["id"] != id {
+		t.Errorf("get by slug: status = %d, body = %v", status, body)
+	}
+	if status, _ := get(productsURL + "/by-slug/no-such-pizza"); status != http.StatusNotFound {
+		t.Errorf("unknown slug: status = %d, want 404", status)
+	}
+
+	// Renaming changes the slug; the old one redirects.
+	resp = doAuthed(t, http.MethodPut, productsURL+"/"+id, admin, map[string]interface{}{"name": "Pizza Napoletana"})
+	if updated := decode(resp); updated["slug"] != "pizza-napoletana" {
+		t.Errorf("renamed slug = %v, want pizza-napoletana", updated["slug"])
+	}
+	noFollow := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := noFollow.Get(productsURL + "/by-slug/margherita-pizza-2")
+	if err != nil {
+		t.Fatalf("get old slug: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMovedPermanently || resp.Header.Get("Location") != "/api/v1/products/by-slug/pizza-napoletana" {
+		t.Errorf("old slug: status = %d, location = %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	if status, body := get(productsURL + "/by-slug/margherita-pizza-2"); status != http.StatusOK || body["id"] != id {
+		t.Errorf("follow old slug: status = %d, body = %v", status, body)
+	}
+
+	// A change that keeps the slug does not add a redirect.
+	resp = doAuthed(t, http.MethodPut, productsURL+"/"+id, admin, map[string]interface{}{"name": "PIZZA NAPOLETANA", "price_cents": 1200})
+	if updated := decode(resp); updated["slug"] != "pizza-napoletana" {
+		t.Errorf("slug after case change = %v, want pizza-napoletana", updated["slug"])
+	}
+
+	// A new product can take a slug given up by a renamed one.
+	resp = postAuthed(t, productsURL, admin, map[string]interface{}{"name": "Margherita Pizza", "price_cents": 1000, "category_id": categoryID(t, "pizza")})
+	second := decode(resp)
+	if second["slug"] != "margherita-pizza-2" {
+		t.Fatalf("second slug = %v, want margherita-pizza-2", second["slug"])
+	}
+	resp, err = noFollow.Get(productsURL + "/by-slug/margherita-pizza-2")
+	if err != nil {
+		t.Fatalf("get reused slug: %v", err)
+	}
+	if body := decode(resp); resp.StatusCode != http.StatusOK || body["id"] != second["id"] {
+		t.Errorf("reused slug: status = %d, body = %v", resp.StatusCode, body)
+	}
+}
+
+func TestCategories(t *testing.T) {
+	ts := setupRouter(t)
+	registerAndLogin(t, ts, "admin@example.com")
+	setRole(t, "admin@example.com", role.Admin)
+	admin := login(t, ts, "admin@example.com", "kite-orbit-42")["access_token"].(string)
+	customer := registerAndLogin(t, ts, "customer@example.com")["access_token"].(string)
+	categoriesURL := ts.URL + "/api/v1/categories"
+	pizzaID := categoryID(t, "pizza")
+
+	decode := func(resp *http.Response) map[string]interface{} {
+		t.Helper()
+		defer resp.Body.Close()
+		var body map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&body)
+		return body
+	}
+	tree := func(url, token string) []interface{} {
+		t.Helper()
+		resp := doAuthed(t, http.MethodGet, url, token, nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET %s status = %d, want 200", url, resp.StatusCode)
+		}
+		return decode(resp)["items"].([]interface{})
+	}
+	listTotal := func(query string) float64 {
+		t.Helper()
+		resp, err := http.Get(ts.URL + "/api/v1/products?" + query)
+		if err != nil {
+			t.Fatalf("GET /products?%s error: %v", query, err)
+		}
+		return decode(resp)["total"].(float64)
+	}
+
+	// Managing categories needs the catalog permission.
+	resp := postAuthed(t, categoriesURL, customer, map[string]interface{}{"name": "Specials"})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("customer create: status = %d, want 403", resp.StatusCode)
+	}
+
+	// Names must make a slug no other category has.
+	resp = postAuthed(t, categoriesURL, admin, map[string]interface{}{"name": "PIZZA"})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("duplicate name: status = %d, want 409", resp.StatusCode)
+	}
+	resp = postAuthed(t, categoriesURL, admin, map[string]interface{}{"name": "Veggie", "parent_id": primitive.NewObjectID().Hex()})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unknown parent: status = %d, want 400", resp.StatusCode)
+	}
+
+	resp = postAuthed(t, categoriesURL, admin, map[string]interface{}{"name": "Veggie Pizza", "parent_id": pizzaID, "icon": "leaf"})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create subcategory: status = %d, want 201", resp.StatusCode)
+	}
+	veggie := decode(resp)
+	veggieID := veggie["id"].(string)
+	if veggie["slug"] != "veggie-pizza" || veggie["parent_id"] != pizzaID || veggie["active"] != true {
+		t.Errorf("created category = %v", veggie)
+	}
+
+	// Products must reference an existing category.
+	resp = postAuthed(t, ts.URL+"/api/v1/products", admin, map[string]interface{}{"name": "Ghost Pizza", "price_cents": 900, "category_id": primitive.NewObjectID().Hex()})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unknown category: status = %d, want 400", resp.StatusCode)
+	}
+	resp = postAuthed(t, ts.URL+"/api/v1/products", admin, map[string]interface{}{"name": "Garden Pizza", "price_cents": 1100, "category_id": veggieID})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create product: status = %d, want 201", resp.StatusCode)
+	}
+	garden := decode(resp)
+	if garden["category_id"] != veggieID {
+		t.Errorf("product category_id = %v, want %s", garden["category_id"], veggieID)
+	}
+
+	// Filtering by a category, by slug or ID, includes its subcategories.
+	if total := listTotal("category=pizza"); total != 2 {
+		t.Errorf("pizza by slug total = %v, want 2", total)
+	}
+	if total := listTotal("category=" + pizzaID); total != 2 {
+		t.Errorf("pizza by id total = %v, want 2", total)
+	}
+	if total := listTotal("category=veggie-pizza"); total != 1 {
+		t.Errorf("veggie-pizza total = %v, want 1", total)
+	}
+	if total := listTotal("category=unknown"); total != 0 {
+		t.Errorf("unknown category total = %v, want 0", total)
+	}
+
+	// The tree is ordered by position and counts products below each node.
+	items := tree(categoriesURL, "")
+	if len(items) != 3 {
+		t.Fatalf("top-level categories = %d, want 3", len(items))
+	}
+	pizza := items[1].(map[string]interface{})
+	if pizza["slug"] != "pizza" || pizza["product_count"] != float64(2) {
+		t.Errorf("pizza node = %v", pizza)
+	}
+	children := pizza["children"].([]interface{})
+	if len(children) != 1 || children[0].(map[string]interface{})["product_count"] != float64(1) {
+		t.Errorf("pizza children = %v", children)
+	}
+
+	// A category cannot move below itself.
+	resp = doAuthed(t, http.MethodPut, categoriesURL+"/"+pizzaID, admin, map[string]interface{}{"parent_id": veggieID})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("cycle: status = %d, want 400", resp.StatusCode)
+	}
+
+	// Inactive categories and their products leave the public tree only.
+	resp = doAuthed(t, http.MethodPut, categoriesURL+"/"+veggieID, admin, map[string]interface{}{"active": false, "position": 3})
+	if body := decode(resp); resp.StatusCode != http.StatusOK || body["active"] != false || body["position"] != float64(3) {
+		t.Fatalf("deactivate: status = %d, body = %v", resp.StatusCode, body)
+	}
+	pizza = tree(categoriesURL, "")[1].(map[string]interface{})
+	if len(pizza["children"].([]interface{})) != 0 || pizza["product_count"] != float64(1) {
+		t.Errorf("public pizza node with inactive child = %v", pizza)
+	}
+	pizza = tree(ts.URL+"/api/v1/admin/categories", admin)[1].(map[string]interface{})
+	if len(pizza["children"].([]interface{})) != 1 {
+		t.Errorf("admin tree hides inactive child: %v", pizza)
+	}
+
+	// Categories in use cannot be deleted.
+	for _, id := range []string{pizzaID, veggieID} {
+		resp = doAuthed(t, http.MethodDelete, categoriesURL+"/"+id, admin, nil)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusConflict {
+			t.Errorf("delete in-use %s: status = %d, want 409", id, resp.StatusCode)
+		}
+	}
+	resp = doAuthed(t, http.MethodDelete, ts.URL+"/api/v1/products/"+garden["id"].(string), admin, nil)
+	resp.Body.Close()
+	resp = doAuthed(t, http.MethodDelete, categoriesURL+"/"+veggieID, admin, nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("delete empty category: status = %d, want 200", resp.StatusCode)
+	}
+	resp, err := http.Get(categoriesURL + "/" + veggieID)
+	if err != nil {
+		t.Fatalf("GET category error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("deleted category: status = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestProductVariants(t *testing.T) {
+	ts := setupRouter(t)
+	registerAndLogin(t, ts, "admin@example.com")
+	setRole(t, "admin@example.com", role.Admin)
+	admin := login(t, ts, "admin@example.com", "kite-orbit-42")["access_token"].(string)
+	productsURL := ts.URL + "/api/v1/products"
+	pizza := categoryID(t, "pizza")
+
+	decode := func(resp *http.Response) map[string]interface{} {
+		t.Helper()
+		defer resp.Body.Close()
+		var body map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&body)
+		return body
+	}
+	sizes := []map[string]interface{}{
+		{"sku": "pep-s", "name": "Small", "price_cents": 900},
+		{"sku": "pep-m", "name": "Medium", "price_cents": 1200},
+		{"sku": "pep-l", "name": "Large", "price_cents": 1500, "is_available": false},
+	}
+
+	resp := postAuthed(t, productsURL, admin, map[string]interface{}{"name": "Pepperoni Pizza", "category_id": pizza, "variants": sizes})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create with variants: status = %d, want 201", resp.StatusCode)
+	}
+	pepperoni := decode(resp)
+	variants := pepperoni["variants"].([]interface{})
+	if len(variants) != 3 || pepperoni["price_cents"] != float64(900) {
+		t.Fatalf("created product = %v", pepperoni)
+	}
+	if v := variants[2].(map[string]interface{}); v["sku"] != "PEP-L" || v["is_available"] != false {
+		t.Errorf("large variant = %v", v)
+	}
+
+	// Invalid variant lists are rejected.
+	for name, body := range map[string]map[string]interface{}{
+		"price beside variants": {"name": "Odd Pizza", "category_id": pizza, "price_cents": 100, "variants": sizes[:1]},
+		"repeated sku":          {"name": "Odd Pizza", "category_id": pizza, "variants": []map[string]interface{}{sizes[0], {"sku": "PEP-S", "name": "Other"}}},
+		"malformed sku":         {"name": "Odd Pizza", "category_id": pizza, "variants": []map[string]interface{}{{"sku": "pep s", "name": "Small"}}},
+	} {
+		resp = postAuthed(t, productsURL, admin, body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", name, resp.StatusCode)
+		}
+	}
+	resp = postAuthed(t, productsURL, admin, map[string]interface{}{"name": "Copy Pizza", "category_id": pizza, "variants": sizes[1:2]})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("sku of another product: status = %d, want 409", resp.StatusCode)
+	}
+
+	// A single price becomes one "Regular" variant.
+	resp = postAuthed(t, productsURL, admin, map[string]interface{}{"name": "Garlic Bread", "category_id": pizza, "price_cents": 450})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create single price: status = %d, want 201", resp.StatusCode)
+	}
+	bread := decode(resp)
+	if v := bread["variants"].([]interface{}); len(v) != 1 || v[0].(map[string]interface{})["sku"] != "GARLIC-BREAD" {
+		t.Errorf("single-price variants = %v", v)
+	}
+
+	// Sorting by price uses the lowest variant price.
+	resp, err := http.Get(productsURL + "?sort=price,asc&page_size=50")
+	if err != nil {
+		t.Fatalf("GET sorted products error: %v", err)
+	}
+	var names []string
+	for _, item := range decode(resp)["items"].([]interface{}) {
+		names = append(names, item.(map[string]interface{})["name"].(string))
+	}
+	want := []string{"Garlic Bread", "Caesar Salad", "Pepperoni Pizza", "Classic Burger", "Margherita Pizza"}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("sorted by price = %v, want %v", names, want)
+	}
+
+	// price_cents only changes products with a single variant.
+	pepURL := productsURL + "/" + pepperoni["id"].(string)
+	resp = doAuthed(t, http.MethodPut, pepURL, admin, map[string]interface{}{"price_cents": 1000})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("price on multi-variant product: status = %d, want 400", resp.StatusCode)
+	}
+	resp = doAuthed(t, http.MethodPut, productsURL+"/"+bread["id"].(string), admin, map[string]interface{}{"price_cents": 500})
+	if body := decode(resp); body["price_cents"] != float64(500) || body["variants"].([]interface{})[0].(map[string]interface{})["price_cents"] != float64(500) {
+		t.Errorf("single-price update = %v", body)
+	}
+
+	// Replacing the variants recomputes the price and frees dropped SKUs.
+	resp = doAuthed(t, http.MethodPut, pepURL, admin, map[string]interface{}{"variants": sizes[1:]})
+	if body := decode(resp); resp.StatusCode != http.StatusOK || body["price_cents"] != float64(1200) {
+		t.Errorf("replace variants: status = %d, body = %v", resp.StatusCode, body)
+	}
+	resp = postAuthed(t, productsURL, admin, map[string]interface{}{"name": "Mini Pizza", "category_id": pizza, "variants": sizes[:1]})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("reuse dropped sku: status = %d, want 201", resp.StatusCode)
+	}
+}
+
+func TestModifierGroupsAndPricing(t *testing.T) {
+	ts := setupRouter(t)
+	registerAndLogin(t, ts, "admin@example.com")
+	setRole(t, "admin@example.com", role.Admin)
+	admin := login(t, ts, "admin@example.com", "kite-orbit-42")["access_token"].(string)
+	groupsURL := ts.URL + "/api/v1/modifier-groups"
+	productsURL := ts.URL + "/api/v1/products"
+
+	decode := func(resp *http.Response) map[string]interface{} {
+		t.Helper()
+		defer resp.Body.Close()
+		var body map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&body)
+		return body
+	}
+	createGroup := func(body map[string]interface{}) string {
+		t.Helper()
+		resp := postAuthed(t, groupsURL, admin, body)
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("create group %v: status = %d, want 201", body["name"], resp.StatusCode)
+		}
+		return decode(resp)["id"].(string)
+	}
+	price := func(productID string, body map[string]interface{}) (int, map[string]interface{}) {
+		t.Helper()
+		resp, err := http.Post(productsURL+"/"+productID+"/price", "application/json", jsonBody(t, body))
+		if err != nil {
+			t.Fatalf("POST price error: %v", err)
+		}
+		return resp.StatusCode, decode(resp)
+	}
+
+	// Limits must be reachable and option names distinct.
+	for name, body := range map[string]map[string]interface{}{
+		"min above options": {"name": "Sauces", "min_select": 3, "options": []map[string]interface{}{{"name": "Garlic"}, {"name": "BBQ"}}},
+		"max below min":     {"name": "Sauces", "min_select": 2, "max_select": 1, "options": []map[string]interface{}{{"name": "Garlic"}, {"name": "BBQ"}}},
+		"repeated option":   {"name": "Sauces", "options": []map[string]interface{}{{"name": "Garlic"}, {"name": "garlic"}}},
+	} {
+		resp := postAuthed(t, groupsURL, admin, body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", name, resp.StatusCode)
+		}
+	}
+
+	crust := createGroup(map[string]interface{}{"name": "Crust", "required": true, "max_select": 1, "options": []map[string]interface{}{
+		{"name": "Thin"}, {"name": "Stuffed", "price_delta_cents": 300},
+	}})
+	toppings := createGroup(map[string]interface{}{"name": "Extra toppings", "max_select": 2, "options": []map[string]interface{}{
+		{"name": "Extra cheese", "price_delta_cents": 150}, {"name": "Olives", "price_delta_cents": 100},
+	}})
+
+	// Products reference existing groups only.
+	pizza := categoryID(t, "pizza")
+	resp := postAuthed(t, productsURL, admin, map[string]interface{}{"name": "Mystery Pizza", "price_cents": 1000, "category_id": pizza,
+		"modifier_group_ids": []string{primitive.NewObjectID().Hex()}})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unknown modifier group: status = %d, want 400", resp.StatusCode)
+	}
+	resp = postAuthed(t, productsURL, admin, map[string]interface{}{
+		"name": "Build Your Pizza", "category_id": pizza, "modifier_group_ids": []string{crust, toppings},
+		"variants": []map[string]interface{}{{"sku": "BYO-S", "name": "Small", "price_cents": 1000}, {"sku": "BYO-L", "name": "Large", "price_cents": 1400}},
+	})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create product: status = %d, want 201", resp.StatusCode)
+	}
+	byo := decode(resp)
+	byoID := byo["id"].(string)
+	if ids := byo["modifier_group_ids"].([]interface{}); len(ids) != 2 || ids[0] != crust {
+		t.Errorf("modifier_group_ids = %v, want [%s %s]", ids, crust, toppings)
+	}
+
+	// Variant, options and quantity make up the price.
+	status, quote := price(byoID, map[string]interface{}{"sku": "byo-l", "quantity": 2, "selections": []map[string]interface{}{
+		{"group_id": toppings, "option_ids": []string{"extra-cheese"}},
+		{"group_id": crust, "option_ids": []string{"stuffed"}},
+	}})
+	if status != http.StatusOK || quote["unit_price_cents"] != float64(1850) || quote["total_cents"] != float64(3700) {
+		t.Errorf("quote: status = %d, body = %v", status, quote)
+	}
+	if opts := quote["options"].([]interface{}); len(opts) != 2 || opts[0].(map[string]interface{})["option_id"] != "stuffed" {
+		t.Errorf("quoted options = %v", opts)
+	}
+
+	// Selections that break a group's rules are refused.
+	for name, body := range map[string]map[string]interface{}{
+		"no variant":       {"selections": []map[string]interface{}{{"group_id": crust, "option_ids": []string{"thin"}}}},
+		"required missing": {"sku": "BYO-S"},
+		"too many":         {"sku": "BYO-S", "selections": []map[string]interface{}{{"group_id": crust, "option_ids": []string{"thin", "stuffed"}}}},
+	} {
+		if status, _ := price(byoID, body); status != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", name, status)
+		}
+	}
+
+	// Products without modifiers price at their single variant.
+	burger := decode(mustGet(t, productsURL+"/by-slug/classic-burger"))
+	if status, quote := price(burger["id"].(string), map[string]interface{}{}); status != http.StatusOK || quote["total_cents"] != float64(999) {
+		t.Errorf("plain quote: status = %d, body = %v", status, quote)
+	}
+
+	// Options made unavailable can no longer be chosen.
+	resp = doAuthed(t, http.MethodPut, groupsURL+"/"+toppings, admin, map[string]interface{}{"options": []map[string]interface{}{
+		{"name": "Extra cheese", "price_delta_cents": 150}, {"name": "Olives", "price_delta_cents": 100, "is_available": false},
+	}})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("update group: status = %d, want 200", resp.StatusCode)
+	}
+	status, _ = price(byoID, map[string]interface{}{"sku": "BYO-S", "selections": []map[string]interface{}{
+		{"group_id": crust, "option_ids": []string{"thin"}},
+		{"group_id": toppings, "option_ids": []string{"olives"}},
+	}})
+	if status != http.StatusBadRequest {
+		t.Errorf("unavailable option: status = %d, want 400", status)
+	}
+
+	// Groups in use cannot be deleted.
+	resp = doAuthed(t, http.MethodDelete, groupsURL+"/"+toppings, admin, nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("delete in-use group: status = %d, want 409", resp.StatusCode)
+	}
+	resp = doAuthed(t, http.MethodPut, productsURL+"/"+byoID, admin, map[string]interface{}{"modifier_group_ids": []string{crust}})
+	resp.Body.Close()
+	resp = doAuthed(t, http.MethodDelete, groupsURL+"/"+toppings, admin, nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("delete unused group: status = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestInventory(t *testing.T) {
+	ts := setupRouter(t)
+	registerAndLogin(t, ts, "admin@example.com")
+	setRole(t, "admin@example.com", role.Admin)
+	admin := login(t, ts, "admin@example.com", "kite-orbit-42")["access_token"].(string)
+	customer := registerAndLogin(t, ts, "customer@example.com")["access_token"].(string)
+	productsURL := ts.URL + "/api/v1/products"
+	ctx := context.Background()
+
+	decode := func(resp *http.Response) map[string]interface{} {
+		t.Helper()
+		defer resp.Body.Close()
+		var body map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&body)
+		return body
+	}
+	burgerID := decode(mustGet(t, productsURL+"/by-slug/classic-burger"))["id"].(string)
+	stockURL := ts.URL + "/api/v1/admin/products/" + burgerID + "/stock"
+	adjust := func(body map[string]interface{}) (int, map[string]interface{}) {
+		t.Helper()
+		resp := postAuthed(t, stockURL+"/adjustments", admin, body)
+		return resp.StatusCode, decode(resp)
+	}
+	burgerAvailability := func() (product, variant bool) {
+		t.Helper()
+		p := decode(mustGet(t, productsURL+"/"+burgerID))
+		return p["is_available"].(bool), p["variants"].([]interface{})[0].(map[string]interface{})["is_available"].(bool)
+	}
+
+	// Stock is managed by staff only.
+	resp := doAuthed(t, http.MethodGet, stockURL, customer, nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("customer stock: status = %d, want 403", resp.StatusCode)
+	}
+
+	// Variants are not tracked until their first adjustment.
+	levels := decode(doAuthed(t, http.MethodGet, stockURL, admin, nil))["items"].([]interface{})
+	if len(levels) != 1 || levels[0].(map[string]interface{})["tracked"] != false {
+		t.Fatalf("levels before tracking = %v", levels)
+	}
+
+	// Reasons must fit the direction of the change.
+	for name, body := range map[string]map[string]interface{}{
+		"restock removing": {"sku": "CLASSIC-BURGER", "delta": -1, "reason": "restock"},
+		"waste adding":     {"sku": "CLASSIC-BURGER", "delta": 1, "reason": "waste"},
+		"unknown reason":   {"sku": "CLASSIC-BURGER", "delta": 1, "reason": "theft"},
+		"unknown sku":      {"sku": "NO-SUCH-SKU", "delta": 1, "reason": "restock"},
+	} {
+		if status, _ := adjust(body); status != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", name, status)
+		}
+	}
+
+	// Removing stock from an untracked variant neither succeeds nor starts
+	// tracking it.
+	if status, _ := adjust(map[string]interface{}{"sku": "CLASSIC-BURGER", "delta": -1, "reason": "waste"}); status != http.StatusConflict {
+		t.Errorf("waste untracked: status = %d, want 409", status)
+	}
+	levels = decode(doAuthed(t, http.MethodGet, stockURL, admin, nil))["items"].([]interface{})
+	if levels[0].(map[string]interface{})["tracked"] != false {
+		t.Errorf("level after refused waste = %v, want untracked", levels[0])
+	}
+	if p, v := burgerAvailability(); !p || !v {
+		t.Errorf("refused waste: product available = %v, variant available = %v", p, v)
+	}
+
+	status, body := adjust(map[string]interface{}{"sku": "classic-burger", "delta": 3, "reason": "restock", "note": "morning delivery"})
+	if status != http.StatusCreated {
+		t.Fatalf("restock: status = %d, want 201", status)
+	}
+	level := body["level"].(map[string]interface{})
+	entry := body["entry"].(map[string]interface{})
+	if level["on_hand"] != float64(3) || level["in_stock"] != true || entry["actor_id"] != userID(t, "admin@example.com") {
+		t.Errorf("restock = %v", body)
+	}
+	if status, _ = adjust(map[string]interface{}{"sku": "CLASSIC-BURGER", "delta": -5, "reason": "waste"}); status != http.StatusConflict {
+		t.Errorf("waste beyond stock: status = %d, want 409", status)
+	}
+
+	// Concurrent orders cannot reserve more than is in stock.
+	svc := inventory.NewService(inventory.NewRepository(testDB), product.NewRepository(testDB))
+	pid, _ := primitive.ObjectIDFromHex(burgerID)
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		held  []*inventory.Reservation
+		short int
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := svc.Reserve(ctx, pid, "CLASSIC-BURGER", 1, time.Minute)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case errors.Is(err, inventory.ErrInsufficientStock):
+				short++
+			case err != nil || res == nil:
+				t.Errorf("Reserve() = %v, %v", res, err)
+			default:
+				held = append(held, res)
+			}
+		}()
+	}
+	wg.Wait()
+	if len(held) != 3 || short != 7 {
+		t.Fatalf("reserved %d and refused %d, want 3 and 7", len(held), short)
+	}
+
+	// Running out takes the product off sale, and freeing stock puts it back.
+	if p, v := burgerAvailability(); p || v {
+		t.Errorf("sold out: product available = %v, variant available = %v", p, v)
+	}
+	if err := svc.Release(ctx, held[0].ID); err != nil {
+		t.Fatalf("Release() error: %v", err)
+	}
+	if p, v := burgerAvailability(); !p || !v {
+		t.Errorf("released: product available = %v, variant available = %v", p, v)
+	}
+	if err := svc.Commit(ctx, held[1].ID); err != nil {
+		t.Fatalf("Commit() error: %v", err)
+	}
+	if err := svc.Commit(ctx, held[1].ID); !errors.Is(err, inventory.ErrReservationNotFound) {
+		t.Errorf("second Commit() error = %v, want ErrReservationNotFound", err)
+	}
+	level = decode(doAuthed(t, http.MethodGet, stockURL, admin, nil))["items"].([]interface{})[0].(map[string]interface{})
+	if level["on_hand"] != float64(2) || level["reserved"] != float64(1) || level["available"] != float64(1) {
+		t.Errorf("level after sale = %v", level)
+	}
+
+	// Untracked variants never run out.
+	pizzaID := decode(mustGet(t, productsURL+"/by-slug/margherita-pizza"))["id"].(string)
+	pizza, _ := primitive.ObjectIDFromHex(pizzaID)
+	if res, err := svc.Reserve(ctx, pizza, "MARGHERITA-PIZZA", 100, time.Minute); res != nil || err != nil {
+		t.Errorf("untracked Reserve() = %v, %v, want nil, nil", res, err)
+	}
+
+	// The threshold keeps the last units back.
+	resp = doAuthed(t, http.MethodPut, stockURL+"/threshold", admin, map[string]interface{}{"sku": "CLASSIC-BURGER", "low_stock_threshold": 1})
+	if level = decode(resp); resp.StatusCode != http.StatusOK || level["in_stock"] != false {
+		t.Errorf("threshold: status = %d, body = %v", resp.StatusCode, level)
+	}
+	if p, _ := burgerAvailability(); p {
+		t.Error("product still available at the low-stock threshold")
+	}
+
+	// A product an admin takes off sale stays off when restocked.
+	resp = doAuthed(t, http.MethodPut, productsURL+"/"+burgerID, admin, map[string]interface{}{"is_available": false})
+	resp.Body.Close()
+	if status, _ = adjust(map[string]interface{}{"sku": "CLASSIC-BURGER", "delta": 5, "reason": "restock"}); status != http.StatusCreated {
+		t.Fatalf("second restock: status = %d, want 201", status)
+	}
+	if p, v := burgerAvailability(); p || !v {
+		t.Errorf("restocked: product available = %v, variant available = %v, want false, true", p, v)
+	}
+
+	// Every change is in the ledger, newest first.
+	ledger := decode(doAuthed(t, http.MethodGet, stockURL+"/ledger?page_size=50", admin, nil))
+	var reasons []string
+	for _, item := range ledger["items"].([]interface{}) {
+		reasons = append(reasons, item.(map[string]interface{})["reason"].(string))
+	}
+	want := "restock sale release reservation reservation reservation restock"
+	if got := strings.Join(reasons, " "); got != want {
+		t.Errorf("ledger reasons = %q, want %q", got, want)
+	}
+}
+
+func TestInventoryProductEdits(t *testing.T) {
+	ts := setupRouter(t)
+	registerAndLogin(t, ts, "admin@example.com")
+	setRole(t, "admin@example.com", role.Admin)
+	admin := login(t, ts, "admin@example.com", "kite-orbit-42")["access_token"].(string)
+	productsURL := ts.URL + "/api/v1/products"
+	ctx := context.Background()
+
+	decode := func(resp *http.Response) map[string]interface{} {
+		t.Helper()
+		defer resp.Body.Close()
+		var body map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&body)
+		return body
+	}
+	sizes := []map[string]interface{}{
+		{"sku": "STK-S", "name": "Small", "price_cents": 900},
+		{"sku": "STK-L", "name": "Large", "price_cents": 1500},
+	}
+	resp := postAuthed(t, productsURL, admin, map[string]interface{}{"name": "Stock Pizza", "category_id": categoryID(t, "pizza"), "variants": sizes})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create: status = %d, want 201", resp.StatusCode)
+	}
+	productID := decode(resp)["id"].(string)
+	pid, _ := primitive.ObjectIDFromHex(productID)
+	stockURL := ts.URL + "/api/v1/admin/products/" + productID + "/stock"
+	adjust := func(sku string, delta int, reason string) {
+		t.Helper()
+		resp := postAuthed(t, stockURL+"/adjustments", admin, map[string]interface{}{"sku": sku, "delta": delta, "reason": reason})
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("%s %s %d: status = %d, want 201", reason, sku, delta, resp.StatusCode)
+		}
+	}
+	edit := func(body map[string]interface{}) int {
+		t.Helper()
+		resp := doAuthed(t, http.MethodPut, productsURL+"/"+productID, admin, body)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	// availability reports the product's and each variant's is_available.
+	availability := func() (bool, map[string]bool) {
+		t.Helper()
+		p := decode(mustGet(t, productsURL+"/"+productID))
+		variants := map[string]bool{}
+		for _, v := range p["variants"].([]interface{}) {
+			v := v.(map[string]interface{})
+			variants[v["sku"].(string)] = v["is_available"].(bool)
+		}
+		return p["is_available"].(bool), variants
+	}
+	stockCount := func(collection string, filter bson.M) int64 {
+		t.Helper()
+		n, err := testDB.Collection(collection).CountDocuments(ctx, filter)
+		if err != nil {
+			t.Fatalf("count %s: %v", collection, err)
+		}
+		return n
+	}
+
+	// API keys with inventory:manage adjust stock, and only stock.
+	resp = postAuthed(t, ts.URL+"/api/v1/admin/api-keys", admin, map[string]interface{}{"name": "Stockroom", "scopes": []string{role.PermInventoryManage}})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create key: status = %d, want 201", resp.StatusCode)
+	}
+	key := decode(resp)["key"].(string)
+	withKey := func(method, url string, body interface{}) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(method, url, jsonBody(t, body))
+		if err != nil {
+			t.Fatalf("new request: %v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Key", key)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s error: %v", method, url, err)
+		}
+		return resp
+	}
+	resp = withKey(http.MethodPost, stockURL+"/adjustments", map[string]interface{}{"sku": "STK-S", "delta": 2, "reason": "restock"})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("restock with key: status = %d, want 201", resp.StatusCode)
+	}
+	if entry := decode(resp)["entry"].(map[string]interface{}); entry["actor_id"] != nil {
+		t.Errorf("key adjustment actor_id = %v, want none", entry["actor_id"])
+	}
+	resp = withKey(http.MethodPut, productsURL+"/"+productID, map[string]interface{}{"description": "by key"})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("edit product with stock key: status = %d, want 403", resp.StatusCode)
+	}
+
+	// A product edit while a variant is out of stock keeps it off sale.
+	adjust("STK-L", 1, "restock")
+	adjust("STK-L", -1, "waste")
+	sizes[1]["price_cents"] = 1600
+	if status := edit(map[string]interface{}{"variants": sizes}); status != http.StatusOK {
+		t.Fatalf("edit variants: status = %d, want 200", status)
+	}
+	if p, v := availability(); !p || !v["STK-S"] || v["STK-L"] {
+		t.Errorf("edited with large sold out: product = %v, variants = %v", p, v)
+	}
+
+	// Edits are written only over the product they were computed from, so a
+	// stock change in between is not overwritten.
+	repo := product.NewRepository(testDB)
+	snapshot, err := repo.FindByID(ctx, pid)
+	if err != nil || snapshot == nil {
+		t.Fatalf("FindByID() = %v, %v", snapshot, err)
+	}
+	adjust("STK-L", 1, "restock")
+	if p, err := repo.UpdateIfUnchanged(ctx, pid, snapshot.UpdatedAt, bson.M{"variants": snapshot.Variants}); p != nil || err != nil {
+		t.Errorf("stale UpdateIfUnchanged() = %v, %v, want nil, nil", p, err)
+	}
+	if _, v := availability(); !v["STK-L"] {
+		t.Error("stale edit overwrote the restocked variant")
+	}
+	adjust("STK-L", -1, "waste")
+
+	// And while the whole product is out of stock, it stays off sale until
+	// restocked.
+	adjust("STK-S", -2, "waste")
+	if status := edit(map[string]interface{}{"description": "Out of stock", "variants": sizes}); status != http.StatusOK {
+		t.Fatalf("edit sold out: status = %d, want 200", status)
+	}
+	if p, v := availability(); p || v["STK-S"] || v["STK-L"] {
+		t.Errorf("edited while sold out: product = %v, variants = %v", p, v)
+	}
+	adjust("STK-S", 1, "restock")
+	if p, v := availability(); !p || !v["STK-S"] || v["STK-L"] {
+		t.Errorf("restocked after edit: product = %v, variants = %v", p, v)
+	}
+
+	// Variants holding stock cannot be dropped; the stock of empty ones goes
+	// with them.
+	if status := edit(map[string]interface{}{"variants": sizes[1:]}); status != http.StatusConflict {
+		t.Errorf("drop stocked variant: status = %d, want 409", status)
+	}
+	if status := edit(map[string]interface{}{"variants": sizes[:1]}); status != http.StatusOK {
+		t.Fatalf("drop empty variant: status = %d, want 200", status)
+	}
+	if n := stockCount("stock_levels", bson.M{"product_id": pid, "sku": "STK-L"}); n != 0 {
+		t.Errorf("dropped variant left %d stock levels", n)
+	}
+
+	// Deleting the product removes its stock and reservations.
+	svc := inventory.NewService(inventory.NewRepository(testDB), product.NewRepository(testDB))
+	if res, err := svc.Reserve(ctx, pid, "STK-S", 1, time.Minute); res == nil || err != nil {
+		t.Fatalf("Reserve() = %v, %v", res, err)
+	}
+	resp = doAuthed(t, http.MethodDelete, productsURL+"/"+productID, admin, nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("delete: status = %d, want 200", resp.StatusCode)
+	}
+	for _, collection := range []string{"stock_levels", "stock_reservations"} {
+		if n := stockCount(collection, bson.M{"product_id": pid}); n != 0 {
+			t.Errorf("deleted product left %d documents in %s", n, collection)
+		}
+	}
+}
+
+func mustGet(t *testing.T, url string) *http.Response {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s error: %v", url, err)
+	}
+	return resp
+}
@@ -1,6 +1,13 @@
 package unit
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -17,7 +24,7 @@ func TestHashPassword(t *testing.T) {
 		wantErr  bool
 	}{
 		{"valid password", "mySecret123", false},
-		{"short password", "ab1", false}, // bcrypt itself doesn't enforce length
+		{"short password", "ab1", false}, // hashing itself doesn't enforce length
 		{"empty password", "", false},
 	}
 	for _, tt := range tests {
@@ -65,7 +72,7 @@ func TestJWTGenerateAndValidate(t *testing.T) {
 	mgr := auth.NewJWTManager("test-secret-key-12345", 15*time.Minute)
 
 	t.Run("valid token round-trip", func(t *testing.T) {
-		token, err := mgr.GenerateAccessToken("user123", "user@example.com")
+		token, err := mgr.GenerateAccessToken(auth.Identity{UserID: "user123", Email: "user@example.com", Name: "Jane Doe", Role: user.RoleAdmin})
 		if err != nil {
 			t.Fatalf("GenerateAccessToken() error: %v", err)
 		}
@@ -83,11 +90,17 @@ func TestJWTGenerateAndValidate(t *testing.T) {
 		if claims.Email != "user@example.com" {
 			t.Errorf("Email = %q, want %q", claims.Email, "user@example.com")
 		}
+		if claims.Name != "Jane Doe" {
+			t.Errorf("Name = %q, want %q", claims.Name, "Jane Doe")
+		}
+		if claims.Role != user.RoleAdmin {
+			t.Errorf("Role = %q, want %q", claims.Role, user.RoleAdmin)
+		}
 	})
 
 	t.Run("expired token", func(t *testing.T) {
 		mgrExpired := auth.NewJWTManager("test-secret", -1*time.Second)
-		token, err := mgrExpired.GenerateAccessToken("user123", "user@example.com")
+		token, err := mgrExpired.GenerateAccessToken(auth.Identity{UserID: "user123", Email: "user@example.com"})
 		if err != nil {
 			t.Fatalf("GenerateAccessToken() error: %v", err)
 		}
@@ -102,7 +115,7 @@ func TestJWTGenerateAndValidate(t *testing.T) {
 		mgrA := auth.NewJWTManager("secret-A", 15*time.Minute)
 		mgrB := auth.NewJWTManager("secret-B", 15*time.Minute)
 
-		token, _ := mgrA.GenerateAccessToken("user1", "a@b.com")
+		token, _ := mgrA.GenerateAccessToken(auth.Identity{UserID: "user1", Email: "a@b.com"})
 		_, err := mgrB.ValidateAccessToken(token)
 		if err == nil {
 			t.Fatal("ValidateAccessToken() expected error for wrong secret")
@@ -139,3 +152,225 @@ func TestAccessTTLSeconds(t *testing.T) {
 		t.Errorf("AccessTTLSeconds() = %d, want 900", got)
 	}
 }
+
+func TestHashRefreshToken(t *testing.T) {
+	token, err := auth.GenerateRefreshTokenString()
+	if err != nil {
+		t.Fatalf("GenerateRefreshTokenString() error: %v", err)
+	}
+
+	hash := auth.HashRefreshToken(token)
+	if len(hash) != 64 {
+		t.Errorf("hash length = %d, want 64 hex chars", len(hash))
+	}
+	if hash == token {
+		t.Error("hash must not equal the plaintext token")
+	}
+	if again := auth.HashRefreshToken(token); again != hash {
+		t.Errorf("HashRefreshToken() not deterministic: %q != %q", again, hash)
+	}
+
+	other, _ := auth.GenerateRefreshTokenString()
+	if auth.HashRefreshToken(other) == hash {
+		t.Error("different tokens should not share a hash")
+	}
+}
+
+func TestMFATokenIsNotAccessToken(t *testing.T) {
+	mgr := auth.NewJWTManager("secret", 15*time.Minute)
+
+	mfaToken, err := mgr.GenerateMFAToken("user-1", 5*time.Minute)
+	if err != nil {
+		t.Fatalf("GenerateMFAToken() error: %v", err)
+	}
+	if _, err = mgr.ValidateAccessToken(mfaToken); err == nil {
+		t.Error("ValidateAccessToken() accepted an MFA challenge token")
+	}
+	claims, err := mgr.ValidateMFAToken(mfaToken)
+	if err != nil {
+		t.Fatalf("ValidateMFAToken() error: %v", err)
+	}
+	if claims.Subject != "user-1" {
+		t.Errorf("Subject = %q, want user-1", claims.Subject)
+	}
+
+	access, _ := mgr.GenerateAccessToken(auth.Identity{UserID: "user-1", Email: "a@example.com"})
+	if _, err = mgr.ValidateMFAToken(access); err == nil {
+		t.Error("ValidateMFAToken() accepted an access token")
+	}
+}
+
+// ── Asymmetric signing tests ───────────────────────────────────────────────
+
+// writeKeyPEM writes a PKCS#8 private key or PKIX public key to a temp file.
+func writeKeyPEM(t *testing.T, key interface{}, public bool) string {
+	t.Helper()
+
+	var (
+		der       []byte
+		blockType string
+		err       error
+	)
+	if public {
+		der, err = x509.MarshalPKIXPublicKey(key)
+		blockType = "PUBLIC KEY"
+	} else {
+		der, err = x509.MarshalPKCS8PrivateKey(key)
+		blockType = "PRIVATE KEY"
+	}
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+	return path
+}
+
+func loadKey(t *testing.T, path string) *auth.SigningKey {
+	t.Helper()
+	k, err := auth.LoadSigningKey(path)
+	if err != nil {
+		t.Fatalf("LoadSigningKey() error: %v", err)
+	}
+	return k
+}
+
+func TestKeyedJWTManager(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate RSA key: %v", err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate Ed25519 key: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		key     interface{}
+		wantAlg string
+		wantKty string
+	}{
+		{"RS256", rsaKey, "RS256", "RSA"},
+		{"EdDSA", edKey, "EdDSA", "OKP"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := loadKey(t, writeKeyPEM(t, tt.key, false))
+			if k.Method.Alg() != tt.wantAlg {
+				t.Fatalf("Method = %s, want %s", k.Method.Alg(), tt.wantAlg)
+			}
+
+			mgr, err := auth.NewKeyedJWTManager(k, nil, 15*time.Minute)
+			if err != nil {
+				t.Fatalf("NewKeyedJWTManager() error: %v", err)
+			}
+
+			token, err := mgr.GenerateAccessToken(auth.Identity{UserID: "user123", Email: "user@example.com"})
+			if err != nil {
+				t.Fatalf("GenerateAccessToken() error: %v", err)
+			}
+			claims, err := mgr.ValidateAccessToken(token)
+			if err != nil {
+				t.Fatalf("ValidateAccessToken() error: %v", err)
+			}
+			if claims.Subject != "user123" {
+				t.Errorf("Subject = %q, want %q", claims.Subject, "user123")
+			}
+
+			jwks := mgr.JWKS()
+			if len(jwks.Keys) != 1 {
+				t.Fatalf("JWKS keys = %d, want 1", len(jwks.Keys))
+			}
+			if jwks.Keys[0].Kid != k.ID || jwks.Keys[0].Kty != tt.wantKty || jwks.Keys[0].Alg != tt.wantAlg {
+				t.Errorf("JWK = %+v, want kid %q kty %q alg %q", jwks.Keys[0], k.ID, tt.wantKty, tt.wantAlg)
+			}
+		})
+	}
+
+	t.Run("retired key still verifies", func(t *testing.T) {
+		oldKey := loadKey(t, writeKeyPEM(t, rsaKey, false))
+		newKey := loadKey(t, writeKeyPEM(t, edKey, false))
+		retiredPub := loadKey(t, writeKeyPEM(t, &rsaKey.PublicKey, true))
+		retiredPub.RetiredAt = time.Now()
+		if retiredPub.ID != oldKey.ID {
+			t.Fatalf("public key kid %q != private key kid %q", retiredPub.ID, oldKey.ID)
+		}
+
+		before, _ := auth.NewKeyedJWTManager(oldKey, nil, 15*time.Minute)
+		token, _ := before.GenerateAccessToken(auth.Identity{UserID: "user1", Email: "a@b.com"})
+
+		after, err := auth.NewKeyedJWTManager(newKey, []*auth.SigningKey{retiredPub}, 15*time.Minute)
+		if err != nil {
+			t.Fatalf("NewKeyedJWTManager() error: %v", err)
+		}
+		if _, err := after.ValidateAccessToken(token); err != nil {
+			t.Errorf("ValidateAccessToken() with retired key error: %v", err)
+		}
+		if got := len(after.JWKS().Keys); got != 2 {
+			t.Errorf("JWKS keys = %d, want 2", got)
+		}
+
+		dropped, _ := auth.NewKeyedJWTManager(newKey, nil, 15*time.Minute)
+		if _, err := dropped.ValidateAccessToken(token); err == nil {
+			t.Error("ValidateAccessToken() expected error once the old key is removed")
+		}
+	})
+
+	t.Run("retired key expires after the token TTL", func(t *testing.T) {
+		oldKey := loadKey(t, writeKeyPEM(t, rsaKey, false))
+		newKey := loadKey(t, writeKeyPEM(t, edKey, false))
+
+		// A token the old key signed, perhaps with a leaked copy of it.
+		before, _ := auth.NewKeyedJWTManager(oldKey, nil, 2*time.Hour)
+		token, _ := before.GenerateAccessToken(auth.Identity{UserID: "user1", Email: "a@b.com"})
+
+		retired := loadKey(t, writeKeyPEM(t, &rsaKey.PublicKey, true))
+		retired.RetiredAt = time.Now().Add(-time.Hour)
+		after, err := auth.NewKeyedJWTManager(newKey, []*auth.SigningKey{retired}, 15*time.Minute)
+		if err != nil {
+			t.Fatalf("NewKeyedJWTManager() error: %v", err)
+		}
+		if _, err := after.ValidateAccessToken(token); err == nil {
+			t.Error("ValidateAccessToken() expected error for a key retired longer than the TTL ago")
+		}
+		if got := len(after.JWKS().Keys); got != 1 {
+			t.Errorf("JWKS keys = %d, want 1", got)
+		}
+	})
+
+	t.Run("retired key needs a retirement time", func(t *testing.T) {
+		retired := loadKey(t, writeKeyPEM(t, &rsaKey.PublicKey, true))
+		if _, err := auth.NewKeyedJWTManager(loadKey(t, writeKeyPEM(t, edKey, false)), []*auth.SigningKey{retired}, 15*time.Minute); err == nil {
+			t.Error("NewKeyedJWTManager() expected error for a retired key without RetiredAt")
+		}
+	})
+
+	t.Run("HS256 token rejected", func(t *testing.T) {
+		keyed, _ := auth.NewKeyedJWTManager(loadKey(t, writeKeyPEM(t, edKey, false)), nil, 15*time.Minute)
+		token, _ := auth.NewJWTManager("secret", 15*time.Minute).GenerateAccessToken(auth.Identity{UserID: "user1", Email: "a@b.com"})
+		if _, err := keyed.ValidateAccessToken(token); err == nil {
+			t.Error("ValidateAccessToken() expected error for HS256 token")
+		}
+	})
+
+	t.Run("public key cannot sign", func(t *testing.T) {
+		pub := loadKey(t, writeKeyPEM(t, edKey.Public(), true))
+		if _, err := auth.NewKeyedJWTManager(pub, nil, 15*time.Minute); err == nil {
+			t.Error("NewKeyedJWTManager() expected error for verify-only active key")
+		}
+	})
+}
+
+func TestParseSigningKeyRejectsWeakRSA(t *testing.T) {
+	weak, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("generate RSA key: %v", err)
+	}
+	if _, err := auth.LoadSigningKey(writeKeyPEM(t, weak, false)); err == nil {
+		t.Error("LoadSigningKey() expected error for 1024-bit RSA key")
+	}
+}
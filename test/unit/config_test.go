@@ -0,0 +1,45 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/one-backend-go/internal/config"
+)
+
+func TestConfigNotifierByEnvironment(t *testing.T) {
+	t.Setenv("JWT_SECRET", "unit-test-secret")
+
+	tests := []struct {
+		name         string
+		env          string
+		notifier     string
+		webhookURL   string
+		wantErr      bool
+		wantNotifier string
+	}{
+		{"production without notifier", "", "", "", true, ""},
+		{"explicit production without notifier", "production", "", "", true, ""},
+		{"production with notifier", "production", "file", "", false, "file"},
+		{"production with log notifier", "production", "log", "", true, ""},
+		{"webhook without url", "production", "webhook", "", true, ""},
+		{"webhook", "production", "webhook", "https://relay.example.com/messages", false, "webhook"},
+		{"development defaults to log", "development", "", "", false, "log"},
+		{"development with log notifier", "development", "log", "", false, "log"},
+		{"unknown environment", "staging", "log", "", true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("APP_ENV", tt.env)
+			t.Setenv("NOTIFIER", tt.notifier)
+			t.Setenv("NOTIFIER_WEBHOOK_URL", tt.webhookURL)
+
+			cfg, err := config.Load()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && cfg.Notifier != tt.wantNotifier {
+				t.Errorf("Notifier = %q, want %q", cfg.Notifier, tt.wantNotifier)
+			}
+		})
+	}
+}
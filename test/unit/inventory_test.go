@@ -0,0 +1,56 @@
+package unit
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/one-backend-go/internal/domain/inventory"
+)
+
+func TestStockLevel(t *testing.T) {
+	tests := []struct {
+		name          string
+		level         inventory.Level
+		wantAvailable int64
+		wantInStock   bool
+	}{
+		{"plenty", inventory.Level{OnHand: 10, Reserved: 2}, 8, true},
+		{"last unit", inventory.Level{OnHand: 1}, 1, true},
+		{"all reserved", inventory.Level{OnHand: 4, Reserved: 4}, 0, false},
+		{"empty", inventory.Level{}, 0, false},
+		{"above threshold", inventory.Level{OnHand: 6, Reserved: 1, LowStockThreshold: 4}, 5, true},
+		{"at threshold", inventory.Level{OnHand: 5, Reserved: 1, LowStockThreshold: 4}, 4, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.level.Available(); got != tt.wantAvailable {
+				t.Errorf("Available() = %d, want %d", got, tt.wantAvailable)
+			}
+			if got := tt.level.InStock(); got != tt.wantInStock {
+				t.Errorf("InStock() = %v, want %v", got, tt.wantInStock)
+			}
+		})
+	}
+}
+
+func TestStockLedgerEntryToResponse(t *testing.T) {
+	reservation := primitive.NewObjectID()
+	e := inventory.Entry{
+		ID:            primitive.NewObjectID(),
+		SKU:           "MARGHERITA-L",
+		Reason:        inventory.ReasonSale,
+		Delta:         -2,
+		ReservedDelta: -2,
+		OnHand:        8,
+		ReservationID: &reservation,
+	}
+
+	r := e.ToResponse()
+	if r.ID != e.ID.Hex() || r.ReservationID != reservation.Hex() || r.Delta != -2 || r.OnHand != 8 {
+		t.Errorf("ToResponse() = %+v", r)
+	}
+	if r.ActorID != "" {
+		t.Errorf("ActorID = %q, want empty for system changes", r.ActorID)
+	}
+}
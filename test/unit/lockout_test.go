@@ -0,0 +1,67 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/one-backend-go/internal/domain/auth"
+)
+
+func TestLockoutPolicyWait(t *testing.T) {
+	policy := auth.LockoutPolicy{
+		MaxFailures: 5,
+		Window:      15 * time.Minute,
+		Lockout:     15 * time.Minute,
+		BaseDelay:   time.Second,
+	}
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		attempts   *auth.LoginAttempts
+		wantWait   time.Duration
+		wantLocked bool
+	}{
+		{"no record", nil, 0, false},
+		{"first failure just now", &auth.LoginAttempts{Failures: 1, LastFailed: now}, time.Second, false},
+		{"first failure elapsed", &auth.LoginAttempts{Failures: 1, LastFailed: now.Add(-2 * time.Second)}, 0, false},
+		{"delay doubles", &auth.LoginAttempts{Failures: 3, LastFailed: now.Add(-time.Second)}, 3 * time.Second, false},
+		{"delay capped at lockout", &auth.LoginAttempts{Failures: 40, LastFailed: now}, 15 * time.Minute, false},
+		{"locked", &auth.LoginAttempts{Failures: 5, LastFailed: now, LockedUntil: now.Add(10 * time.Minute)}, 10 * time.Minute, true},
+		{"lock expired", &auth.LoginAttempts{Failures: 5, LastFailed: now.Add(-16 * time.Minute), LockedUntil: now.Add(-time.Minute)}, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wait, locked := policy.Wait(tt.attempts, now)
+			if wait != tt.wantWait || locked != tt.wantLocked {
+				t.Errorf("Wait() = (%v, %v), want (%v, %v)", wait, locked, tt.wantWait, tt.wantLocked)
+			}
+		})
+	}
+}
+
+func TestLockoutPolicyNoDelay(t *testing.T) {
+	policy := auth.LockoutPolicy{Lockout: 15 * time.Minute}
+	now := time.Now()
+	if wait, _ := policy.Wait(&auth.LoginAttempts{Failures: 3, LastFailed: now}, now); wait != 0 {
+		t.Errorf("Wait() = %v, want 0 when BaseDelay is disabled", wait)
+	}
+}
+
+func TestLockoutPolicyShouldLock(t *testing.T) {
+	policy := auth.LockoutPolicy{}
+	tests := []struct {
+		failures, limit int
+		want            bool
+	}{
+		{4, 5, false},
+		{5, 5, true},
+		{6, 5, true},
+		{100, 0, false}, // limit disabled
+	}
+	for _, tt := range tests {
+		if got := policy.ShouldLock(tt.failures, tt.limit); got != tt.want {
+			t.Errorf("ShouldLock(%d, %d) = %v, want %v", tt.failures, tt.limit, got, tt.want)
+		}
+	}
+}
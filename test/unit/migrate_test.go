@@ -0,0 +1,60 @@
+package unit
+
+import (
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/one-backend-go/internal/db/migrate"
+	"github.com/one-backend-go/internal/db/migrations"
+)
+
+func noop(context.Context, *mongo.Database) error { return nil }
+
+func TestMigrateSortOrdersByVersion(t *testing.T) {
+	sorted, err := migrate.Sort([]migrate.Migration{
+		{Version: 3, Name: "c", Up: noop},
+		{Version: 1, Name: "a", Up: noop},
+		{Version: 2, Name: "b", Up: noop},
+	})
+	if err != nil {
+		t.Fatalf("Sort: %v", err)
+	}
+	for i, want := range []int64{1, 2, 3} {
+		if sorted[i].Version != want {
+			t.Errorf("sorted[%d].Version = %d, want %d", i, sorted[i].Version, want)
+		}
+	}
+}
+
+func TestMigrateSortRejectsInvalidSets(t *testing.T) {
+	tests := []struct {
+		name string
+		set  []migrate.Migration
+	}{
+		{"duplicate version", []migrate.Migration{{Version: 1, Name: "a", Up: noop}, {Version: 1, Name: "b", Up: noop}}},
+		{"zero version", []migrate.Migration{{Version: 0, Name: "a", Up: noop}}},
+		{"negative version", []migrate.Migration{{Version: -1, Name: "a", Up: noop}}},
+		{"missing up", []migrate.Migration{{Version: 1, Name: "a"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := migrate.Sort(tt.set); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestRegisteredMigrationsAreValid(t *testing.T) {
+	all := migrations.All()
+	if _, err := migrate.Sort(all); err != nil {
+		t.Fatalf("registered migrations: %v", err)
+	}
+	for _, m := range all {
+		if m.Name == "" {
+			t.Errorf("migration %d has no name", m.Version)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/one-backend-go/internal/domain/category"
 	"github.com/one-backend-go/internal/domain/product"
 	"github.com/one-backend-go/internal/domain/user"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -13,11 +14,12 @@ func TestUserToResponse(t *testing.T) {
 	now := time.Now().UTC()
 	id := primitive.NewObjectID()
 	u := &user.User{
-		ID:        id,
-		Name:      "Jane Doe",
-		Email:     "jane@example.com",
-		Role:      user.RoleUser,
-		CreatedAt: now,
+		ID:            id,
+		Name:          "Jane Doe",
+		Email:         "jane@example.com",
+		Role:          user.RoleUser,
+		EmailVerified: true,
+		CreatedAt:     now,
 	}
 
 	resp := u.ToResponse()
@@ -33,17 +35,22 @@ func TestUserToResponse(t *testing.T) {
 	if resp.Role != "user" {
 		t.Errorf("Role = %q, want %q", resp.Role, "user")
 	}
+	if !resp.EmailVerified {
+		t.Error("EmailVerified = false, want true")
+	}
 }
 
 func TestProductToResponse(t *testing.T) {
 	now := time.Now().UTC()
 	id := primitive.NewObjectID()
+	categoryID := primitive.NewObjectID()
 	p := &product.Product{
 		ID:          id,
 		Name:        "Pizza",
 		Description: "Delicious",
 		PriceCents:  1299,
-		Category:    "pizza",
+		Variants:    []product.Variant{{SKU: "PIZZA", Name: "Regular", PriceCents: 1299, IsAvailable: true}},
+		CategoryID:  categoryID,
 		ImageURL:    "https://example.com/pizza.jpg",
 		IsAvailable: true,
 		CreatedAt:   now,
@@ -57,10 +64,31 @@ func TestProductToResponse(t *testing.T) {
 	if resp.PriceCents != 1299 {
 		t.Errorf("PriceCents = %d, want %d", resp.PriceCents, 1299)
 	}
-	if resp.Category != "pizza" {
-		t.Errorf("Category = %q, want %q", resp.Category, "pizza")
+	if len(resp.Variants) != 1 || resp.Variants[0].SKU != "PIZZA" {
+		t.Errorf("Variants = %+v, want the PIZZA variant", resp.Variants)
+	}
+	if resp.CategoryID != categoryID.Hex() {
+		t.Errorf("CategoryID = %q, want %q", resp.CategoryID, categoryID.Hex())
 	}
 	if resp.ImageURL != "https://example.com/pizza.jpg" {
 		t.Errorf("ImageURL = %q, want %q", resp.ImageURL, "https://example.com/pizza.jpg")
 	}
 }
+
+func TestCategoryToResponse(t *testing.T) {
+	parent := primitive.NewObjectID()
+	c := &category.Category{ID: primitive.NewObjectID(), Name: "Pizza", Slug: "pizza", ParentID: &parent, Active: true}
+
+	resp := c.ToResponse()
+	if resp.ParentID != parent.Hex() {
+		t.Errorf("ParentID = %q, want %q", resp.ParentID, parent.Hex())
+	}
+	if resp.Slug != "pizza" || !resp.Active {
+		t.Errorf("ToResponse() = %+v", resp)
+	}
+
+	c.ParentID = nil
+	if resp = c.ToResponse(); resp.ParentID != "" {
+		t.Errorf("top-level ParentID = %q, want empty", resp.ParentID)
+	}
+}
@@ -0,0 +1,87 @@
+package unit
+
+import (
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/one-backend-go/internal/domain/modifier"
+)
+
+func TestModifierPrice(t *testing.T) {
+	crust := modifier.Group{ID: primitive.NewObjectID(), Name: "Crust", MinSelect: 1, MaxSelect: 1, Options: []modifier.Option{
+		{ID: "thin", Name: "Thin", IsAvailable: true},
+		{ID: "stuffed", Name: "Stuffed", PriceDeltaCents: 300, IsAvailable: true},
+	}}
+	toppings := modifier.Group{ID: primitive.NewObjectID(), Name: "Toppings", MaxSelect: 2, Options: []modifier.Option{
+		{ID: "extra-cheese", Name: "Extra cheese", PriceDeltaCents: 150, IsAvailable: true},
+		{ID: "olives", Name: "Olives", PriceDeltaCents: 100, IsAvailable: true},
+		{ID: "anchovies", Name: "Anchovies", PriceDeltaCents: 200, IsAvailable: false},
+	}}
+	removals := modifier.Group{ID: primitive.NewObjectID(), Name: "Remove", Options: []modifier.Option{
+		{ID: "no-onions", Name: "No onions", IsAvailable: true},
+		{ID: "no-cheese", Name: "No cheese", PriceDeltaCents: -100, IsAvailable: true},
+	}}
+	sauce := modifier.Group{ID: primitive.NewObjectID(), Name: "Dip", Required: true, Options: []modifier.Option{
+		{ID: "garlic", Name: "Garlic", PriceDeltaCents: 50, IsAvailable: true},
+	}}
+	groups := []modifier.Group{crust, toppings, removals}
+
+	sel := func(g modifier.Group, ids ...string) modifier.Selection {
+		return modifier.Selection{GroupID: g.ID.Hex(), OptionIDs: ids}
+	}
+
+	tests := []struct {
+		name       string
+		groups     []modifier.Group
+		selections []modifier.Selection
+		wantDelta  int64
+		wantErr    bool
+	}{
+		{"required group only", groups, []modifier.Selection{sel(crust, "thin")}, 0, false},
+		{"options summed", groups, []modifier.Selection{sel(toppings, "extra-cheese", "olives"), sel(crust, "stuffed")}, 550, false},
+		{"negative delta", groups, []modifier.Selection{sel(crust, "thin"), sel(removals, "no-onions", "no-cheese")}, -100, false},
+		{"empty selection in optional group", groups, []modifier.Selection{sel(crust, "thin"), sel(toppings)}, 0, false},
+		{"required flag without minimum", []modifier.Group{sauce}, []modifier.Selection{sel(sauce, "garlic")}, 50, false},
+		{"missing required group", groups, nil, 0, true},
+		{"required flag unmet", []modifier.Group{sauce}, nil, 0, true},
+		{"too many", groups, []modifier.Selection{sel(crust, "thin", "stuffed")}, 0, true},
+		{"over maximum", groups, []modifier.Selection{sel(crust, "thin"), sel(toppings, "extra-cheese", "olives", "olives")}, 0, true},
+		{"unknown option", groups, []modifier.Selection{sel(crust, "deep-dish")}, 0, true},
+		{"unavailable option", groups, []modifier.Selection{sel(crust, "thin"), sel(toppings, "anchovies")}, 0, true},
+		{"option twice", groups, []modifier.Selection{sel(crust, "thin"), sel(removals, "no-onions", "no-onions")}, 0, true},
+		{"group not on product", groups, []modifier.Selection{sel(crust, "thin"), sel(sauce, "garlic")}, 0, true},
+		{"group twice", groups, []modifier.Selection{sel(crust, "thin"), sel(crust, "thin")}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options, delta, err := modifier.Price(tt.groups, tt.selections)
+			if tt.wantErr {
+				if !errors.Is(err, modifier.ErrInvalidSelection) {
+					t.Errorf("Price() error = %v, want ErrInvalidSelection", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Price() error = %v", err)
+			}
+			if delta != tt.wantDelta {
+				t.Errorf("Price() delta = %d, want %d", delta, tt.wantDelta)
+			}
+			var sum int64
+			for _, o := range options {
+				sum += o.PriceDeltaCents
+			}
+			if sum != delta {
+				t.Errorf("option deltas sum to %d, want %d", sum, delta)
+			}
+		})
+	}
+
+	// Chosen options are listed in the product's group order.
+	options, _, _ := modifier.Price(groups, []modifier.Selection{sel(toppings, "olives"), sel(crust, "thin")})
+	if len(options) != 2 || options[0].OptionID != "thin" || options[1].OptionID != "olives" {
+		t.Errorf("Price() options = %+v, want thin then olives", options)
+	}
+}
@@ -0,0 +1,163 @@
+package unit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/one-backend-go/internal/pkg/notify"
+)
+
+func TestFileNotifierAppendsJSONLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.log")
+	n := notify.NewFileNotifier(path)
+
+	msgs := []notify.Message{
+		{To: "a@example.com", Kind: "password_reset", Subject: "Reset", Data: map[string]string{"token": "t1"}},
+		{To: "b@example.com", Kind: "password_reset", Subject: "Reset", Data: map[string]string{"token": "t2"}},
+	}
+	for _, m := range msgs {
+		if err := n.Send(context.Background(), m); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer f.Close()
+
+	var got []notify.Message
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var m notify.Message
+		if err := json.Unmarshal(sc.Bytes(), &m); err != nil {
+			t.Fatalf("line %q: %v", sc.Text(), err)
+		}
+		got = append(got, m)
+	}
+
+	if len(got) != len(msgs) {
+		t.Fatalf("got %d lines, want %d", len(got), len(msgs))
+	}
+	for i, m := range got {
+		if m.To != msgs[i].To || m.Data["token"] != msgs[i].Data["token"] {
+			t.Errorf("line %d = %+v, want %+v", i, m, msgs[i])
+		}
+		if m.SentAt.IsZero() {
+			t.Errorf("line %d: SentAt not set", i)
+		}
+	}
+}
+
+func TestMemoryNotifierLast(t *testing.T) {
+	n := notify.NewMemoryNotifier()
+	ctx := context.Background()
+	_ = n.Send(ctx, notify.Message{To: "a@example.com", Kind: "password_reset", Body: "first"})
+	_ = n.Send(ctx, notify.Message{To: "a@example.com", Kind: "password_reset", Body: "second"})
+	_ = n.Send(ctx, notify.Message{To: "b@example.com", Kind: "password_reset", Body: "other"})
+
+	m, ok := n.Last("a@example.com", "password_reset")
+	if !ok || m.Body != "second" {
+		t.Errorf("Last = %+v, %v; want second message", m, ok)
+	}
+	if _, ok := n.Last("a@example.com", "email_verification"); ok {
+		t.Error("Last found a message of the wrong kind")
+	}
+}
+
+func TestNewNotifier(t *testing.T) {
+	tests := []struct {
+		kind    string
+		wantErr bool
+	}{
+		{"", false},
+		{"log", false},
+		{"file", false},
+		{"webhook", false},
+		{"smtp", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			opts := notify.Options{FilePath: filepath.Join(t.TempDir(), "out.log"), WebhookURL: "http://localhost/notify"}
+			_, err := notify.New(tt.kind, opts)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New(%q) error = %v, wantErr %v", tt.kind, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWebhookNotifierPostsMessages(t *testing.T) {
+	var (
+		got    notify.Message
+		auth   string
+		status = http.StatusAccepted
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	n := notify.NewWebhookNotifier(srv.URL, "relay-secret")
+	msg := notify.Message{To: "a@example.com", Kind: "password_reset", Data: map[string]string{"token": "t1"}}
+	if err := n.Send(context.Background(), msg); err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+	if got.To != msg.To || got.Data["token"] != "t1" || got.SentAt.IsZero() {
+		t.Errorf("posted %+v", got)
+	}
+	if auth != "Bearer relay-secret" {
+		t.Errorf("Authorization = %q", auth)
+	}
+
+	// Undelivered messages are errors, so callers can tell.
+	status = http.StatusBadGateway
+	if err := n.Send(context.Background(), msg); err == nil {
+		t.Error("Send() succeeded on a 502")
+	}
+}
+
+func TestLogNotifierRedactsTokens(t *testing.T) {
+	var buf bytes.Buffer
+	prev := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	msg := notify.Message{
+		To:   "a@example.com",
+		Kind: "password_reset",
+		Body: "Use this token to reset your password: s3cr3t-token",
+		Data: map[string]string{"token": "s3cr3t-token"},
+	}
+
+	// Not logged at all at the server's info level.
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})))
+	if err := notify.NewLogNotifier().Send(context.Background(), msg); err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("logged at info level: %s", buf.String())
+	}
+
+	// Redacted at debug level.
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	if err := notify.NewLogNotifier().Send(context.Background(), msg); err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+	if out := buf.String(); strings.Contains(out, "s3cr3t-token") || !strings.Contains(out, "[redacted]") {
+		t.Errorf("debug log = %s, want the token redacted", out)
+	}
+}
@@ -0,0 +1,168 @@
+package unit
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/one-backend-go/internal/pkg/oidc"
+)
+
+// testIssuer serves discovery and a JWKS holding one P-256 signing key.
+type testIssuer struct {
+	*httptest.Server
+	key *ecdsa.PrivateKey
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	iss := &testIssuer{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 iss.URL,
+			"authorization_endpoint": iss.URL + "/authorize",
+			"token_endpoint":         iss.URL + "/token",
+			"jwks_uri":               iss.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		b64 := base64.RawURLEncoding.EncodeToString
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{
+			{"kty": "EC", "crv": "P-256", "kid": "k1", "use": "sig",
+				"x": b64(key.X.FillBytes(make([]byte, 32))), "y": b64(key.Y.FillBytes(make([]byte, 32)))},
+			{"kty": "RSA", "kid": "enc", "use": "enc", "n": "AQAB", "e": "AQAB"},
+		}})
+	})
+	iss.Server = httptest.NewServer(mux)
+	t.Cleanup(iss.Close)
+	return iss
+}
+
+// sign returns an ES256 ID token with sensible defaults overridden by claims.
+func (iss *testIssuer) sign(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+
+	all := jwt.MapClaims{
+		"iss":   iss.URL,
+		"sub":   "user-1",
+		"aud":   "client-1",
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Minute).Unix(),
+		"nonce": "n-1",
+	}
+	for k, v := range claims {
+		all[k] = v
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, all)
+	token.Header["kid"] = "k1"
+	signed, err := token.SignedString(iss.key)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	return signed
+}
+
+func TestOIDCChallenge(t *testing.T) {
+	// Test vector from RFC 7636, appendix B.
+	got := oidc.Challenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk")
+	if want := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"; got != want {
+		t.Errorf("Challenge() = %q, want %q", got, want)
+	}
+}
+
+func TestOIDCAuthCodeURL(t *testing.T) {
+	iss := newTestIssuer(t)
+	p := oidc.NewProvider(oidc.Config{
+		Name: "test", Issuer: iss.URL, ClientID: "client-1", RedirectURL: "https://app.example/cb",
+	}, nil)
+
+	raw, err := p.AuthCodeURL(context.Background(), "s-1", "n-1", "verifier")
+	if err != nil {
+		t.Fatalf("AuthCodeURL() error = %v", err)
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatalf("parse %q: %v", raw, err)
+	}
+	q := u.Query()
+	want := map[string]string{
+		"response_type":         "code",
+		"client_id":             "client-1",
+		"redirect_uri":          "https://app.example/cb",
+		"scope":                 "openid email profile",
+		"state":                 "s-1",
+		"nonce":                 "n-1",
+		"code_challenge":        oidc.Challenge("verifier"),
+		"code_challenge_method": "S256",
+	}
+	for k, v := range want {
+		if got := q.Get(k); got != v {
+			t.Errorf("%s = %q, want %q", k, got, v)
+		}
+	}
+	if q.Has("code_verifier") {
+		t.Error("authorization URL leaks the code verifier")
+	}
+}
+
+func TestOIDCVerify(t *testing.T) {
+	iss := newTestIssuer(t)
+	p := oidc.NewProvider(oidc.Config{Name: "test", Issuer: iss.URL, ClientID: "client-1"}, nil)
+	ctx := context.Background()
+
+	claims, err := p.Verify(ctx, iss.sign(t, jwt.MapClaims{"email": "a@example.com", "email_verified": "true"}), "n-1")
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if claims.Subject != "user-1" || claims.Email != "a@example.com" || !claims.EmailVerified {
+		t.Errorf("Verify() = %+v", claims)
+	}
+
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+		nonce  string
+	}{
+		{"wrong nonce", nil, "n-2"},
+		{"missing nonce", jwt.MapClaims{"nonce": ""}, ""},
+		{"wrong audience", jwt.MapClaims{"aud": "client-2"}, "n-1"},
+		{"wrong issuer", jwt.MapClaims{"iss": "https://evil.example"}, "n-1"},
+		{"expired", jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()}, "n-1"},
+		{"no expiry", jwt.MapClaims{"exp": nil}, "n-1"},
+		{"foreign azp", jwt.MapClaims{"aud": []string{"client-1", "client-2"}, "azp": "client-2"}, "n-1"},
+		{"no subject", jwt.MapClaims{"sub": ""}, "n-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := p.Verify(ctx, iss.sign(t, tt.claims), tt.nonce)
+			if !errors.Is(err, oidc.ErrInvalidIDToken) {
+				t.Errorf("Verify() error = %v, want ErrInvalidIDToken", err)
+			}
+		})
+	}
+
+	// Tokens signed by another key are rejected.
+	other := newTestIssuer(t)
+	forged := other.sign(t, jwt.MapClaims{"iss": iss.URL})
+	if _, err := p.Verify(ctx, forged, "n-1"); !errors.Is(err, oidc.ErrInvalidIDToken) {
+		t.Errorf("Verify(forged) error = %v, want ErrInvalidIDToken", err)
+	}
+}
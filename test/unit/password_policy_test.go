@@ -0,0 +1,131 @@
+package unit
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/one-backend-go/internal/config"
+	"github.com/one-backend-go/internal/pkg/password"
+)
+
+// policyConfig returns the default PASSWORD_* policy settings.
+func policyConfig() *config.Config {
+	return &config.Config{
+		PasswordMinLength:       8,
+		PasswordMaxLength:       72,
+		PasswordRequiredClasses: []string{"letter", "digit"},
+		PasswordMaxRepeat:       3,
+		PasswordBlocklist:       "builtin",
+	}
+}
+
+func TestPasswordPolicyCheck(t *testing.T) {
+	p := password.DefaultPolicy()
+
+	tests := []struct {
+		name      string
+		candidate string
+		want      []string // substrings of the expected problems, in order
+	}{
+		{"acceptable", "kite-orbit-42", nil},
+		{"too short", "ab1", []string{"at least 8 characters"}},
+		{"too long", strings.Repeat("ab1", 25), []string{"at most 72 bytes"}},
+		{"no digit", "kite-orbit", []string{"a digit"}},
+		{"no letter", "1234-5678", []string{"a letter"}},
+		{"repeated character", "kite-orbit-4444", []string{"more than 3 times"}},
+		{"common", "password1", []string{"too common"}},
+		{"common in another case", "PassWord123", []string{"too common"}},
+		{"common with digits and symbols around it", "2024Monkey!!7", []string{"too common"}},
+		{"everything at once", "aaaa", []string{"at least 8", "a digit", "more than 3 times"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := p.Check(tt.candidate)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Check(%q) = %q, want %d problems", tt.candidate, got, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(got[i], want) {
+					t.Errorf("Check(%q)[%d] = %q, want it to mention %q", tt.candidate, i, got[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestPasswordPolicyFromConfig(t *testing.T) {
+	cfg := policyConfig()
+	cfg.PasswordRequiredClasses = []string{"lower", "upper", "digit", "symbol"}
+	cfg.PasswordBlocklist = "none"
+
+	p, err := password.NewPolicyFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewPolicyFromConfig: %v", err)
+	}
+	if got := p.Check("Password1!"); len(got) != 0 {
+		t.Errorf("Check with the blocklist disabled = %q, want none", got)
+	}
+	if got := p.Check("password1"); len(got) != 2 {
+		t.Errorf("Check(password1) = %q, want missing upper and symbol", got)
+	}
+}
+
+func TestPasswordPolicyBlocklistFile(t *testing.T) {
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "list.txt")
+	if err := os.WriteFile(plain, []byte("# house rules\nFoodService2024\n\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	gzipped := filepath.Join(dir, "list.txt.gz")
+	f, err := os.Create(gzipped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(f)
+	_, _ = gz.Write([]byte("foodservice2024\n"))
+	if err = gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	for _, path := range []string{plain, gzipped} {
+		cfg := policyConfig()
+		cfg.PasswordBlocklist = path
+		p, err := password.NewPolicyFromConfig(cfg)
+		if err != nil {
+			t.Fatalf("NewPolicyFromConfig(%s): %v", path, err)
+		}
+		if got := p.Check("foodservice2024"); len(got) != 1 {
+			t.Errorf("%s: Check(foodservice2024) = %q, want too common", path, got)
+		}
+		// A custom list replaces the built-in one.
+		if got := p.Check("password1"); len(got) != 0 {
+			t.Errorf("%s: Check(password1) = %q, want none", path, got)
+		}
+	}
+}
+
+func TestPasswordPolicyRejectsBadConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(cfg *config.Config)
+	}{
+		{"zero min length", func(cfg *config.Config) { cfg.PasswordMinLength = 0 }},
+		{"max below min", func(cfg *config.Config) { cfg.PasswordMaxLength = 4 }},
+		{"negative repeat", func(cfg *config.Config) { cfg.PasswordMaxRepeat = -1 }},
+		{"unknown class", func(cfg *config.Config) { cfg.PasswordRequiredClasses = []string{"emoji"} }},
+		{"missing blocklist file", func(cfg *config.Config) { cfg.PasswordBlocklist = "/nonexistent/list.txt" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := policyConfig()
+			tt.modify(cfg)
+			if _, err := password.NewPolicyFromConfig(cfg); err == nil {
+				t.Error("NewPolicyFromConfig succeeded, want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package unit
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/one-backend-go/internal/pkg/password"
+)
+
+// fastParams keeps hashing cheap in tests.
+func fastParams(algorithm string) password.Params {
+	return password.Params{Algorithm: algorithm, BcryptCost: bcrypt.MinCost, Argon2Memory: 64, Argon2Time: 1, Argon2Threads: 1}
+}
+
+func newHasher(t *testing.T, p password.Params) *password.Hasher {
+	t.Helper()
+	h, err := password.New(p)
+	if err != nil {
+		t.Fatalf("New(%+v): %v", p, err)
+	}
+	return h
+}
+
+func TestPasswordHashRoundTrip(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		prefix    string
+	}{
+		{password.Argon2id, "$argon2id$v=19$m=64,t=1,p=1$"},
+		{password.Bcrypt, "$bcrypt$r=4$"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			h := newHasher(t, fastParams(tt.algorithm))
+			hash, err := h.Hash("s3cret-pass")
+			if err != nil {
+				t.Fatalf("Hash: %v", err)
+			}
+			if !strings.HasPrefix(hash, tt.prefix) {
+				t.Errorf("hash = %q, want prefix %q", hash, tt.prefix)
+			}
+
+			if ok, rehash := h.Verify(hash, "s3cret-pass"); !ok || rehash {
+				t.Errorf("Verify(correct) = %v, %v; want true, false", ok, rehash)
+			}
+			if ok, _ := h.Verify(hash, "wrong-pass1"); ok {
+				t.Error("Verify(wrong) = true, want false")
+			}
+
+			other, _ := h.Hash("s3cret-pass")
+			if other == hash {
+				t.Error("two hashes of the same password are identical; salt not random")
+			}
+		})
+	}
+}
+
+func TestPasswordVerifyFlagsOutdatedHashes(t *testing.T) {
+	argonFast := newHasher(t, fastParams(password.Argon2id))
+	bcryptFast := newHasher(t, fastParams(password.Bcrypt))
+
+	argonHash, _ := argonFast.Hash("s3cret-pass")
+	bcryptHash, _ := bcryptFast.Hash("s3cret-pass")
+	legacy, _ := bcrypt.GenerateFromPassword([]byte("s3cret-pass"), bcrypt.MinCost)
+
+	stronger := fastParams(password.Argon2id)
+	stronger.Argon2Time = 2
+	argonStronger := newHasher(t, stronger)
+
+	tests := []struct {
+		name       string
+		hasher     *password.Hasher
+		hash       string
+		wantRehash bool
+	}{
+		{"same argon2id params", argonFast, argonHash, false},
+		{"argon2id cost raised", argonStronger, argonHash, true},
+		{"bcrypt to argon2id", argonFast, bcryptHash, true},
+		{"argon2id to bcrypt", bcryptFast, argonHash, true},
+		{"legacy bcrypt", bcryptFast, string(legacy), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, rehash := tt.hasher.Verify(tt.hash, "s3cret-pass")
+			if !ok {
+				t.Fatal("Verify = false, want true")
+			}
+			if rehash != tt.wantRehash {
+				t.Errorf("rehash = %v, want %v", rehash, tt.wantRehash)
+			}
+		})
+	}
+}
+
+func TestPasswordVerifyRejectsMalformedHashes(t *testing.T) {
+	h := newHasher(t, fastParams(password.Argon2id))
+	for _, hash := range []string{
+		"",
+		"plaintext",
+		"$argon2id$v=19$m=64,t=1,p=1$onlysalt",
+		"$argon2id$v=18$m=64,t=1,p=1$c2FsdHNhbHQ$aGFzaA",
+		"$argon2id$v=19$m=64,t=0,p=0$c2FsdHNhbHQ$aGFzaA",
+		"$bcrypt$r=x$salt$hash",
+		"$scrypt$ln=15,r=8,p=1$c2FsdA$aGFzaA",
+	} {
+		if ok, _ := h.Verify(hash, "s3cret-pass"); ok {
+			t.Errorf("Verify(%q) = true, want false", hash)
+		}
+	}
+}
+
+func TestPasswordNewRejectsBadParams(t *testing.T) {
+	bad := []password.Params{
+		{Algorithm: "md5"},
+		{Algorithm: password.Bcrypt, BcryptCost: 99},
+		{Algorithm: password.Argon2id, Argon2Memory: 64, Argon2Time: 0, Argon2Threads: 1},
+		{Algorithm: password.Argon2id, Argon2Memory: 64, Argon2Time: 1, Argon2Threads: 0},
+	}
+	for _, p := range bad {
+		if _, err := password.New(p); err == nil {
+			t.Errorf("New(%+v) succeeded, want error", p)
+		}
+	}
+}
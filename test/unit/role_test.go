@@ -0,0 +1,30 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/one-backend-go/internal/domain/role"
+)
+
+func TestHasPermission(t *testing.T) {
+	tests := []struct {
+		name    string
+		granted []string
+		want    string
+		wantOK  bool
+	}{
+		{"exact match", []string{role.PermProductsWrite}, role.PermProductsWrite, true},
+		{"missing", []string{role.PermProductsWrite}, role.PermOrdersRefund, false},
+		{"global wildcard", []string{role.PermAll}, role.PermRolesManage, true},
+		{"resource wildcard", []string{"orders:*"}, role.PermOrdersRefund, true},
+		{"other resource wildcard", []string{"orders:*"}, role.PermProductsWrite, false},
+		{"no permissions", nil, role.PermProductsWrite, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := role.HasPermission(tt.granted, tt.want); got != tt.wantOK {
+				t.Errorf("HasPermission(%v, %q) = %v, want %v", tt.granted, tt.want, got, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package unit
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/one-backend-go/internal/domain/product"
+	"github.com/one-backend-go/internal/pkg/slug"
+)
+
+func TestSlugMake(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"simple", "Margherita Pizza", "margherita-pizza"},
+		{"punctuation collapses", "  Fish -- and   Chips!! ", "fish-and-chips"},
+		{"accents removed", "Crème Brûlée", "creme-brulee"},
+		{"ampersand spelled out", "Mac & Cheese", "mac-and-cheese"},
+		{"special letters", "Smørrebrød Straße", "smorrebrod-strasse"},
+		{"digits kept", "7Up 0.5l", "7up-0-5l"},
+		{"compatibility forms", "Ｐｉｚｚａ ½", "pizza-1-2"},
+		{"nothing spellable", "寿司", ""},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := slug.Make(tt.in); got != tt.want {
+				t.Errorf("Make(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSlugLength(t *testing.T) {
+	long := strings.Repeat("extra cheesy ", 20)
+	s := slug.Make(long)
+	if len(s) > slug.MaxLength || strings.HasSuffix(s, "-") || !strings.HasPrefix(s, "extra-cheesy-") {
+		t.Errorf("Make(long) = %q (%d bytes)", s, len(s))
+	}
+
+	withSuffix := slug.WithSuffix(strings.Repeat("a", slug.MaxLength), 12)
+	if len(withSuffix) != slug.MaxLength || !strings.HasSuffix(withSuffix, "-12") {
+		t.Errorf("WithSuffix(long) = %q (%d bytes)", withSuffix, len(withSuffix))
+	}
+	if got := slug.WithSuffix("pizza", 2); got != "pizza-2" {
+		t.Errorf("WithSuffix(pizza, 2) = %q", got)
+	}
+}
+
+func TestProductBaseSlugFallsBack(t *testing.T) {
+	if got := product.BaseSlug("Margherita Pizza"); got != "margherita-pizza" {
+		t.Errorf("BaseSlug(Margherita Pizza) = %q", got)
+	}
+	for _, name := range []string{"寿司", "!!"} {
+		if got := product.BaseSlug(name); got != "product" {
+			t.Errorf("BaseSlug(%q) = %q, want product", name, got)
+		}
+	}
+}
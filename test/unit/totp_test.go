@@ -0,0 +1,96 @@
+package unit
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/one-backend-go/internal/pkg/totp"
+)
+
+// rfc6238Secret is the SHA-1 test key from RFC 6238 appendix B,
+// "12345678901234567890", base32-encoded.
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCodeRFC6238Vectors(t *testing.T) {
+	// The RFC lists 8-digit codes; 6-digit codes are their last six digits.
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		got, err := totp.Code(rfc6238Secret, totp.Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code(%d) error: %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("Code(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestTOTPVerify(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := totp.Step(now)
+	codeAt := func(offset int64) string {
+		c, _ := totp.Code(rfc6238Secret, step+offset)
+		return c
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		wantOK   bool
+		wantStep int64
+	}{
+		{"current step", codeAt(0), true, step},
+		{"previous step within skew", codeAt(-1), true, step - 1},
+		{"next step within skew", codeAt(1), true, step + 1},
+		{"outside skew", codeAt(2), false, 0},
+		{"wrong length", "12345", false, 0},
+		{"garbage", "abcdef", false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStep, ok := totp.Verify(rfc6238Secret, tt.code, now, 1)
+			if ok != tt.wantOK || gotStep != tt.wantStep {
+				t.Errorf("Verify() = (%d, %v), want (%d, %v)", gotStep, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestTOTPGenerateSecretAndURI(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret() error: %v", err)
+	}
+	if len(secret) != 32 {
+		t.Errorf("secret length = %d, want 32 base32 chars", len(secret))
+	}
+	if _, err = totp.Code(secret, 1); err != nil {
+		t.Errorf("generated secret does not decode: %v", err)
+	}
+
+	uri, err := url.Parse(totp.URI("Food Service", "jane@example.com", secret))
+	if err != nil {
+		t.Fatalf("URI does not parse: %v", err)
+	}
+	if uri.Scheme != "otpauth" || uri.Host != "totp" {
+		t.Errorf("URI = %s, want otpauth://totp/...", uri)
+	}
+	if !strings.HasSuffix(uri.Path, "Food Service:jane@example.com") {
+		t.Errorf("label = %q, want issuer:account", uri.Path)
+	}
+	if q := uri.Query(); q.Get("secret") != secret || q.Get("issuer") != "Food Service" {
+		t.Errorf("query = %v, want secret and issuer", q)
+	}
+}
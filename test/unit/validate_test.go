@@ -1,6 +1,7 @@
 package unit
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/one-backend-go/internal/pkg/validate"
@@ -52,6 +53,7 @@ func TestValidatorStrongPass(t *testing.T) {
 		{"too short", passInput{Password: "ab1"}, true},
 		{"empty", passInput{Password: ""}, true},
 		{"mixed case with digits", passInput{Password: "MyPass123"}, false},
+		{"common", passInput{Password: "password1"}, true},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -63,6 +65,20 @@ func TestValidatorStrongPass(t *testing.T) {
 	}
 }
 
+func TestValidatorStrongPassMessage(t *testing.T) {
+	v := validate.New()
+
+	type passInput struct {
+		Password string `validate:"required,strongpass"`
+	}
+
+	errs := v.Struct(passInput{Password: "aaaa"})
+	want := "must be at least 8 characters; must contain a digit; must not repeat a character more than 3 times in a row"
+	if errs["password"] != want {
+		t.Errorf("password error = %q, want %q", errs["password"], want)
+	}
+}
+
 func TestValidatorEmail(t *testing.T) {
 	v := validate.New()
 
@@ -88,3 +104,33 @@ func TestValidatorEmail(t *testing.T) {
 		})
 	}
 }
+
+func TestValidatorSKU(t *testing.T) {
+	v := validate.New()
+
+	type skuInput struct {
+		SKU string `validate:"required,sku"`
+	}
+
+	tests := []struct {
+		name    string
+		input   skuInput
+		wantErr bool
+	}{
+		{"valid", skuInput{SKU: "PIZZA-MARG-L"}, false},
+		{"lower case and dots", skuInput{SKU: "cola.500ml"}, false},
+		{"space", skuInput{SKU: "PIZZA L"}, true},
+		{"leading separator", skuInput{SKU: "-PIZZA"}, true},
+		{"double separator", skuInput{SKU: "PIZZA--L"}, true},
+		{"too long", skuInput{SKU: strings.Repeat("A", 65)}, true},
+		{"empty", skuInput{SKU: ""}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := v.Struct(tt.input)
+			if (errs != nil) != tt.wantErr {
+				t.Errorf("Struct() errors = %v, wantErr %v", errs, tt.wantErr)
+			}
+		})
+	}
+}